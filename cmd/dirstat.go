@@ -6,10 +6,14 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"amurru/filetools/internal/categories"
 	"amurru/filetools/internal/exclusions"
+	"amurru/filetools/internal/filetype"
 	"amurru/filetools/internal/output"
+	"amurru/filetools/internal/rules"
 	"github.com/spf13/cobra"
 )
 
@@ -26,37 +30,124 @@ and collect statistics about file sizes, types, and directory utilization. It pr
 - File type breakdown with counts and percentages
 - Directory breakdown with file counts and size percentages
 - Information about the largest file
+- Per-owner and per-group breakdown of file counts and sizes
+- A permission audit of world-writable, setuid/setgid and unreadable files
+- Empty directories, empty files, broken symlinks and symlinks pointing outside the tree
+- Counts of each entry type; named pipes, sockets and device nodes are listed
+  separately and not counted as files
+- Directories with the most direct and cumulative entries, the tree's inode count
+  and the free space and inodes of the filesystem
+- Optionally, MIME type and content category breakdown detected from file contents
+- Optionally, the largest files and the directories holding most of each file
+  type, listed with --type-top
+- Optionally, a breakdown by user-defined categories loaded with --categories
+- Optionally, threshold rules such as "logs/ > 5G" given with --rule or --rules;
+  violations are reported and make the command exit with status 2
+- Optionally, tracked, untracked and ignored bytes of git worktrees and the
+  size of their .git directories, read directly from the index and ignore files
+- Optionally, files carrying extended attributes or POSIX ACLs and the bytes
+  their attributes consume (Linux only)
+- Optionally, the projected savings of filesystem compression per file type,
+  estimated by compressing a sample of each file
 
 The output includes percentages relative to the total directory utilization.
 
+Results can be saved as a snapshot with --save-snapshot and later compared
+against a new run with --compare, reporting added, removed, grown and shrunk
+directories and file types. A snapshot of another directory is refused unless
+--compare-any-root is given.
+
+Runs can also be appended to a history file with --record; "dirstat history"
+then reports growth rates over time and projects when the filesystem fills up.
+
 If the directory is not specified, the current directory will be used.
 `,
 	Run: runDirstat,
 }
 
+// dirstatOptions holds the optional analyses performed by analyzeDirectory
+type dirstatOptions struct {
+	detectTypes bool // Sniff file contents for MIME type and category
+	parallel    int  // Number of directories read concurrently, 1 walks sequentially
+	top         int  // Number of entries in hot spot lists
+	typeTop     int  // Largest files and directories listed per file type, 0 disables the drill-down
+
+	categories *categories.Config // User-defined categories, nil if not configured
+	sampleSize int64              // Bytes per file compressed to estimate compressibility, 0 disables the estimate
+	git        bool               // Classify files as tracked, untracked or ignored by git
+	rules      []rules.Rule       // Threshold rules checked against the result
+	xattrs     bool               // Report extended attributes and POSIX ACLs
+}
+
+var (
+	walkParallelism    int
+	hotspotCount       int
+	typeTopCount       int
+	detectContentTypes bool
+	categoriesFile     string
+	estimateCompress   bool
+	gitStats           bool
+	xattrStats         bool
+	ruleFlags          []string
+	rulesFile          string
+	compressSample     int64
+	saveSnapshotPath   string
+	compareSnapshot    string
+	compareAnyRoot     bool
+)
+
 func init() {
 	rootCmd.AddCommand(dirstatCmd)
+
+	// Command-specific flags
+	dirstatCmd.Flags().IntVarP(&walkParallelism, "parallel", "p", 1, "Number of directories to read concurrently (1 walks sequentially)")
+	dirstatCmd.Flags().IntVar(&hotspotCount, "top", 10, "Number of directories listed as entry count hot spots")
+	dirstatCmd.Flags().IntVar(&typeTopCount, "type-top", 0, "Number of largest files and directories listed for each file type (0 disables)")
+	dirstatCmd.Flags().StringVar(&categoriesFile, "categories", "", "JSON file defining named categories of globs, extensions and path prefixes")
+	dirstatCmd.Flags().StringArrayVar(&ruleFlags, "rule", nil, "Threshold rule such as \"logs/ > 5G\", \"files > 1e6\" or \"*.core present\" (repeatable)")
+	dirstatCmd.Flags().StringVar(&rulesFile, "rules", "", "File with one threshold rule per line")
+	dirstatCmd.Flags().BoolVar(&gitStats, "git", false, "Report tracked, untracked and ignored bytes of git worktrees and the size of .git directories")
+	dirstatCmd.Flags().BoolVar(&xattrStats, "xattrs", false, "Report files carrying extended attributes or POSIX ACLs and the bytes they consume (Linux only)")
+	dirstatCmd.Flags().BoolVar(&estimateCompress, "estimate-compression", false, "Estimate compressed size per file type by compressing a sample of each file")
+	dirstatCmd.Flags().Int64Var(&compressSample, "sample-size", 64*1024, "Bytes per file compressed by --estimate-compression")
+	dirstatCmd.Flags().BoolVar(&detectContentTypes, "detect-types", false, "Detect MIME type and category of each file by sniffing its contents")
+	dirstatCmd.Flags().StringVar(&saveSnapshotPath, "save-snapshot", "", "Save the result as a JSON snapshot file for later comparison")
+	dirstatCmd.Flags().StringVar(&compareSnapshot, "compare", "", "Compare against a previously saved snapshot file and report the differences")
+	dirstatCmd.Flags().BoolVar(&compareAnyRoot, "compare-any-root", false, "Compare against a snapshot of another directory")
 }
 
 // analyzeDirectory traverses the directory and collects statistics
-func analyzeDirectory(rootDir string, fileMatchers, dirMatchers []exclusions.ExclusionMatcher) (*output.DirStatResult, error) {
-	totalFiles := 0
-	totalSize := int64(0)
-	var largestFile *output.FileInfo
+func analyzeDirectory(rootDir string, fileMatchers, dirMatchers []exclusions.ExclusionMatcher, opts dirstatOptions) (*output.DirStatResult, error) {
+	collector := newDirStatCollector(opts)
+	inspector, err := newFileInspector(rootDir, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	fileTypes := make(map[string]*output.FileType)
-	directories := make(map[string]*output.DirectoryInfo)
-	var exclusionsList []output.Exclusion
+	var tracker *gitTracker
+	if opts.git {
+		tracker = newGitTracker(rootDir)
+	}
 
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+	// visit may be called concurrently when walking in parallel, so all
+	// shared state is updated through the collector and the git tracker
+	visit := func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			// Skip files/directories we can't access
 			fmt.Fprintf(os.Stderr, "Warning: could not access %s: %v\n", path, err)
+			if info != nil && info.IsDir() {
+				if relPath, relErr := filepath.Rel(rootDir, path); relErr == nil {
+					collector.addUnreadableDir(relPath)
+				}
+			}
 			return nil
 		}
 
 		// Skip the root directory itself
 		if path == rootDir {
+			if tracker != nil && info.IsDir() {
+				tracker.visitDir(path, ".")
+			}
 			return nil
 		}
 
@@ -68,102 +159,791 @@ func analyzeDirectory(rootDir string, fileMatchers, dirMatchers []exclusions.Exc
 
 		// Check for exclusions
 		if exclusion := exclusions.CheckExclusions(relPath, info.IsDir(), fileMatchers, dirMatchers); exclusion != nil {
-			exclusionsList = append(exclusionsList, *exclusion)
+			collector.addExclusion(*exclusion)
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		if info.IsDir() {
-			// Initialize directory stats
-			directories[relPath] = &output.DirectoryInfo{
-				Path:      relPath,
-				FileCount: 0,
-				TotalSize: 0,
-			}
-		} else {
-			// File statistics
-			totalFiles++
-			totalSize += info.Size()
-
-			// Track largest file
-			if largestFile == nil || info.Size() > largestFile.Size {
-				largestFile = &output.FileInfo{
-					Name: filepath.Base(path),
-					Size: info.Size(),
-					Path: relPath,
-				}
-			}
+		// Named pipes, sockets and devices have no meaningful size and must
+		// not be opened, so they are only counted
+		if isSpecialFile(info.Mode()) {
+			collector.addSpecial(relPath, info)
+			return nil
+		}
 
-			// File type statistics
-			ext := strings.ToLower(filepath.Ext(path))
-			if ext == "" {
-				ext = "(no extension)"
+		if tracker != nil {
+			if info.IsDir() {
+				tracker.visitDir(path, relPath)
+			} else {
+				tracker.addFile(relPath, info.Size())
 			}
+		}
 
-			if _, exists := fileTypes[ext]; !exists {
-				fileTypes[ext] = &output.FileType{
-					Extension: ext,
-					Count:     0,
-					TotalSize: 0,
-				}
-			}
-			fileTypes[ext].Count++
-			fileTypes[ext].TotalSize += info.Size()
-
-			// Add to directory statistics
-			dir := filepath.Dir(relPath)
-			if dirStats, exists := directories[dir]; exists {
-				dirStats.FileCount++
-				dirStats.TotalSize += info.Size()
-			}
+		// Inspect the entry before taking the collector lock
+		if info.IsDir() {
+			collector.addDir(relPath, info, inspector.inspectDir(path))
+		} else {
+			collector.addFile(relPath, info, inspector.inspect(path, info))
 		}
 
 		return nil
-	})
+	}
+
+	if opts.parallel > 1 {
+		err = walkParallel(rootDir, opts.parallel, visit)
+	} else {
+		err = filepath.Walk(rootDir, visit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := collector.result()
+	result.Root = rootDir
+	result.Filesystem = filesystemInfo(rootDir)
+	if len(opts.rules) > 0 {
+		result.Rules = collector.evaluator.Evaluate(result)
+	}
+	if tracker != nil {
+		result.Git = tracker.stats()
+	}
+
+	return result, nil
+}
+
+// fileDetails holds per-file information that requires extra I/O and is
+// gathered outside the collector lock
+type fileDetails struct {
+	readable        bool
+	contentType     *filetype.Info
+	compressedSize  int64 // Estimated compressed size, -1 if not estimated
+	symlinkTarget   string
+	brokenSymlink   bool
+	externalSymlink bool
+	xattrs          xattrInfo
+}
+
+// xattrInfo describes the extended attributes of a file
+type xattrInfo struct {
+	count int   // Number of attributes
+	bytes int64 // Size of attribute names and values
+	acl   bool  // A POSIX access or default ACL is present
+}
+
+// fileInspector gathers the details of files required by the enabled analyses
+type fileInspector struct {
+	opts  dirstatOptions
+	roots []string // Absolute root path, and its resolved form if different
+}
 
+// newFileInspector creates a fileInspector for the tree rooted at rootDir
+func newFileInspector(rootDir string, opts dirstatOptions) (*fileInspector, error) {
+	absRoot, err := filepath.Abs(rootDir)
 	if err != nil {
 		return nil, err
 	}
 
+	roots := []string{absRoot}
+	if realRoot, err := filepath.EvalSymlinks(absRoot); err == nil && realRoot != absRoot {
+		roots = append(roots, realRoot)
+	}
+
+	return &fileInspector{opts: opts, roots: roots}, nil
+}
+
+// inspect gathers the details of a single file
+func (fi *fileInspector) inspect(path string, info os.FileInfo) fileDetails {
+	details := fileDetails{readable: isReadable(info), compressedSize: -1}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		fi.inspectSymlink(path, &details)
+	}
+
+	// Sniffing a symlink would read its target instead
+	if fi.opts.detectTypes && info.Mode().IsRegular() {
+		detected, err := filetype.Detect(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not detect type of %s: %v\n", path, err)
+		} else {
+			details.contentType = &detected
+		}
+	}
+
+	// Reading attributes would follow a symlink to its target
+	if info.Mode().IsRegular() {
+		fi.inspectXattrs(path, &details)
+	}
+
+	if fi.opts.sampleSize > 0 && info.Mode().IsRegular() {
+		compressed, err := estimateCompressedSize(path, info.Size(), fi.opts.sampleSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not estimate compressed size of %s: %v\n", path, err)
+		} else {
+			details.compressedSize = compressed
+		}
+	}
+
+	return details
+}
+
+// inspectDir gathers the details of a directory
+func (fi *fileInspector) inspectDir(path string) fileDetails {
+	details := fileDetails{readable: true, compressedSize: -1}
+	fi.inspectXattrs(path, &details)
+	return details
+}
+
+// inspectXattrs reads the extended attributes of a file if requested
+func (fi *fileInspector) inspectXattrs(path string, details *fileDetails) {
+	if !fi.opts.xattrs {
+		return
+	}
+	xattrs, err := readXattrs(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read extended attributes of %s: %v\n", path, err)
+		return
+	}
+	details.xattrs = xattrs
+}
+
+// inspectSymlink checks whether a symlink is broken or points outside the root
+func (fi *fileInspector) inspectSymlink(path string, details *fileDetails) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read symlink %s: %v\n", path, err)
+		return
+	}
+	details.symlinkTarget = target
+
+	if _, err := os.Stat(path); err != nil {
+		details.brokenSymlink = true
+	}
+
+	// Resolve the full chain where possible, otherwise judge the literal target
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolved = target
+		if !filepath.IsAbs(resolved) {
+			resolved = filepath.Join(filepath.Dir(path), resolved)
+		}
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return
+	}
+
+	details.externalSymlink = true
+	for _, root := range fi.roots {
+		if rel, err := filepath.Rel(root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			details.externalSymlink = false
+			break
+		}
+	}
+}
+
+// dirStatCollector accumulates directory statistics. Its methods are safe
+// for concurrent use, and the result does not depend on the order in which
+// entries are added.
+type dirStatCollector struct {
+	mu   sync.Mutex
+	opts dirstatOptions
+
+	totalFiles        int
+	totalSize         int64
+	largestFile       *output.FileInfo
+	fileTypes         map[string]*output.FileType
+	typeFiles         map[string][]output.FileInfo                // Largest files per extension, at most opts.typeTop
+	typeDirs          map[string]map[string]*output.DirectoryInfo // Files per extension and directory
+	contentTypes      map[filetype.Info]*output.ContentType
+	contentCategories map[string]*output.ContentCategory
+	userCategories    map[string]*output.CategoryInfo
+	compression       *compressionStats
+	evaluator         *rules.Evaluator
+	directories       map[string]*output.DirectoryInfo
+	ownership         *ownershipStats
+	exclusions        []output.Exclusion
+	directEntries     map[string]int // Number of entries directly inside each directory
+	entries           int            // Entries visited, excluding the root
+	entryTypes        map[string]int
+	hardLinks         map[[2]uint64]bool
+	extraLinks        int // Additional names of inodes already counted
+	unreadableDirs    map[string]bool
+	emptyFiles        []string
+	brokenSymlinks    []output.SymlinkInfo
+	externalSymlinks  []output.SymlinkInfo
+	specialFiles      []output.SpecialFile
+	xattrFiles        []output.XattrFile
+}
+
+// newDirStatCollector creates an empty dirStatCollector
+func newDirStatCollector(opts dirstatOptions) *dirStatCollector {
+	return &dirStatCollector{
+		opts:              opts,
+		fileTypes:         make(map[string]*output.FileType),
+		typeFiles:         make(map[string][]output.FileInfo),
+		typeDirs:          make(map[string]map[string]*output.DirectoryInfo),
+		contentTypes:      make(map[filetype.Info]*output.ContentType),
+		contentCategories: make(map[string]*output.ContentCategory),
+		userCategories:    make(map[string]*output.CategoryInfo),
+		compression:       newCompressionStats(opts.sampleSize),
+		evaluator:         rules.NewEvaluator(opts.rules),
+		directories:       make(map[string]*output.DirectoryInfo),
+		ownership:         newOwnershipStats(),
+		directEntries:     make(map[string]int),
+		unreadableDirs:    make(map[string]bool),
+		entryTypes:        make(map[string]int),
+		hardLinks:         make(map[[2]uint64]bool),
+	}
+}
+
+// addExclusion records an excluded file or directory
+func (c *dirStatCollector) addExclusion(exclusion output.Exclusion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.exclusions = append(c.exclusions, exclusion)
+	c.directEntries[filepath.Dir(exclusion.Path)]++
+}
+
+// addUnreadableDir records a directory whose entries could not be read
+func (c *dirStatCollector) addUnreadableDir(relPath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.unreadableDirs[relPath] = true
+}
+
+// addDir records a directory
+func (c *dirStatCollector) addDir(relPath string, info os.FileInfo, details fileDetails) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Permission audit applies to both files and directories
+	c.ownership.audit(relPath, info, details.readable)
+	c.addXattrs(relPath, details.xattrs)
+	c.entryTypes[entryTypeDirectory]++
+
+	// Initialize directory stats
+	c.directories[relPath] = &output.DirectoryInfo{
+		Path:      relPath,
+		FileCount: 0,
+		TotalSize: 0,
+	}
+	c.directEntries[filepath.Dir(relPath)]++
+	c.entries++
+}
+
+// addSpecial records a named pipe, socket, device node or other special file
+func (c *dirStatCollector) addSpecial(relPath string, info os.FileInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ownership.audit(relPath, info, true)
+	kind := entryType(info.Mode())
+	c.entryTypes[kind]++
+	c.specialFiles = append(c.specialFiles, output.SpecialFile{Path: relPath, Type: kind})
+	c.directEntries[filepath.Dir(relPath)]++
+	c.entries++
+	c.addInode(info)
+}
+
+// addInode counts the inode of a non-directory, so hard links are counted
+// only once. The caller must hold the lock.
+func (c *dirStatCollector) addInode(info os.FileInfo) {
+	if key, nlink, ok := inodeKey(info); ok && nlink > 1 {
+		if c.hardLinks[key] {
+			c.extraLinks++
+		}
+		c.hardLinks[key] = true
+	}
+}
+
+// addXattrs records the extended attributes of a file or directory. The
+// caller must hold the lock.
+func (c *dirStatCollector) addXattrs(relPath string, xattrs xattrInfo) {
+	if xattrs.count == 0 {
+		return
+	}
+	c.xattrFiles = append(c.xattrFiles, output.XattrFile{
+		Path:  relPath,
+		Count: xattrs.count,
+		Bytes: xattrs.bytes,
+		ACL:   xattrs.acl,
+	})
+}
+
+// addTypeDetails records a file for the drill-down of its file type. The
+// caller must hold the lock.
+func (c *dirStatCollector) addTypeDetails(ext, relPath string, size int64) {
+	// Keep the largest files sorted, preferring the first in walk order on ties
+	file := output.FileInfo{Name: filepath.Base(relPath), Size: size, Path: relPath}
+	files := c.typeFiles[ext]
+	i := sort.Search(len(files), func(i int) bool {
+		return size > files[i].Size || (size == files[i].Size && walkOrderLess(relPath, files[i].Path))
+	})
+	if i < c.opts.typeTop {
+		files = append(files, output.FileInfo{})
+		copy(files[i+1:], files[i:])
+		files[i] = file
+		if len(files) > c.opts.typeTop {
+			files = files[:c.opts.typeTop]
+		}
+		c.typeFiles[ext] = files
+	}
+
+	dir := filepath.Dir(relPath)
+	if _, exists := c.typeDirs[ext]; !exists {
+		c.typeDirs[ext] = make(map[string]*output.DirectoryInfo)
+	}
+	if _, exists := c.typeDirs[ext][dir]; !exists {
+		c.typeDirs[ext][dir] = &output.DirectoryInfo{Path: dir}
+	}
+	c.typeDirs[ext][dir].FileCount++
+	c.typeDirs[ext][dir].TotalSize += size
+}
+
+// addFile records a file and its details
+func (c *dirStatCollector) addFile(relPath string, info os.FileInfo, details fileDetails) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ownership.audit(relPath, info, details.readable)
+	c.addXattrs(relPath, details.xattrs)
+	c.entryTypes[entryType(info.Mode())]++
+	c.directEntries[filepath.Dir(relPath)]++
+	c.entries++
+	c.addInode(info)
+
+	// Clutter: empty files and problematic symlinks
+	if info.Mode().IsRegular() && info.Size() == 0 {
+		c.emptyFiles = append(c.emptyFiles, relPath)
+	}
+	if details.brokenSymlink {
+		c.brokenSymlinks = append(c.brokenSymlinks, output.SymlinkInfo{Path: relPath, Target: details.symlinkTarget})
+	}
+	if details.externalSymlink {
+		c.externalSymlinks = append(c.externalSymlinks, output.SymlinkInfo{Path: relPath, Target: details.symlinkTarget})
+	}
+
+	// File statistics
+	c.totalFiles++
+	c.totalSize += info.Size()
+
+	// Track largest file, preferring the first in walk order on ties
+	if c.largestFile == nil || info.Size() > c.largestFile.Size ||
+		(info.Size() == c.largestFile.Size && walkOrderLess(relPath, c.largestFile.Path)) {
+		c.largestFile = &output.FileInfo{
+			Name: filepath.Base(relPath),
+			Size: info.Size(),
+			Path: relPath,
+		}
+	}
+
+	// File type statistics
+	ext := strings.ToLower(filepath.Ext(relPath))
+	if ext == "" {
+		ext = "(no extension)"
+	}
+
+	if _, exists := c.fileTypes[ext]; !exists {
+		c.fileTypes[ext] = &output.FileType{
+			Extension: ext,
+			Count:     0,
+			TotalSize: 0,
+		}
+	}
+	c.fileTypes[ext].Count++
+	c.fileTypes[ext].TotalSize += info.Size()
+	if c.opts.typeTop > 0 {
+		c.addTypeDetails(ext, relPath, info.Size())
+	}
+
+	// Compression estimate
+	if details.compressedSize >= 0 {
+		c.compression.addFile(ext, info.Size(), details.compressedSize)
+	}
+
+	// Content type statistics
+	if detected := details.contentType; detected != nil {
+		if _, exists := c.contentTypes[*detected]; !exists {
+			c.contentTypes[*detected] = &output.ContentType{
+				MIMEType: detected.MIMEType,
+				Category: detected.Category,
+			}
+		}
+		c.contentTypes[*detected].Count++
+		c.contentTypes[*detected].TotalSize += info.Size()
+
+		if _, exists := c.contentCategories[detected.Category]; !exists {
+			c.contentCategories[detected.Category] = &output.ContentCategory{
+				Category: detected.Category,
+			}
+		}
+		c.contentCategories[detected.Category].Count++
+		c.contentCategories[detected.Category].TotalSize += info.Size()
+	}
+
+	// User-defined category statistics
+	if c.opts.categories != nil {
+		name := c.opts.categories.Match(relPath)
+		if _, exists := c.userCategories[name]; !exists {
+			c.userCategories[name] = &output.CategoryInfo{Name: name}
+		}
+		c.userCategories[name].Count++
+		c.userCategories[name].TotalSize += info.Size()
+	}
+
+	// Presence rules
+	c.evaluator.ObserveFile(relPath)
+
+	// Owner and group statistics
+	c.ownership.addFile(info)
+
+	// Add to directory statistics
+	dir := filepath.Dir(relPath)
+	if dirStats, exists := c.directories[dir]; exists {
+		dirStats.FileCount++
+		dirStats.TotalSize += info.Size()
+	}
+}
+
+// result converts the collected statistics into a sorted DirStatResult
+func (c *dirStatCollector) result() *output.DirStatResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Convert maps to slices and calculate percentages
 	var fileTypesSlice []output.FileType
-	for _, ft := range fileTypes {
-		ft.Percentage = float64(ft.TotalSize) / float64(totalSize) * 100
+	for ext, ft := range c.fileTypes {
+		ft.Percentage = sizePercentage(ft.TotalSize, c.totalSize)
+		if c.opts.typeTop > 0 {
+			ft.LargestFiles = c.typeFiles[ext]
+			ft.Directories = c.typeDirectories(ext, ft.TotalSize)
+		}
 		fileTypesSlice = append(fileTypesSlice, *ft)
 	}
 
+	var contentTypesSlice []output.ContentType
+	for _, ct := range c.contentTypes {
+		ct.Percentage = sizePercentage(ct.TotalSize, c.totalSize)
+		contentTypesSlice = append(contentTypesSlice, *ct)
+	}
+
+	var contentCategoriesSlice []output.ContentCategory
+	for _, cc := range c.contentCategories {
+		cc.Percentage = sizePercentage(cc.TotalSize, c.totalSize)
+		contentCategoriesSlice = append(contentCategoriesSlice, *cc)
+	}
+
+	// Every configured category is reported, even if empty, in configuration order
+	var categoriesSlice []output.CategoryInfo
+	if c.opts.categories != nil {
+		for _, name := range append(c.opts.categories.Names(), categories.Uncategorised) {
+			category := output.CategoryInfo{Name: name}
+			if collected, exists := c.userCategories[name]; exists {
+				category = *collected
+			}
+			category.Percentage = sizePercentage(category.TotalSize, c.totalSize)
+			categoriesSlice = append(categoriesSlice, category)
+		}
+	}
+
 	var directoriesSlice []output.DirectoryInfo
-	for _, dir := range directories {
+	for _, dir := range c.directories {
 		if dir.FileCount > 0 { // Only include directories with files
-			dir.Percentage = float64(dir.TotalSize) / float64(totalSize) * 100
+			dir.Percentage = sizePercentage(dir.TotalSize, c.totalSize)
 			directoriesSlice = append(directoriesSlice, *dir)
 		}
 	}
 
 	// Sort file types by total size (descending)
 	sort.Slice(fileTypesSlice, func(i, j int) bool {
-		return fileTypesSlice[i].TotalSize > fileTypesSlice[j].TotalSize
+		if fileTypesSlice[i].TotalSize != fileTypesSlice[j].TotalSize {
+			return fileTypesSlice[i].TotalSize > fileTypesSlice[j].TotalSize
+		}
+		return fileTypesSlice[i].Extension < fileTypesSlice[j].Extension
+	})
+
+	// Sort content types and categories by total size (descending)
+	sort.Slice(contentTypesSlice, func(i, j int) bool {
+		if contentTypesSlice[i].TotalSize != contentTypesSlice[j].TotalSize {
+			return contentTypesSlice[i].TotalSize > contentTypesSlice[j].TotalSize
+		}
+		if contentTypesSlice[i].MIMEType != contentTypesSlice[j].MIMEType {
+			return contentTypesSlice[i].MIMEType < contentTypesSlice[j].MIMEType
+		}
+		return contentTypesSlice[i].Category < contentTypesSlice[j].Category
+	})
+	sort.SliceStable(categoriesSlice, func(i, j int) bool {
+		return categoriesSlice[i].TotalSize > categoriesSlice[j].TotalSize
+	})
+
+	sort.Slice(contentCategoriesSlice, func(i, j int) bool {
+		if contentCategoriesSlice[i].TotalSize != contentCategoriesSlice[j].TotalSize {
+			return contentCategoriesSlice[i].TotalSize > contentCategoriesSlice[j].TotalSize
+		}
+		return contentCategoriesSlice[i].Category < contentCategoriesSlice[j].Category
 	})
 
 	// Sort directories by total size (descending)
 	sort.Slice(directoriesSlice, func(i, j int) bool {
-		return directoriesSlice[i].TotalSize > directoriesSlice[j].TotalSize
+		if directoriesSlice[i].TotalSize != directoriesSlice[j].TotalSize {
+			return directoriesSlice[i].TotalSize > directoriesSlice[j].TotalSize
+		}
+		return walkOrderLess(directoriesSlice[i].Path, directoriesSlice[j].Path)
+	})
+
+	// Directories without any entries, not counting those we failed to read
+	var emptyDirectories []string
+	for path := range c.directories {
+		if c.directEntries[path] == 0 && !c.unreadableDirs[path] {
+			emptyDirectories = append(emptyDirectories, path)
+		}
+	}
+
+	directHotspots, cumulativeHotspots := c.entryHotspots()
+
+	var entryTypes []output.EntryTypeCount
+	for _, kind := range entryTypeOrder {
+		if count := c.entryTypes[kind]; count > 0 {
+			entryTypes = append(entryTypes, output.EntryTypeCount{Type: kind, Count: count})
+		}
+	}
+
+	var xattrs *output.XattrStats
+	if c.opts.xattrs {
+		xattrs = c.xattrStats()
+	}
+
+	var compression *output.CompressionEstimate
+	if c.opts.sampleSize > 0 {
+		compression = c.compression.estimate()
+	}
+
+	// Lists are reported in walk order regardless of how they were collected
+	sort.Slice(c.exclusions, func(i, j int) bool {
+		return walkOrderLess(c.exclusions[i].Path, c.exclusions[j].Path)
+	})
+	c.ownership.sortPermissions()
+	sortWalkOrder(emptyDirectories)
+	sortWalkOrder(c.emptyFiles)
+	sort.Slice(c.brokenSymlinks, func(i, j int) bool {
+		return walkOrderLess(c.brokenSymlinks[i].Path, c.brokenSymlinks[j].Path)
+	})
+	sort.Slice(c.externalSymlinks, func(i, j int) bool {
+		return walkOrderLess(c.externalSymlinks[i].Path, c.externalSymlinks[j].Path)
 	})
+	sort.Slice(c.specialFiles, func(i, j int) bool {
+		return walkOrderLess(c.specialFiles[i].Path, c.specialFiles[j].Path)
+	})
+
+	owners, groups := c.ownership.ownerSlices(c.totalSize)
 
 	result := &output.DirStatResult{
-		TotalFiles:  totalFiles,
-		TotalSize:   totalSize,
-		LargestFile: largestFile,
-		FileTypes:   fileTypesSlice,
-		Directories: directoriesSlice,
-		Exclusions:  exclusionsList,
+		TotalFiles:              c.totalFiles,
+		TotalSize:               c.totalSize,
+		LargestFile:             c.largestFile,
+		TotalInodes:             c.entries + 1 - c.extraLinks, // The root itself consumes an inode too
+		EntryTypes:              entryTypes,
+		SpecialFiles:            c.specialFiles,
+		Xattrs:                  xattrs,
+		FileTypes:               fileTypesSlice,
+		ContentTypes:            contentTypesSlice,
+		ContentCategories:       contentCategoriesSlice,
+		Categories:              categoriesSlice,
+		Compression:             compression,
+		Directories:             directoriesSlice,
+		Owners:                  owners,
+		Groups:                  groups,
+		Permissions:             &c.ownership.permissions,
+		EmptyDirectories:        emptyDirectories,
+		EmptyFiles:              c.emptyFiles,
+		BrokenSymlinks:          c.brokenSymlinks,
+		ExternalSymlinks:        c.externalSymlinks,
+		DirectEntryHotspots:     directHotspots,
+		CumulativeEntryHotspots: cumulativeHotspots,
+		Exclusions:              c.exclusions,
 	}
 
-	return result, nil
+	return result
+}
+
+// typeDirectories returns the directories holding the most bytes of a file
+// type, with percentages relative to total, the size of the type
+func (c *dirStatCollector) typeDirectories(ext string, total int64) []output.DirectoryInfo {
+	dirs := make([]output.DirectoryInfo, 0, len(c.typeDirs[ext]))
+	for _, dir := range c.typeDirs[ext] {
+		dir.Percentage = sizePercentage(dir.TotalSize, total)
+		dirs = append(dirs, *dir)
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].TotalSize != dirs[j].TotalSize {
+			return dirs[i].TotalSize > dirs[j].TotalSize
+		}
+		return walkOrderLess(dirs[i].Path, dirs[j].Path)
+	})
+	if len(dirs) > c.opts.typeTop {
+		dirs = dirs[:c.opts.typeTop]
+	}
+	return dirs
+}
+
+// xattrStats summarizes the collected extended attributes, listing the
+// files with the most attribute bytes
+func (c *dirStatCollector) xattrStats() *output.XattrStats {
+	stats := &output.XattrStats{}
+	for _, file := range c.xattrFiles {
+		stats.FilesWithXattrs++
+		stats.TotalBytes += file.Bytes
+		if file.ACL {
+			stats.FilesWithACLs++
+		}
+	}
+
+	largest := append([]output.XattrFile(nil), c.xattrFiles...)
+	sort.Slice(largest, func(i, j int) bool {
+		if largest[i].Bytes != largest[j].Bytes {
+			return largest[i].Bytes > largest[j].Bytes
+		}
+		return walkOrderLess(largest[i].Path, largest[j].Path)
+	})
+	if c.opts.top >= 0 && len(largest) > c.opts.top {
+		largest = largest[:c.opts.top]
+	}
+	stats.Largest = largest
+	return stats
 }
 
+// entryHotspots returns the directories with the most direct and cumulative entries
+func (c *dirStatCollector) entryHotspots() ([]output.DirectoryEntries, []output.DirectoryEntries) {
+	// Every directory we visited, plus the root
+	paths := []string{"."}
+	for path := range c.directories {
+		paths = append(paths, path)
+	}
+
+	// Accumulate bottom-up: deeper directories are processed before their parents
+	sort.Slice(paths, func(i, j int) bool {
+		di, dj := strings.Count(paths[i], string(filepath.Separator)), strings.Count(paths[j], string(filepath.Separator))
+		if di != dj {
+			return di > dj
+		}
+		return paths[i] < paths[j]
+	})
+	cumulative := make(map[string]int)
+	for _, path := range paths {
+		cumulative[path] += c.directEntries[path]
+		if path != "." {
+			cumulative[filepath.Dir(path)] += cumulative[path]
+		}
+	}
+
+	all := make([]output.DirectoryEntries, 0, len(paths))
+	for _, path := range paths {
+		all = append(all, output.DirectoryEntries{
+			Path:              path,
+			DirectEntries:     c.directEntries[path],
+			CumulativeEntries: cumulative[path],
+		})
+	}
+
+	direct := topEntries(all, func(e output.DirectoryEntries) int { return e.DirectEntries }, c.opts.top)
+	cumulativeTop := topEntries(all, func(e output.DirectoryEntries) int { return e.CumulativeEntries }, c.opts.top)
+	return direct, cumulativeTop
+}
+
+// topEntries returns the n directories with the highest count, skipping empty ones
+func topEntries(all []output.DirectoryEntries, count func(output.DirectoryEntries) int, n int) []output.DirectoryEntries {
+	sorted := make([]output.DirectoryEntries, 0, len(all))
+	for _, entry := range all {
+		if count(entry) > 0 {
+			sorted = append(sorted, entry)
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if count(sorted[i]) != count(sorted[j]) {
+			return count(sorted[i]) > count(sorted[j])
+		}
+		return walkOrderLess(sorted[i].Path, sorted[j].Path)
+	})
+
+	if n >= 0 && len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// Entry types as reported in DirStatResult.EntryTypes
+const (
+	entryTypeRegular     = "regular"
+	entryTypeDirectory   = "directory"
+	entryTypeSymlink     = "symlink"
+	entryTypeNamedPipe   = "named_pipe"
+	entryTypeSocket      = "socket"
+	entryTypeBlockDevice = "block_device"
+	entryTypeCharDevice  = "char_device"
+	entryTypeIrregular   = "irregular"
+)
+
+// entryTypeOrder is the order in which entry types are reported
+var entryTypeOrder = []string{
+	entryTypeRegular,
+	entryTypeDirectory,
+	entryTypeSymlink,
+	entryTypeNamedPipe,
+	entryTypeSocket,
+	entryTypeBlockDevice,
+	entryTypeCharDevice,
+	entryTypeIrregular,
+}
+
+// entryType classifies a file by its mode
+func entryType(mode os.FileMode) string {
+	switch {
+	case mode.IsRegular():
+		return entryTypeRegular
+	case mode.IsDir():
+		return entryTypeDirectory
+	case mode&os.ModeSymlink != 0:
+		return entryTypeSymlink
+	case mode&os.ModeNamedPipe != 0:
+		return entryTypeNamedPipe
+	case mode&os.ModeSocket != 0:
+		return entryTypeSocket
+	case mode&os.ModeCharDevice != 0:
+		return entryTypeCharDevice
+	case mode&os.ModeDevice != 0:
+		return entryTypeBlockDevice
+	default:
+		return entryTypeIrregular
+	}
+}
+
+// isSpecialFile reports whether mode describes a named pipe, socket, device
+// or other file that is neither regular, a directory nor a symlink
+func isSpecialFile(mode os.FileMode) bool {
+	switch entryType(mode) {
+	case entryTypeRegular, entryTypeDirectory, entryTypeSymlink:
+		return false
+	}
+	return true
+}
+
+// sizePercentage returns size as a percentage of total, or 0 for an empty total
+func sizePercentage(size, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(size) / float64(total) * 100
+}
+
+// exitRuleViolation is the exit status of dirstat when a threshold rule is
+// violated, distinct from the status 1 used for errors
+const exitRuleViolation = 2
+
 // runDirstat executes the dirstat command
 func runDirstat(cmd *cobra.Command, args []string) {
 	rootDir := "."
@@ -180,12 +960,89 @@ func runDirstat(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// Load the snapshot to compare against before doing any work
+	var snapshot *output.DirStatResult
+	if compareSnapshot != "" {
+		var err error
+		snapshot, err = loadSnapshot(compareSnapshot)
+		if err == nil && !compareAnyRoot {
+			err = checkSnapshotRoot(snapshot, rootDir)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Parse exclusion patterns
 	fileMatchers := exclusions.ParseExclusions(excludeFilePatterns, true)
 	dirMatchers := exclusions.ParseExclusions(excludeDirPatterns, false)
 
+	if walkParallelism < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --parallel must be at least 1\n")
+		os.Exit(1)
+	}
+	if hotspotCount < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --top must not be negative\n")
+		os.Exit(1)
+	}
+	if typeTopCount < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --type-top must not be negative\n")
+		os.Exit(1)
+	}
+	if recordDepth < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --record-depth must not be negative\n")
+		os.Exit(1)
+	}
+
+	opts := dirstatOptions{
+		detectTypes: detectContentTypes,
+		parallel:    walkParallelism,
+		top:         hotspotCount,
+		typeTop:     typeTopCount,
+	}
+	if estimateCompress {
+		if compressSample < compressionChunks {
+			fmt.Fprintf(os.Stderr, "Error: --sample-size must be at least %d\n", compressionChunks)
+			os.Exit(1)
+		}
+		opts.sampleSize = compressSample
+	}
+	opts.git = gitStats
+	if xattrStats {
+		if xattrsSupported {
+			opts.xattrs = true
+		} else {
+			fmt.Fprintln(os.Stderr, "Warning: --xattrs is not supported on this platform")
+		}
+	}
+	for _, text := range ruleFlags {
+		rule, err := rules.Parse(text)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.rules = append(opts.rules, rule)
+	}
+	if rulesFile != "" {
+		fileRules, err := rules.Load(rulesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading rules: %v\n", err)
+			os.Exit(1)
+		}
+		opts.rules = append(opts.rules, fileRules...)
+	}
+	if categoriesFile != "" {
+		config, err := categories.Load(categoriesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading categories: %v\n", err)
+			os.Exit(1)
+		}
+		opts.categories = config
+	}
+
 	// Analyze directory
-	result, err := analyzeDirectory(rootDir, fileMatchers, dirMatchers)
+	result, err := analyzeDirectory(rootDir, fileMatchers, dirMatchers, opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error analyzing directory: %v\n", err)
 		os.Exit(1)
@@ -221,6 +1078,57 @@ func runDirstat(cmd *cobra.Command, args []string) {
 		flags = append(flags, output.Flag{Name: "exclude-dir", Value: excludeDirPatterns})
 	}
 
+	// Add analysis flags if specified
+	if walkParallelism > 1 {
+		flags = append(flags, output.Flag{Name: "parallel", Value: fmt.Sprintf("%d", walkParallelism)})
+	}
+	if hotspotCount != 10 {
+		flags = append(flags, output.Flag{Name: "top", Value: fmt.Sprintf("%d", hotspotCount)})
+	}
+	if typeTopCount > 0 {
+		flags = append(flags, output.Flag{Name: "type-top", Value: fmt.Sprintf("%d", typeTopCount)})
+	}
+	if categoriesFile != "" {
+		flags = append(flags, output.Flag{Name: "categories", Value: categoriesFile})
+	}
+	for _, text := range ruleFlags {
+		flags = append(flags, output.Flag{Name: "rule", Value: text})
+	}
+	if rulesFile != "" {
+		flags = append(flags, output.Flag{Name: "rules", Value: rulesFile})
+	}
+	if gitStats {
+		flags = append(flags, output.Flag{Name: "git", Value: "true"})
+	}
+	if opts.xattrs {
+		flags = append(flags, output.Flag{Name: "xattrs", Value: "true"})
+	}
+	if estimateCompress {
+		flags = append(flags, output.Flag{Name: "estimate-compression", Value: "true"})
+		flags = append(flags, output.Flag{Name: "sample-size", Value: fmt.Sprintf("%d", compressSample)})
+	}
+	if detectContentTypes {
+		flags = append(flags, output.Flag{Name: "detect-types", Value: "true"})
+	}
+	if saveSnapshotPath != "" {
+		flags = append(flags, output.Flag{Name: "save-snapshot", Value: saveSnapshotPath})
+	}
+	if compareSnapshot != "" {
+		flags = append(flags, output.Flag{Name: "compare", Value: compareSnapshot})
+	}
+	if compareAnyRoot {
+		flags = append(flags, output.Flag{Name: "compare-any-root", Value: "true"})
+	}
+	if recordHistory {
+		flags = append(flags, output.Flag{Name: "record", Value: "true"})
+	}
+	if recordDepth != 3 {
+		flags = append(flags, output.Flag{Name: "record-depth", Value: fmt.Sprintf("%d", recordDepth)})
+	}
+	if historyFile != "" {
+		flags = append(flags, output.Flag{Name: "history", Value: historyFile})
+	}
+
 	metadata := &output.Metadata{
 		ToolName:    "filetools",
 		SubCommand:  "dirstat",
@@ -232,9 +1140,39 @@ func runDirstat(cmd *cobra.Command, args []string) {
 	// Set metadata in result
 	result.Metadata = metadata
 
-	// Output the results
-	if err := formatter.FormatDirStat(result, writer); err != nil {
+	// Save snapshot if requested
+	if saveSnapshotPath != "" {
+		if err := saveSnapshot(result, saveSnapshotPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Record the run for dirstat history
+	if recordHistory {
+		if err := appendHistory(rootDir, result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error recording history: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Output the comparison instead of the statistics when comparing
+	if snapshot != nil {
+		diff := diffDirStat(snapshot, result)
+		diff.Metadata = metadata
+		diff.Rules = result.Rules
+		if err := formatter.FormatDirStatDiff(diff, writer); err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+			os.Exit(1)
+		}
+	} else if err := formatter.FormatDirStat(result, writer); err != nil {
 		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
 		os.Exit(1)
 	}
+
+	// Fail with a distinct exit code when thresholds are exceeded, for use from cron and monitoring
+	if result.Rules != nil && len(result.Rules.Violations) > 0 {
+		cleanup()
+		os.Exit(exitRuleViolation)
+	}
 }
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"compress/flate"
+	"io"
+	"os"
+	"sort"
+
+	"amurru/filetools/internal/output"
+)
+
+// compressionChunks is the number of evenly spaced chunks a sample is made
+// of, so that headers and trailers do not dominate the estimate
+const compressionChunks = 4
+
+// estimateCompressedSize estimates the deflate-compressed size of a file by
+// compressing up to sampleSize bytes of it. Files no larger than the sample
+// are compressed whole and the result is exact.
+func estimateCompressedSize(path string, size int64, sampleSize int64) (int64, error) {
+	if size == 0 {
+		return 0, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	counter := &countingWriter{}
+	compressor, err := flate.NewWriter(counter, flate.DefaultCompression)
+	if err != nil {
+		return 0, err
+	}
+
+	var sampled int64
+	if size <= sampleSize {
+		sampled, err = io.Copy(compressor, file)
+	} else {
+		chunkSize := sampleSize / compressionChunks
+		stride := (size - chunkSize) / (compressionChunks - 1)
+		for i := int64(0); i < compressionChunks && err == nil; i++ {
+			var n int64
+			n, err = io.Copy(compressor, io.NewSectionReader(file, i*stride, chunkSize))
+			sampled += n
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := compressor.Close(); err != nil {
+		return 0, err
+	}
+	if sampled == 0 {
+		return size, nil
+	}
+
+	// Compression never saves less than nothing: storing the file raw is always possible
+	compressed := int64(float64(counter.n) / float64(sampled) * float64(size))
+	if compressed > size {
+		compressed = size
+	}
+	return compressed, nil
+}
+
+// countingWriter discards data written to it, counting the bytes
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// compressionStats accumulates estimated compressed sizes per file type
+type compressionStats struct {
+	sampleSize int64
+	fileTypes  map[string]*output.CompressionFileType
+	total      output.CompressionFileType
+}
+
+// newCompressionStats creates an empty compressionStats
+func newCompressionStats(sampleSize int64) *compressionStats {
+	return &compressionStats{
+		sampleSize: sampleSize,
+		fileTypes:  make(map[string]*output.CompressionFileType),
+	}
+}
+
+// addFile records the size and estimated compressed size of a file
+func (s *compressionStats) addFile(ext string, size, compressed int64) {
+	if _, exists := s.fileTypes[ext]; !exists {
+		s.fileTypes[ext] = &output.CompressionFileType{Extension: ext}
+	}
+	for _, stats := range []*output.CompressionFileType{s.fileTypes[ext], &s.total} {
+		stats.Count++
+		stats.TotalSize += size
+		stats.CompressedSize += compressed
+	}
+}
+
+// estimate returns the compression estimate with file types sorted by projected savings
+func (s *compressionStats) estimate() *output.CompressionEstimate {
+	estimate := &output.CompressionEstimate{
+		SampleSize:        s.sampleSize,
+		TotalSize:         s.total.TotalSize,
+		CompressedSize:    s.total.CompressedSize,
+		CompressionRatio:  compressionRatio(s.total.TotalSize, s.total.CompressedSize),
+		SavingsPercentage: savingsPercentage(s.total.TotalSize, s.total.CompressedSize),
+	}
+
+	for _, ft := range s.fileTypes {
+		ft.CompressionRatio = compressionRatio(ft.TotalSize, ft.CompressedSize)
+		ft.SavingsPercentage = savingsPercentage(ft.TotalSize, ft.CompressedSize)
+		estimate.FileTypes = append(estimate.FileTypes, *ft)
+	}
+
+	sort.Slice(estimate.FileTypes, func(i, j int) bool {
+		savedI := estimate.FileTypes[i].TotalSize - estimate.FileTypes[i].CompressedSize
+		savedJ := estimate.FileTypes[j].TotalSize - estimate.FileTypes[j].CompressedSize
+		if savedI != savedJ {
+			return savedI > savedJ
+		}
+		return estimate.FileTypes[i].Extension < estimate.FileTypes[j].Extension
+	})
+
+	return estimate
+}
+
+// compressionRatio returns the original size divided by the compressed size
+func compressionRatio(size, compressed int64) float64 {
+	if compressed == 0 {
+		return 1
+	}
+	return float64(size) / float64(compressed)
+}
+
+// savingsPercentage returns the share of size saved by compression
+func savingsPercentage(size, compressed int64) float64 {
+	return sizePercentage(size-compressed, size)
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"amurru/filetools/internal/git"
+	"amurru/filetools/internal/output"
+)
+
+// gitTracker classifies the files of the analyzed tree as tracked,
+// untracked or ignored by the git worktrees they belong to. Directories
+// must be visited before their contents, as filepath.Walk and walkParallel do.
+type gitTracker struct {
+	mu          sync.Mutex
+	repos       map[string]*gitRepo            // Worktrees by their path relative to the analyzed root
+	ignores     map[string][]git.IgnorePattern // Patterns of each directory's .gitignore
+	ignoredDirs map[string]bool                // Directories ignored as a whole
+}
+
+// gitRepo is a git worktree found in or around the analyzed tree
+type gitRepo struct {
+	dir    string              // Worktree path relative to the analyzed root
+	prefix string              // Slash-separated path of the analyzed root inside the worktree, if the root is below it
+	index  *git.Index          // Tracked paths
+	base   []git.IgnorePattern // info/exclude, plus ignore files above the analyzed root
+	stats  output.GitRepository
+}
+
+// newGitTracker creates a gitTracker for the tree rooted at rootDir. If the
+// root is inside a worktree, that worktree and its ignore files above the
+// root are taken into account.
+func newGitTracker(rootDir string) *gitTracker {
+	t := &gitTracker{
+		repos:       make(map[string]*gitRepo),
+		ignores:     make(map[string][]git.IgnorePattern),
+		ignoredDirs: make(map[string]bool),
+	}
+
+	absRoot, err := filepath.Abs(rootDir)
+	if err != nil {
+		return t
+	}
+
+	// A worktree rooted at the analyzed root itself is found when visiting it
+	for dir := filepath.Dir(absRoot); ; dir = filepath.Dir(dir) {
+		if repo := openGitRepo(dir); repo != nil {
+			t.addEnclosingRepo(repo, dir, absRoot)
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return t
+}
+
+// addEnclosingRepo registers the worktree at worktreeDir containing the analyzed root
+func (t *gitTracker) addEnclosingRepo(repo *gitRepo, worktreeDir, absRoot string) {
+	rel, err := filepath.Rel(worktreeDir, absRoot)
+	if err != nil {
+		return
+	}
+	repo.dir = "."
+	repo.prefix = filepath.ToSlash(rel)
+	repo.stats.Path = "."
+
+	// Load the ignore files of the directories between the worktree and the root
+	ignored := false
+	dir := worktreeDir
+	repoPath := ""
+	for _, part := range strings.Split(repo.prefix, "/") {
+		patterns, err := git.LoadIgnore(filepath.Join(dir, ".gitignore"), repoPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not read %s: %v\n", filepath.Join(dir, ".gitignore"), err)
+		}
+		repo.base = append(repo.base, patterns...)
+
+		dir = filepath.Join(dir, part)
+		repoPath = strings.TrimPrefix(repoPath+"/"+part, "/")
+		if git.Ignored(repo.base, repoPath, true) {
+			ignored = true
+			break
+		}
+	}
+
+	t.repos["."] = repo
+	t.ignoredDirs["."] = ignored
+}
+
+// openGitRepo opens the worktree at dir, returning nil if dir is not one
+func openGitRepo(dir string) *gitRepo {
+	gitPath := filepath.Join(dir, ".git")
+	info, err := os.Lstat(gitPath)
+	if err != nil {
+		return nil
+	}
+
+	// Linked worktrees and submodules have a .git file pointing to the git directory
+	gitDir := gitPath
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return nil
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(gitPath)
+		if err != nil || !strings.HasPrefix(string(data), "gitdir:") {
+			return nil
+		}
+		gitDir = strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(dir, gitDir)
+		}
+	}
+
+	index, err := git.LoadIndex(filepath.Join(gitDir, "index"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: could not read git index: %v\n", err)
+		}
+		index = &git.Index{}
+	}
+
+	// info/exclude lives in the common directory shared by linked worktrees
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+	exclude, err := git.LoadIgnore(filepath.Join(commonDir, "info", "exclude"), "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read git excludes: %v\n", err)
+	}
+
+	return &gitRepo{index: index, base: exclude}
+}
+
+// path returns the slash-separated path of relPath inside the worktree
+func (r *gitRepo) path(relPath string) string {
+	rel, err := filepath.Rel(r.dir, relPath)
+	if err != nil || rel == "." {
+		rel = ""
+	}
+	rel = filepath.ToSlash(rel)
+	if r.prefix == "" || r.prefix == "." {
+		return rel
+	}
+	if rel == "" {
+		return r.prefix
+	}
+	return r.prefix + "/" + rel
+}
+
+// inGitDir reports whether a worktree path lies inside the .git directory
+func (r *gitRepo) inGitDir(repoPath string) bool {
+	return r.prefix == "" && (repoPath == ".git" || strings.HasPrefix(repoPath, ".git/"))
+}
+
+// repoFor returns the innermost worktree containing the directory dir
+func (t *gitTracker) repoFor(dir string) *gitRepo {
+	for {
+		if repo, exists := t.repos[dir]; exists {
+			return repo
+		}
+		if dir == "." {
+			return nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// patterns returns the ignore patterns applying to entries of dir, ordered
+// from lowest to highest precedence
+func (t *gitTracker) patterns(repo *gitRepo, dir string) []git.IgnorePattern {
+	var chain [][]git.IgnorePattern
+	for {
+		chain = append(chain, t.ignores[dir])
+		if dir == repo.dir || dir == "." {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	patterns := append([]git.IgnorePattern{}, repo.base...)
+	for i := len(chain) - 1; i >= 0; i-- {
+		patterns = append(patterns, chain[i]...)
+	}
+	return patterns
+}
+
+// visitDir records whether a directory starts a worktree or is ignored, and
+// loads its .gitignore file
+func (t *gitTracker) visitDir(path, relPath string) {
+	// Nested worktrees and submodules take over their subtree
+	if relPath != "." || t.repoFor(".") == nil {
+		if repo := openGitRepo(path); repo != nil {
+			patterns, err := git.LoadIgnore(filepath.Join(path, ".gitignore"), "")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not read %s: %v\n", filepath.Join(path, ".gitignore"), err)
+			}
+			repo.dir = relPath
+			repo.stats.Path = relPath
+
+			t.mu.Lock()
+			t.repos[relPath] = repo
+			t.ignores[relPath] = patterns
+			t.mu.Unlock()
+			return
+		}
+	}
+
+	t.mu.Lock()
+	repo := t.repoFor(relPath)
+	if repo == nil {
+		t.mu.Unlock()
+		return
+	}
+	repoPath := repo.path(relPath)
+	if repo.inGitDir(repoPath) {
+		t.mu.Unlock()
+		return
+	}
+	ignored := t.ignoredDirs[relPath]
+	if relPath != repo.dir {
+		parent := filepath.Dir(relPath)
+		ignored = t.ignoredDirs[parent] || git.Ignored(t.patterns(repo, parent), repoPath, true)
+		t.ignoredDirs[relPath] = ignored
+	}
+	t.mu.Unlock()
+
+	// Git does not read ignore files inside ignored directories
+	if ignored {
+		return
+	}
+	patterns, err := git.LoadIgnore(filepath.Join(path, ".gitignore"), repoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read %s: %v\n", filepath.Join(path, ".gitignore"), err)
+	}
+	if len(patterns) > 0 {
+		t.mu.Lock()
+		t.ignores[relPath] = patterns
+		t.mu.Unlock()
+	}
+}
+
+// addFile attributes a file to the tracked, untracked, ignored or .git
+// bytes of its worktree. Files outside any worktree are not counted.
+func (t *gitTracker) addFile(relPath string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent := filepath.Dir(relPath)
+	repo := t.repoFor(parent)
+	if repo == nil {
+		return
+	}
+
+	repoPath := repo.path(relPath)
+	stats := &repo.stats
+	switch {
+	case repo.inGitDir(repoPath):
+		stats.GitDirFiles++
+		stats.GitDirSize += size
+	case repo.index.Contains(repoPath):
+		stats.TrackedFiles++
+		stats.TrackedSize += size
+	case t.ignoredDirs[parent] || git.Ignored(t.patterns(repo, parent), repoPath, false):
+		stats.IgnoredFiles++
+		stats.IgnoredSize += size
+	default:
+		stats.UntrackedFiles++
+		stats.UntrackedSize += size
+	}
+}
+
+// stats returns the per-worktree statistics in walk order along with their totals
+func (t *gitTracker) stats() *output.GitStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := &output.GitStats{Repositories: []output.GitRepository{}}
+	for _, repo := range t.repos {
+		result.Repositories = append(result.Repositories, repo.stats)
+
+		total := &result.GitCounts
+		total.TrackedFiles += repo.stats.TrackedFiles
+		total.TrackedSize += repo.stats.TrackedSize
+		total.UntrackedFiles += repo.stats.UntrackedFiles
+		total.UntrackedSize += repo.stats.UntrackedSize
+		total.IgnoredFiles += repo.stats.IgnoredFiles
+		total.IgnoredSize += repo.stats.IgnoredSize
+		total.GitDirFiles += repo.stats.GitDirFiles
+		total.GitDirSize += repo.stats.GitDirSize
+	}
+
+	sort.Slice(result.Repositories, func(i, j int) bool {
+		return walkOrderLess(result.Repositories[i].Path, result.Repositories[j].Path)
+	})
+	return result
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"amurru/filetools/internal/history"
+	"amurru/filetools/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// dirstatHistoryCmd represents the dirstat history command
+var dirstatHistoryCmd = &cobra.Command{
+	Use:   "history [directory]",
+	Short: "Report growth trends of a directory from recorded dirstat runs",
+	Long: `Report how a directory tree has grown over the dirstat runs recorded with
+dirstat --record.
+
+The report includes:
+
+- The total size, file count and filesystem free space of every run
+- The growth rate of the tree and of its fastest growing directories, fitted
+  over all runs by least squares
+- A projection of when the filesystem will be full if its free space keeps
+  shrinking at the fitted rate
+- With -o html, charts of the total size and free space over time
+
+Runs are matched by the absolute path of the analyzed directory, so pass the
+same directory that was recorded. If the directory is not specified, the
+current directory will be used.
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runDirstatHistory,
+}
+
+var (
+	historyFile   string
+	recordHistory bool
+	recordDepth   int
+	historyTop    int
+)
+
+func init() {
+	dirstatCmd.AddCommand(dirstatHistoryCmd)
+
+	// Shared by dirstat --record and dirstat history
+	dirstatCmd.PersistentFlags().StringVar(&historyFile, "history", "", "History file (default: filetools/dirstat-history.jsonl in the user configuration directory)")
+
+	dirstatCmd.Flags().BoolVar(&recordHistory, "record", false, "Append a summary of this run to the history file for dirstat history")
+	dirstatCmd.Flags().IntVar(&recordDepth, "record-depth", 3, "Depth of the directories whose sizes --record keeps, deeper ones count towards their parent at that depth (0 for all)")
+	dirstatHistoryCmd.Flags().IntVar(&historyTop, "top", 10, "Number of fastest growing directories listed")
+}
+
+// historyPath returns the history file given with --history, or the default
+func historyPath() (string, error) {
+	if historyFile != "" {
+		return historyFile, nil
+	}
+	return history.DefaultPath()
+}
+
+// historyRecord summarizes a dirstat result for the history file. The sizes
+// of directories deeper than depth are added to their parent at that depth,
+// which keeps the records of large trees small; all directories are kept if
+// depth is 0.
+func historyRecord(root string, result *output.DirStatResult, at time.Time, depth int) history.Record {
+	record := history.Record{
+		Time:        at.UTC(),
+		Root:        root,
+		TotalFiles:  result.TotalFiles,
+		TotalSize:   result.TotalSize,
+		TotalInodes: result.TotalInodes,
+		Filesystem:  result.Filesystem,
+		Depth:       depth,
+		Directories: make(map[string]int64),
+	}
+
+	// Files stored directly in the root are recorded as directory "."
+	rootSize := result.TotalSize
+	for _, dir := range result.Directories {
+		path := dir.Path
+		if parts := strings.Split(path, string(filepath.Separator)); depth > 0 && len(parts) > depth {
+			path = filepath.Join(parts[:depth]...)
+		}
+		record.Directories[path] += dir.TotalSize
+		rootSize -= dir.TotalSize
+	}
+	if rootSize > 0 {
+		record.Directories["."] = rootSize
+	}
+	return record
+}
+
+// appendHistory records a dirstat result of rootDir in the history file
+func appendHistory(rootDir string, result *output.DirStatResult) error {
+	root, err := filepath.Abs(rootDir)
+	if err != nil {
+		return err
+	}
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	return history.Append(path, historyRecord(root, result, time.Now(), recordDepth))
+}
+
+// analyzeHistory computes growth trends from records of one directory, oldest first
+func analyzeHistory(root string, records []history.Record, top int) *output.DirStatHistoryResult {
+	result := &output.DirStatHistoryResult{Root: root}
+	if len(records) == 0 {
+		return result
+	}
+
+	first := records[0].Time
+	days := make([]float64, len(records))
+	sizes := make([]float64, len(records))
+	for i, record := range records {
+		days[i] = record.Time.Sub(first).Hours() / 24
+		sizes[i] = float64(record.TotalSize)
+
+		point := output.HistoryPoint{
+			Time:       record.Time.Format(time.RFC3339),
+			TotalFiles: record.TotalFiles,
+			TotalSize:  record.TotalSize,
+		}
+		if record.Filesystem != nil {
+			point.AvailableBytes = record.Filesystem.AvailableBytes
+		}
+		result.Points = append(result.Points, point)
+	}
+	result.GrowthPerDay = linearTrend(days, sizes)
+
+	// Directories missing from a run had no files at the time
+	paths := make(map[string]bool)
+	for _, record := range records {
+		for path := range record.Directories {
+			paths[path] = true
+		}
+	}
+	for path := range paths {
+		dirSizes := make([]float64, len(records))
+		for i, record := range records {
+			dirSizes[i] = float64(record.Directories[path])
+		}
+		growth := linearTrend(days, dirSizes)
+		if growth == 0 {
+			continue
+		}
+		result.Directories = append(result.Directories, output.DirectoryGrowth{
+			Path:         path,
+			FirstSize:    records[0].Directories[path],
+			LastSize:     records[len(records)-1].Directories[path],
+			GrowthPerDay: growth,
+		})
+	}
+	sort.Slice(result.Directories, func(i, j int) bool {
+		if result.Directories[i].GrowthPerDay != result.Directories[j].GrowthPerDay {
+			return result.Directories[i].GrowthPerDay > result.Directories[j].GrowthPerDay
+		}
+		return walkOrderLess(result.Directories[i].Path, result.Directories[j].Path)
+	})
+	if top >= 0 && len(result.Directories) > top {
+		result.Directories = result.Directories[:top]
+	}
+
+	result.Projection = projectFill(records)
+	return result
+}
+
+// projectFill fits the free space of the filesystem over the runs that
+// recorded it and projects when it reaches zero
+func projectFill(records []history.Record) *output.FillProjection {
+	var days, available []float64
+	var last *history.Record
+	for i, record := range records {
+		if record.Filesystem == nil {
+			continue
+		}
+		days = append(days, record.Time.Sub(records[0].Time).Hours()/24)
+		available = append(available, float64(record.Filesystem.AvailableBytes))
+		last = &records[i]
+	}
+	if last == nil {
+		return nil
+	}
+
+	projection := &output.FillProjection{
+		TotalBytes:     last.Filesystem.TotalBytes,
+		AvailableBytes: last.Filesystem.AvailableBytes,
+		ConsumedPerDay: -linearTrend(days, available),
+	}
+	if projection.ConsumedPerDay > 0 {
+		projection.Filling = true
+		projection.DaysUntilFull = float64(projection.AvailableBytes) / projection.ConsumedPerDay
+
+		// time.Duration covers about 292 years
+		if until := projection.DaysUntilFull * 24 * float64(time.Hour); until < math.MaxInt64 {
+			projection.FullAt = last.Time.Add(time.Duration(until)).UTC().Format(time.RFC3339)
+		}
+	}
+	return projection
+}
+
+// linearTrend returns the least-squares slope of ys over xs, or 0 when the
+// xs do not vary
+func linearTrend(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0
+	}
+
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var covariance, variance float64
+	for i := range xs {
+		covariance += (xs[i] - meanX) * (ys[i] - meanY)
+		variance += (xs[i] - meanX) * (xs[i] - meanX)
+	}
+	if variance == 0 {
+		return 0
+	}
+	return covariance / variance
+}
+
+// runDirstatHistory executes the dirstat history command
+func runDirstatHistory(cmd *cobra.Command, args []string) {
+	rootDir := "."
+	if len(args) > 0 {
+		rootDir = args[0]
+	}
+	if historyTop < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --top must not be negative\n")
+		os.Exit(1)
+	}
+
+	root, err := filepath.Abs(rootDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	path, err := historyPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	records, err := history.Load(path, root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(records) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no runs of %s recorded in %s, record them with dirstat --record\n", root, path)
+		os.Exit(1)
+	}
+
+	result := analyzeHistory(root, records, historyTop)
+
+	// Get output writer (file or stdout)
+	writer, cleanup, err := getOutputWriter(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	// Get output format and create formatter
+	format := getOutputFormat(cmd)
+	formatter := output.NewFormatter(format)
+
+	// Create metadata
+	flags := []output.Flag{
+		{Name: "output", Value: string(format)},
+	}
+	if outputFile != "" {
+		flags = append(flags, output.Flag{Name: "file", Value: outputFile})
+	}
+	if historyFile != "" {
+		flags = append(flags, output.Flag{Name: "history", Value: historyFile})
+	}
+	if historyTop != 10 {
+		flags = append(flags, output.Flag{Name: "top", Value: fmt.Sprintf("%d", historyTop)})
+	}
+
+	result.Metadata = &output.Metadata{
+		ToolName:    "filetools",
+		SubCommand:  "dirstat history",
+		Flags:       flags,
+		Version:     version,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+	}
+
+	if err := formatter.FormatDirStatHistory(result, writer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(1)
+	}
+}
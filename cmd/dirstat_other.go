@@ -0,0 +1,23 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"os"
+)
+
+// fileOwner is not supported on this platform
+func fileOwner(info os.FileInfo) (uid, gid string, ok bool) {
+	return "", "", false
+}
+
+// isReadable reports whether a file is readable, judged from its permission
+// bits only
+func isReadable(info os.FileInfo) bool {
+	return info.Mode().Perm()&0444 != 0
+}
+
+// inodeKey is not supported on this platform
+func inodeKey(info os.FileInfo) (key [2]uint64, nlink uint64, ok bool) {
+	return key, 0, false
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"os"
+	"os/user"
+	"sort"
+
+	"amurru/filetools/internal/output"
+)
+
+// ownershipStats accumulates per-owner and per-group file statistics
+// along with the permission audit for a directory tree
+type ownershipStats struct {
+	owners      map[string]*output.OwnerInfo
+	groups      map[string]*output.OwnerInfo
+	permissions output.PermissionAudit
+}
+
+// newOwnershipStats creates an empty ownershipStats
+func newOwnershipStats() *ownershipStats {
+	return &ownershipStats{
+		owners: make(map[string]*output.OwnerInfo),
+		groups: make(map[string]*output.OwnerInfo),
+	}
+}
+
+// addFile attributes a file to its owner and group
+func (s *ownershipStats) addFile(info os.FileInfo) {
+	uid, gid, ok := fileOwner(info)
+	if !ok {
+		return
+	}
+
+	if _, exists := s.owners[uid]; !exists {
+		s.owners[uid] = &output.OwnerInfo{ID: uid}
+	}
+	s.owners[uid].FileCount++
+	s.owners[uid].TotalSize += info.Size()
+
+	if _, exists := s.groups[gid]; !exists {
+		s.groups[gid] = &output.OwnerInfo{ID: gid}
+	}
+	s.groups[gid].FileCount++
+	s.groups[gid].TotalSize += info.Size()
+}
+
+// audit records any noteworthy permission bits of a file or directory
+func (s *ownershipStats) audit(relPath string, info os.FileInfo, readable bool) {
+	mode := info.Mode()
+	if mode&os.ModeSymlink != 0 {
+		// Symlink permissions are meaningless, the target is audited on its own
+		return
+	}
+
+	if mode.Perm()&0002 != 0 {
+		s.permissions.WorldWritable = append(s.permissions.WorldWritable, relPath)
+	}
+	if mode&os.ModeSetuid != 0 {
+		s.permissions.Setuid = append(s.permissions.Setuid, relPath)
+	}
+	if mode&os.ModeSetgid != 0 && !info.IsDir() {
+		s.permissions.Setgid = append(s.permissions.Setgid, relPath)
+	}
+	if !info.IsDir() && !readable {
+		s.permissions.Unreadable = append(s.permissions.Unreadable, relPath)
+	}
+}
+
+// sortPermissions orders the permission audit lists in walk order
+func (s *ownershipStats) sortPermissions() {
+	sortWalkOrder(s.permissions.WorldWritable)
+	sortWalkOrder(s.permissions.Setuid)
+	sortWalkOrder(s.permissions.Setgid)
+	sortWalkOrder(s.permissions.Unreadable)
+}
+
+// ownerSlices resolves IDs to names and returns owners and groups sorted by size
+func (s *ownershipStats) ownerSlices(totalSize int64) ([]output.OwnerInfo, []output.OwnerInfo) {
+	owners := ownerSlice(s.owners, totalSize, func(id string) string {
+		if u, err := user.LookupId(id); err == nil {
+			return u.Username
+		}
+		return id
+	})
+	groups := ownerSlice(s.groups, totalSize, func(id string) string {
+		if g, err := user.LookupGroupId(id); err == nil {
+			return g.Name
+		}
+		return id
+	})
+	return owners, groups
+}
+
+// ownerSlice converts an owner map to a slice sorted by total size (descending)
+func ownerSlice(m map[string]*output.OwnerInfo, totalSize int64, lookup func(id string) string) []output.OwnerInfo {
+	var result []output.OwnerInfo
+	for _, owner := range m {
+		owner.Name = lookup(owner.ID)
+		owner.Percentage = sizePercentage(owner.TotalSize, totalSize)
+		result = append(result, *owner)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalSize != result[j].TotalSize {
+			return result[i].TotalSize > result[j].TotalSize
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
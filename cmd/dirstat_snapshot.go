@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"amurru/filetools/internal/output"
+)
+
+// saveSnapshot writes a dirstat result to path as JSON so it can be compared
+// later. The root is stored as an absolute path, so that a comparison can
+// check it is made against the same directory.
+func saveSnapshot(result *output.DirStatResult, path string) error {
+	snapshot := *result
+	if snapshot.Root != "" {
+		root, err := filepath.Abs(snapshot.Root)
+		if err != nil {
+			return err
+		}
+		snapshot.Root = root
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	formatter := output.NewFormatter(output.FormatJSON)
+	if err := formatter.FormatDirStat(&snapshot, file); err != nil {
+		return fmt.Errorf("failed to write snapshot '%s': %w", path, err)
+	}
+	return file.Close()
+}
+
+// loadSnapshot reads a dirstat result previously saved as JSON
+func loadSnapshot(path string) (*output.DirStatResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot '%s': %w", path, err)
+	}
+
+	var result output.DirStatResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid snapshot '%s': %w", path, err)
+	}
+	return &result, nil
+}
+
+// checkSnapshotRoot returns an error if a snapshot was taken of another
+// directory than root. Snapshots without an absolute root, as saved by older
+// versions, are not checked.
+func checkSnapshotRoot(snapshot *output.DirStatResult, root string) error {
+	if !filepath.IsAbs(snapshot.Root) {
+		return nil
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	if filepath.Clean(snapshot.Root) != absRoot {
+		return fmt.Errorf("the snapshot is of %s, not %s; use --compare-any-root to compare them anyway", snapshot.Root, absRoot)
+	}
+	return nil
+}
+
+// diffDirStat compares a new dirstat result against an older snapshot
+func diffDirStat(oldResult, newResult *output.DirStatResult) *output.DirStatDiffResult {
+	diff := &output.DirStatDiffResult{
+		OldTotalFiles:  oldResult.TotalFiles,
+		NewTotalFiles:  newResult.TotalFiles,
+		OldTotalSize:   oldResult.TotalSize,
+		NewTotalSize:   newResult.TotalSize,
+		SizePercentage: percentageChange(oldResult.TotalSize, newResult.TotalSize),
+	}
+	if oldResult.Metadata != nil {
+		diff.SnapshotGeneratedAt = oldResult.Metadata.GeneratedAt
+	}
+
+	// Directories
+	oldDirs := make(map[string]diffStat)
+	for _, dir := range oldResult.Directories {
+		oldDirs[dir.Path] = diffStat{count: dir.FileCount, size: dir.TotalSize}
+	}
+	newDirs := make(map[string]diffStat)
+	for _, dir := range newResult.Directories {
+		newDirs[dir.Path] = diffStat{count: dir.FileCount, size: dir.TotalSize}
+	}
+	diff.Directories = diffEntries(oldDirs, newDirs)
+
+	// File types
+	oldTypes := make(map[string]diffStat)
+	for _, ft := range oldResult.FileTypes {
+		oldTypes[ft.Extension] = diffStat{count: ft.Count, size: ft.TotalSize}
+	}
+	newTypes := make(map[string]diffStat)
+	for _, ft := range newResult.FileTypes {
+		newTypes[ft.Extension] = diffStat{count: ft.Count, size: ft.TotalSize}
+	}
+	diff.FileTypes = diffEntries(oldTypes, newTypes)
+
+	return diff
+}
+
+// diffStat holds the file count and size compared between two runs
+type diffStat struct {
+	count int
+	size  int64
+}
+
+// diffEntries returns the changed entries between two keyed stats, sorted
+// by the magnitude of their size change (largest first)
+func diffEntries(oldStats, newStats map[string]diffStat) []output.DiffEntry {
+	var entries []output.DiffEntry
+
+	for key, newStat := range newStats {
+		oldStat, existed := oldStats[key]
+		entry := output.DiffEntry{
+			Key:            key,
+			OldCount:       oldStat.count,
+			NewCount:       newStat.count,
+			CountDelta:     newStat.count - oldStat.count,
+			OldSize:        oldStat.size,
+			NewSize:        newStat.size,
+			SizeDelta:      newStat.size - oldStat.size,
+			SizePercentage: percentageChange(oldStat.size, newStat.size),
+		}
+
+		switch {
+		case !existed:
+			entry.Status = "added"
+		case entry.SizeDelta > 0:
+			entry.Status = "grown"
+		case entry.SizeDelta < 0:
+			entry.Status = "shrunk"
+		case entry.CountDelta != 0:
+			entry.Status = "changed"
+		default:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	for key, oldStat := range oldStats {
+		if _, exists := newStats[key]; exists {
+			continue
+		}
+		entries = append(entries, output.DiffEntry{
+			Key:            key,
+			Status:         "removed",
+			OldCount:       oldStat.count,
+			CountDelta:     -oldStat.count,
+			OldSize:        oldStat.size,
+			SizeDelta:      -oldStat.size,
+			SizePercentage: -100,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		di, dj := absInt64(entries[i].SizeDelta), absInt64(entries[j].SizeDelta)
+		if di != dj {
+			return di > dj
+		}
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries
+}
+
+// percentageChange returns the relative change from oldValue to newValue in percent
+func percentageChange(oldValue, newValue int64) float64 {
+	if oldValue == 0 {
+		return 0
+	}
+	return float64(newValue-oldValue) / float64(oldValue) * 100
+}
+
+// absInt64 returns the absolute value of n
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
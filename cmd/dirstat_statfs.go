@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd
+
+package cmd
+
+import (
+	"syscall"
+
+	"amurru/filetools/internal/output"
+)
+
+// filesystemInfo returns the capacity of the filesystem holding path
+func filesystemInfo(path string) *output.FilesystemInfo {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil
+	}
+
+	blockSize := uint64(stat.Bsize)
+	return &output.FilesystemInfo{
+		TotalBytes:     uint64(stat.Blocks) * blockSize,
+		FreeBytes:      uint64(stat.Bfree) * blockSize,
+		AvailableBytes: uint64(stat.Bavail) * blockSize,
+		TotalInodes:    uint64(stat.Files),
+		FreeInodes:     uint64(stat.Ffree),
+	}
+}
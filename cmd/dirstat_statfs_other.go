@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd)
+
+package cmd
+
+import (
+	"amurru/filetools/internal/output"
+)
+
+// filesystemInfo is not supported on this platform
+func filesystemInfo(path string) *output.FilesystemInfo {
+	return nil
+}
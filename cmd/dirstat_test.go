@@ -0,0 +1,779 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"amurru/filetools/internal/categories"
+	"amurru/filetools/internal/exclusions"
+	"amurru/filetools/internal/history"
+	"amurru/filetools/internal/output"
+	"amurru/filetools/internal/rules"
+)
+
+// createDirstatTree creates files relative to a temporary directory and returns its path
+func createDirstatTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tmpDir
+}
+
+func TestAnalyzeDirectory(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{
+		"a.txt":       "hello",
+		"sub/b.txt":   "hello world",
+		"sub/c.log":   "log",
+		"other/d.bin": "0123456789abcdefXYZ",
+	})
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.TotalFiles != 4 {
+		t.Errorf("expected 4 files, got %d", result.TotalFiles)
+	}
+	if result.TotalSize != 38 {
+		t.Errorf("expected total size 38, got %d", result.TotalSize)
+	}
+	if result.LargestFile == nil || result.LargestFile.Path != filepath.Join("other", "d.bin") {
+		t.Errorf("unexpected largest file: %+v", result.LargestFile)
+	}
+	if len(result.FileTypes) != 3 || result.FileTypes[0].Extension != ".bin" {
+		t.Errorf("unexpected file types: %+v", result.FileTypes)
+	}
+}
+
+func TestAnalyzeDirectoryOwnership(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ownership is not available on windows")
+	}
+
+	tmpDir := createDirstatTree(t, map[string]string{
+		"shared.txt": "shared",
+		"tool":       "binary",
+		"plain.txt":  "plain",
+	})
+	if err := os.Chmod(filepath.Join(tmpDir, "shared.txt"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(tmpDir, "tool"), 0755|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Owners) != 1 || result.Owners[0].FileCount != 3 {
+		t.Errorf("expected a single owner with 3 files, got %+v", result.Owners)
+	}
+	if len(result.Groups) != 1 || result.Groups[0].TotalSize != result.TotalSize {
+		t.Errorf("expected a single group owning all bytes, got %+v", result.Groups)
+	}
+	if got := result.Permissions.WorldWritable; len(got) != 1 || got[0] != "shared.txt" {
+		t.Errorf("unexpected world-writable files: %v", got)
+	}
+	if got := result.Permissions.Setuid; len(got) != 1 || got[0] != "tool" {
+		t.Errorf("unexpected setuid files: %v", got)
+	}
+}
+
+func TestAnalyzeDirectoryDetectTypes(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{
+		"image":    "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"main.go":  "package main\n",
+		"notes.md": "some notes\n",
+	})
+	// Symlinks are not sniffed, broken or not
+	if err := os.Symlink("image", filepath.Join(tmpDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing", filepath.Join(tmpDir, "broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{detectTypes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	categories := make(map[string]int)
+	for _, cc := range result.ContentCategories {
+		categories[cc.Category] = cc.Count
+	}
+	if categories["image"] != 1 || categories["source"] != 1 || categories["document"] != 1 {
+		t.Errorf("unexpected content categories: %+v", result.ContentCategories)
+	}
+
+	if len(result.ContentTypes) != 3 {
+		t.Errorf("expected 3 content types, got %+v", result.ContentTypes)
+	}
+}
+
+func TestDiffDirStat(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{
+		"logs/app.log":  "line\n",
+		"docs/a.txt":    "text",
+		"cache/tmp.bin": "cache",
+	})
+
+	oldResult, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := saveSnapshot(oldResult, snapshotPath); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := loadSnapshot(snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot only matches the directory it was taken of
+	if err := checkSnapshotRoot(snapshot, tmpDir); err != nil {
+		t.Errorf("checkSnapshotRoot() = %v for the same directory", err)
+	}
+	if err := checkSnapshotRoot(snapshot, t.TempDir()); err == nil {
+		t.Error("checkSnapshotRoot() accepted another directory")
+	}
+
+	// Grow logs, remove cache and add a new directory
+	if err := os.WriteFile(filepath.Join(tmpDir, "logs", "app.log"), []byte("line\nline\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(tmpDir, "cache")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "media"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "media", "v.mp4"), []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newResult, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := diffDirStat(snapshot, newResult)
+
+	statuses := make(map[string]string)
+	for _, entry := range diff.Directories {
+		statuses[entry.Key] = entry.Status
+	}
+	expected := map[string]string{"logs": "grown", "cache": "removed", "media": "added"}
+	for key, status := range expected {
+		if statuses[key] != status {
+			t.Errorf("directory %s: expected status %s, got %q", key, status, statuses[key])
+		}
+	}
+	if _, ok := statuses["docs"]; ok {
+		t.Error("unchanged directory docs should not be reported")
+	}
+
+	for _, entry := range diff.Directories {
+		if entry.Key == "logs" && (entry.SizeDelta != 5 || entry.SizePercentage != 100) {
+			t.Errorf("unexpected logs delta: %+v", entry)
+		}
+	}
+
+	if diff.OldTotalFiles != 3 || diff.NewTotalFiles != 3 {
+		t.Errorf("unexpected file totals: %d -> %d", diff.OldTotalFiles, diff.NewTotalFiles)
+	}
+}
+
+func TestAnalyzeDirectoryParallelMatchesSequential(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 5; j++ {
+			// Equal sizes across directories exercise the tie-breaking rules
+			files[fmt.Sprintf("d%d/sub%d/file%d.txt", i, j, j)] = fmt.Sprintf("%0*d", j+1, 0)
+			files[fmt.Sprintf("d%d/sub%d-x/skip.log", i, j)] = "log"
+		}
+		files[fmt.Sprintf("d%d/top.bin", i)] = "binary"
+	}
+	tmpDir := createDirstatTree(t, files)
+
+	fileMatchers := exclusions.ParseExclusions("*.log", true)
+	dirMatchers := exclusions.ParseExclusions("sub4", false)
+
+	sequential, err := analyzeDirectory(tmpDir, fileMatchers, dirMatchers, dirstatOptions{parallel: 1, top: 5, typeTop: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Free space is a live value that may change between walks
+	sequential.Filesystem = nil
+
+	for _, parallel := range []int{2, 8} {
+		result, err := analyzeDirectory(tmpDir, fileMatchers, dirMatchers, dirstatOptions{parallel: parallel, top: 5, typeTop: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result.Filesystem = nil
+		if !reflect.DeepEqual(sequential, result) {
+			t.Errorf("parallel=%d result differs from sequential walk:\n%+v\n%+v", parallel, sequential, result)
+		}
+	}
+}
+
+func TestWalkOrderLess(t *testing.T) {
+	sep := string(filepath.Separator)
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"a", "b", true},
+		{"a", "a" + sep + "b", true},
+		{"a" + sep + "b", "a-c", true}, // "a" and its children come before "a-c"
+		{"a-c", "a" + sep + "b", false},
+		{"b", "a" + sep + "z", false},
+	}
+
+	for _, tt := range tests {
+		if got := walkOrderLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("walkOrderLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeDirectoryClutter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+
+	tmpDir := createDirstatTree(t, map[string]string{
+		"data/file.txt":  "content",
+		"data/empty.txt": "",
+	})
+	for _, dir := range []string{"empty", "nested/empty"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("missing.txt", filepath.Join(tmpDir, "data", "broken")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("file.txt", filepath.Join(tmpDir, "data", "inside")); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(tmpDir, "outside")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantEmptyDirs := []string{"empty", filepath.Join("nested", "empty")}
+	if !reflect.DeepEqual(result.EmptyDirectories, wantEmptyDirs) {
+		t.Errorf("empty directories = %v, want %v", result.EmptyDirectories, wantEmptyDirs)
+	}
+	if want := []string{filepath.Join("data", "empty.txt")}; !reflect.DeepEqual(result.EmptyFiles, want) {
+		t.Errorf("empty files = %v, want %v", result.EmptyFiles, want)
+	}
+	if len(result.BrokenSymlinks) != 1 || result.BrokenSymlinks[0].Target != "missing.txt" {
+		t.Errorf("unexpected broken symlinks: %+v", result.BrokenSymlinks)
+	}
+	if len(result.ExternalSymlinks) != 1 || result.ExternalSymlinks[0].Path != "outside" {
+		t.Errorf("unexpected external symlinks: %+v", result.ExternalSymlinks)
+	}
+}
+
+func TestAnalyzeDirectoryEntryHotspots(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{
+		"a.txt":         "a",
+		"big/1.txt":     "1",
+		"big/2.txt":     "2",
+		"big/3.txt":     "3",
+		"deep/x/1.txt":  "1",
+		"deep/x/2.txt":  "2",
+		"deep/x/y/1.go": "1",
+		"deep/x/y/2.go": "2",
+	})
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{top: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Root, 4 directories and 8 files
+	if result.TotalInodes != 13 {
+		t.Errorf("total inodes = %d, want 13", result.TotalInodes)
+	}
+
+	// Ties are broken by walk order, the root comes first
+	wantDirect := []output.DirectoryEntries{
+		{Path: ".", DirectEntries: 3, CumulativeEntries: 12},
+		{Path: "big", DirectEntries: 3, CumulativeEntries: 3},
+	}
+	if !reflect.DeepEqual(result.DirectEntryHotspots, wantDirect) {
+		t.Errorf("direct hot spots = %+v, want %+v", result.DirectEntryHotspots, wantDirect)
+	}
+
+	wantCumulative := []output.DirectoryEntries{
+		{Path: ".", DirectEntries: 3, CumulativeEntries: 12},
+		{Path: "deep", DirectEntries: 1, CumulativeEntries: 6},
+	}
+	if !reflect.DeepEqual(result.CumulativeEntryHotspots, wantCumulative) {
+		t.Errorf("cumulative hot spots = %+v, want %+v", result.CumulativeEntryHotspots, wantCumulative)
+	}
+}
+
+func TestAnalyzeDirectoryHardLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("inode numbers are not available on windows")
+	}
+
+	tmpDir := createDirstatTree(t, map[string]string{"file.txt": "content"})
+	if err := os.Link(filepath.Join(tmpDir, "file.txt"), filepath.Join(tmpDir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Root and one file, the second name shares its inode
+	if result.TotalInodes != 2 {
+		t.Errorf("total inodes = %d, want 2", result.TotalInodes)
+	}
+}
+
+func TestAnalyzeDirectoryCategories(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{
+		"build/app":      "binary!",
+		"logs/app.log":   "log",
+		"logs/old.log":   "log",
+		"src/main.go":    "package main",
+		"notes/todo.txt": "x",
+	})
+
+	config, err := categories.Parse([]byte(`{"categories": [
+		{"name": "build artifacts", "prefixes": ["build"]},
+		{"name": "logs", "extensions": [".log"]},
+		{"name": "media", "extensions": [".jpg"]},
+		{"name": "source", "globs": ["src/**/*.go"]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{categories: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []output.CategoryInfo{
+		{Name: "source", Count: 1, TotalSize: 12},
+		{Name: "build artifacts", Count: 1, TotalSize: 7},
+		{Name: "logs", Count: 2, TotalSize: 6},
+		{Name: categories.Uncategorised, Count: 1, TotalSize: 1},
+		{Name: "media"},
+	}
+	if len(result.Categories) != len(want) {
+		t.Fatalf("got %d categories, want %d: %+v", len(result.Categories), len(want), result.Categories)
+	}
+	for i, category := range result.Categories {
+		category.Percentage = 0
+		if category != want[i] {
+			t.Errorf("category %d = %+v, want %+v", i, category, want[i])
+		}
+	}
+}
+
+func TestEstimateCompressedSize(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repetitive := filepath.Join(tmpDir, "repetitive.txt")
+	if err := os.WriteFile(repetitive, []byte(strings.Repeat("all work and no play ", 10000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	random := filepath.Join(tmpDir, "random.bin")
+	data := make([]byte, 200000)
+	rand.New(rand.NewSource(1)).Read(data)
+	if err := os.WriteFile(random, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sampleSize := range []int64{4096, 1 << 20} {
+		compressed, err := estimateCompressedSize(repetitive, 210000, sampleSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compressed <= 0 || compressed > 210000/10 {
+			t.Errorf("sample %d: repetitive text estimated at %d bytes, want a high ratio", sampleSize, compressed)
+		}
+
+		compressed, err = estimateCompressedSize(random, int64(len(data)), sampleSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compressed != int64(len(data)) {
+			t.Errorf("sample %d: random data estimated at %d bytes, want %d", sampleSize, compressed, len(data))
+		}
+	}
+}
+
+func TestAnalyzeDirectoryCompression(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{
+		"a.txt": strings.Repeat("a", 1000),
+		"b.txt": strings.Repeat("b", 1000),
+		"empty": "",
+	})
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{sampleSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := result.Compression
+	if c == nil {
+		t.Fatal("expected a compression estimate")
+	}
+	if c.TotalSize != 2000 || c.CompressedSize <= 0 || c.CompressedSize >= 200 {
+		t.Errorf("unexpected totals: %+v", c)
+	}
+	if len(c.FileTypes) != 2 || c.FileTypes[0].Extension != ".txt" || c.FileTypes[0].Count != 2 {
+		t.Errorf("unexpected file types: %+v", c.FileTypes)
+	}
+
+	result, err = analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Compression != nil {
+		t.Error("compression estimated without being requested")
+	}
+}
+
+func TestAnalyzeDirectoryGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tmpDir := createDirstatTree(t, map[string]string{
+		".gitignore":           "*.log\n/build/\n",
+		"main.go":              "package main",
+		"notes.txt":            "untracked",
+		"debug.log":            "ignored",
+		"build/app":            "ignored binary",
+		"docs/.gitignore":      "!keep.log\n",
+		"docs/keep.log":        "kept",
+		"vendor/lib/.gitkeep":  "",
+		"vendor/lib/lib.go":    "package lib",
+		"vendor/lib/extra.txt": "untracked",
+	})
+	gitCmd := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	gitCmd(tmpDir, "init", "-q")
+	gitCmd(tmpDir, "add", ".gitignore", "main.go", "docs")
+	nested := filepath.Join(tmpDir, "vendor", "lib")
+	gitCmd(nested, "init", "-q")
+	gitCmd(nested, "add", "lib.go")
+
+	var results []*output.DirStatResult
+	for _, parallel := range []int{1, 4} {
+		result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{git: true, parallel: parallel})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, result)
+	}
+	if !reflect.DeepEqual(results[0].Git, results[1].Git) {
+		t.Errorf("parallel git stats differ:\n%+v\n%+v", results[0].Git, results[1].Git)
+	}
+
+	repos := results[0].Git.Repositories
+	if len(repos) != 2 || repos[0].Path != "." || repos[1].Path != filepath.Join("vendor", "lib") {
+		t.Fatalf("unexpected repositories: %+v", repos)
+	}
+
+	outer := repos[0]
+	if outer.TrackedFiles != 4 || outer.UntrackedFiles != 1 || outer.IgnoredFiles != 2 {
+		t.Errorf("outer worktree: %+v", outer.GitCounts)
+	}
+	if outer.IgnoredSize != int64(len("ignored")+len("ignored binary")) {
+		t.Errorf("outer ignored size = %d", outer.IgnoredSize)
+	}
+	if outer.GitDirFiles == 0 || outer.GitDirSize == 0 {
+		t.Errorf("outer .git not measured: %+v", outer.GitCounts)
+	}
+
+	inner := repos[1]
+	if inner.TrackedFiles != 1 || inner.UntrackedFiles != 2 || inner.IgnoredFiles != 0 {
+		t.Errorf("nested worktree: %+v", inner.GitCounts)
+	}
+
+	// Analyzing a subdirectory still uses the enclosing worktree's index and ignore files
+	sub, err := analyzeDirectory(filepath.Join(tmpDir, "docs"), nil, nil, dirstatOptions{git: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.Git.Repositories) != 1 || sub.Git.TrackedFiles != 2 || sub.Git.IgnoredFiles != 0 {
+		t.Errorf("subdirectory: %+v", sub.Git)
+	}
+}
+
+func TestAnalyzeDirectoryRules(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{
+		"logs/app.log":     strings.Repeat("x", 2048),
+		"logs/old/app.log": strings.Repeat("x", 2048),
+		"core/app.core":    "dump",
+		"src/main.go":      "package main",
+	})
+
+	var parsed []rules.Rule
+	for _, text := range []string{"logs/ > 3K", "logs/ files > 2", "*.core present", "*.bak present", "files >= 4"} {
+		rule, err := rules.Parse(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, rule)
+	}
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{rules: parsed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Rules == nil || result.Rules.Checked != 5 {
+		t.Fatalf("unexpected rule report: %+v", result.Rules)
+	}
+	var violated []string
+	for _, violation := range result.Rules.Violations {
+		violated = append(violated, violation.Rule)
+	}
+	if want := []string{"logs/ > 3K", "*.core present", "files >= 4"}; !reflect.DeepEqual(violated, want) {
+		t.Errorf("violated rules = %v, want %v", violated, want)
+	}
+
+	result, err = analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rules != nil {
+		t.Error("rules reported without any configured")
+	}
+}
+
+func TestEntryType(t *testing.T) {
+	tests := []struct {
+		mode    os.FileMode
+		want    string
+		special bool
+	}{
+		{0644, "regular", false},
+		{os.ModeDir | 0755, "directory", false},
+		{os.ModeSymlink | 0777, "symlink", false},
+		{os.ModeNamedPipe | 0644, "named_pipe", true},
+		{os.ModeSocket | 0755, "socket", true},
+		{os.ModeDevice | 0660, "block_device", true},
+		{os.ModeDevice | os.ModeCharDevice | 0666, "char_device", true},
+		{os.ModeIrregular, "irregular", true},
+	}
+
+	for _, tt := range tests {
+		if got := entryType(tt.mode); got != tt.want {
+			t.Errorf("entryType(%v) = %q, want %q", tt.mode, got, tt.want)
+		}
+		if got := isSpecialFile(tt.mode); got != tt.special {
+			t.Errorf("isSpecialFile(%v) = %v, want %v", tt.mode, got, tt.special)
+		}
+	}
+}
+
+func TestAnalyzeDirectoryTypeDrillDown(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{
+		"a/one.log":   "1111",
+		"a/two.log":   "22",
+		"b/three.log": "333",
+		"b/four.log":  "4444",
+		"c/five.log":  "5",
+		"c/notes.txt": "text",
+	})
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{typeTop: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs *output.FileType
+	for i := range result.FileTypes {
+		if result.FileTypes[i].Extension == ".log" {
+			logs = &result.FileTypes[i]
+		}
+	}
+	if logs == nil {
+		t.Fatal("no .log file type")
+	}
+
+	// Equal sizes are listed in walk order
+	wantFiles := []output.FileInfo{
+		{Name: "one.log", Size: 4, Path: filepath.Join("a", "one.log")},
+		{Name: "four.log", Size: 4, Path: filepath.Join("b", "four.log")},
+	}
+	if !reflect.DeepEqual(logs.LargestFiles, wantFiles) {
+		t.Errorf("largest files = %+v, want %+v", logs.LargestFiles, wantFiles)
+	}
+	wantDirs := []output.DirectoryInfo{
+		{Path: "b", FileCount: 2, TotalSize: 7, Percentage: 50},
+		{Path: "a", FileCount: 2, TotalSize: 6, Percentage: 6.0 / 14 * 100},
+	}
+	if !reflect.DeepEqual(logs.Directories, wantDirs) {
+		t.Errorf("directories = %+v, want %+v", logs.Directories, wantDirs)
+	}
+
+	// The drill-down is only collected on request
+	result, err = analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ft := range result.FileTypes {
+		if ft.LargestFiles != nil || ft.Directories != nil {
+			t.Errorf("unexpected drill-down for %s: %+v", ft.Extension, ft)
+		}
+	}
+}
+
+func TestLinearTrend(t *testing.T) {
+	tests := []struct {
+		xs, ys []float64
+		want   float64
+	}{
+		{nil, nil, 0},
+		{[]float64{0}, []float64{5}, 0},
+		{[]float64{0, 1, 2}, []float64{10, 20, 30}, 10},
+		{[]float64{0, 1, 2, 3}, []float64{0, 3, 1, 5}, 1.3},
+		{[]float64{2, 2}, []float64{1, 5}, 0}, // No time elapsed
+	}
+
+	for _, tt := range tests {
+		if got := linearTrend(tt.xs, tt.ys); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("linearTrend(%v, %v) = %v, want %v", tt.xs, tt.ys, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var records []history.Record
+	for day := 0; day < 4; day++ {
+		records = append(records, history.Record{
+			Time:       start.AddDate(0, 0, day),
+			Root:       "/srv",
+			TotalFiles: 10 + day,
+			TotalSize:  int64(1000 + 300*day),
+			Filesystem: &output.FilesystemInfo{TotalBytes: 10000, AvailableBytes: uint64(4000 - 500*day)},
+			Directories: map[string]int64{
+				"logs":   int64(100 + 200*day),
+				"cache":  int64(400 + 100*day),
+				"static": 500,
+			},
+		})
+	}
+	// A directory that only appeared in the last run
+	records[3].Directories["tmp"] = 30
+
+	result := analyzeHistory("/srv", records, 2)
+
+	if len(result.Points) != 4 || result.Points[3].Time != "2024-01-04T00:00:00Z" || result.Points[3].AvailableBytes != 2500 {
+		t.Errorf("points = %+v", result.Points)
+	}
+	if result.GrowthPerDay != 300 {
+		t.Errorf("growth = %v, want 300", result.GrowthPerDay)
+	}
+
+	// Unchanged directories are left out and the list is limited to top
+	wantDirs := []output.DirectoryGrowth{
+		{Path: "logs", FirstSize: 100, LastSize: 700, GrowthPerDay: 200},
+		{Path: "cache", FirstSize: 400, LastSize: 700, GrowthPerDay: 100},
+	}
+	if !reflect.DeepEqual(result.Directories, wantDirs) {
+		t.Errorf("directories = %+v, want %+v", result.Directories, wantDirs)
+	}
+
+	want := &output.FillProjection{
+		TotalBytes:     10000,
+		AvailableBytes: 2500,
+		ConsumedPerDay: 500,
+		Filling:        true,
+		DaysUntilFull:  5,
+		FullAt:         "2024-01-09T00:00:00Z",
+	}
+	if !reflect.DeepEqual(result.Projection, want) {
+		t.Errorf("projection = %+v, want %+v", result.Projection, want)
+	}
+
+	// Freeing space never fills the filesystem
+	for i := range records {
+		records[i].Filesystem.AvailableBytes = uint64(1000 + 100*i)
+	}
+	if p := analyzeHistory("/srv", records, 10).Projection; p.Filling || p.FullAt != "" || p.ConsumedPerDay != -100 {
+		t.Errorf("projection = %+v, want not filling", p)
+	}
+}
+
+func TestHistoryRecord(t *testing.T) {
+	result := &output.DirStatResult{
+		TotalFiles:  3,
+		TotalSize:   60,
+		Directories: []output.DirectoryInfo{{Path: "a", FileCount: 2, TotalSize: 50}},
+	}
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	record := historyRecord("/srv", result, at, 0)
+	if !record.Time.Equal(at) || record.Time.Location() != time.UTC {
+		t.Errorf("time = %v, want %v in UTC", record.Time, at)
+	}
+	want := map[string]int64{"a": 50, ".": 10}
+	if !reflect.DeepEqual(record.Directories, want) {
+		t.Errorf("directories = %v, want %v", record.Directories, want)
+	}
+
+	// Deeper directories count towards their parent at the recorded depth
+	result.Directories = []output.DirectoryInfo{
+		{Path: "a", TotalSize: 10},
+		{Path: filepath.Join("a", "b"), TotalSize: 20},
+		{Path: filepath.Join("a", "b", "c"), TotalSize: 15},
+		{Path: filepath.Join("a", "d", "e", "f"), TotalSize: 5},
+	}
+	record = historyRecord("/srv", result, at, 2)
+	want = map[string]int64{".": 10, "a": 10, filepath.Join("a", "b"): 35, filepath.Join("a", "d"): 5}
+	if !reflect.DeepEqual(record.Directories, want) || record.Depth != 2 {
+		t.Errorf("directories = %v at depth %d, want %v at depth 2", record.Directories, record.Depth, want)
+	}
+}
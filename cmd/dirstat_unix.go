@@ -0,0 +1,66 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// fileOwner returns the numeric user and group IDs owning a file
+func fileOwner(info os.FileInfo) (uid, gid string, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", "", false
+	}
+	return strconv.FormatUint(uint64(stat.Uid), 10), strconv.FormatUint(uint64(stat.Gid), 10), true
+}
+
+// Groups of the current user, looked up once for isReadable
+var (
+	userGroupsOnce sync.Once
+	userGroups     map[uint64]bool
+)
+
+// isReadable reports whether the current user may read a file, judged from
+// its owner and permission bits without another system call per file. ACLs
+// are not taken into account.
+func isReadable(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+	userGroupsOnce.Do(func() {
+		userGroups = map[uint64]bool{uint64(os.Getegid()): true}
+		groups, _ := os.Getgroups()
+		for _, gid := range groups {
+			userGroups[uint64(gid)] = true
+		}
+	})
+	return canRead(info.Mode().Perm(), uint64(stat.Uid), uint64(stat.Gid), os.Geteuid(), userGroups)
+}
+
+// canRead reports whether a user with the uid and groups may read a file of
+// the owner, group and permission bits. Root may read any file.
+func canRead(perm os.FileMode, owner, group uint64, uid int, groups map[uint64]bool) bool {
+	switch {
+	case uid == 0:
+		return true
+	case owner == uint64(uid):
+		return perm&0400 != 0
+	case groups[group]:
+		return perm&0040 != 0
+	}
+	return perm&0004 != 0
+}
+
+// inodeKey identifies the inode of a file and reports its hard link count
+func inodeKey(info os.FileInfo) (key [2]uint64, nlink uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return key, 0, false
+	}
+	return [2]uint64{uint64(stat.Dev), uint64(stat.Ino)}, uint64(stat.Nlink), true
+}
@@ -0,0 +1,80 @@
+//go:build linux || darwin || freebsd
+
+package cmd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"amurru/filetools/internal/output"
+)
+
+func TestAnalyzeDirectorySpecialFiles(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{"data/file.txt": "content"})
+	if err := syscall.Mkfifo(filepath.Join(tmpDir, "data", "pipe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("unix", filepath.Join(tmpDir, "s.sock"))
+	if err != nil {
+		t.Skipf("could not create a unix socket: %v", err)
+	}
+	defer listener.Close()
+
+	// Opening the pipe to detect its type or compress it would block
+	opts := dirstatOptions{detectTypes: true, sampleSize: 1024}
+	result, err := analyzeDirectory(tmpDir, nil, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.TotalFiles != 1 || result.TotalSize != 7 {
+		t.Errorf("got %d files of %d bytes, want 1 file of 7 bytes", result.TotalFiles, result.TotalSize)
+	}
+	wantTypes := []output.EntryTypeCount{
+		{Type: "regular", Count: 1},
+		{Type: "directory", Count: 1},
+		{Type: "named_pipe", Count: 1},
+		{Type: "socket", Count: 1},
+	}
+	if !reflect.DeepEqual(result.EntryTypes, wantTypes) {
+		t.Errorf("entry types = %+v, want %+v", result.EntryTypes, wantTypes)
+	}
+	wantSpecial := []output.SpecialFile{
+		{Path: filepath.Join("data", "pipe"), Type: "named_pipe"},
+		{Path: "s.sock", Type: "socket"},
+	}
+	if !reflect.DeepEqual(result.SpecialFiles, wantSpecial) {
+		t.Errorf("special files = %+v, want %+v", result.SpecialFiles, wantSpecial)
+	}
+
+	// Root, data, file, pipe and socket
+	if result.TotalInodes != 5 {
+		t.Errorf("total inodes = %d, want 5", result.TotalInodes)
+	}
+}
+
+func TestCanRead(t *testing.T) {
+	groups := map[uint64]bool{100: true}
+	tests := []struct {
+		perm         os.FileMode
+		owner, group uint64
+		uid          int
+		want         bool
+	}{
+		{0400, 1000, 1, 1000, true},
+		{0044, 1000, 100, 1000, false}, // The owner bits apply to the owner
+		{0040, 2000, 100, 1000, true},
+		{0404, 2000, 100, 1000, false}, // The group bits apply to members
+		{0004, 2000, 200, 1000, true},
+		{0000, 2000, 200, 0, true},
+	}
+	for _, tt := range tests {
+		if got := canRead(tt.perm, tt.owner, tt.group, tt.uid, groups); got != tt.want {
+			t.Errorf("canRead(%o, %d, %d, %d) = %t, want %t", tt.perm, tt.owner, tt.group, tt.uid, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+//go:build linux
+
+package cmd
+
+import (
+	"strings"
+	"syscall"
+)
+
+// xattrsSupported reports whether readXattrs is implemented on this platform
+const xattrsSupported = true
+
+// Linux stores POSIX ACLs as these extended attributes
+const (
+	aclAccessXattr  = "system.posix_acl_access"
+	aclDefaultXattr = "system.posix_acl_default"
+)
+
+// readXattrs returns the extended attributes of the file at path. Symlinks
+// are followed, so callers should not pass them.
+func readXattrs(path string) (xattrInfo, error) {
+	var info xattrInfo
+
+	size, err := syscall.Listxattr(path, nil)
+	if err == syscall.ENOTSUP {
+		return info, nil
+	} else if err != nil || size == 0 {
+		return info, err
+	}
+	list := make([]byte, size)
+	if size, err = syscall.Listxattr(path, list); err != nil {
+		return info, err
+	}
+
+	for _, name := range strings.Split(string(list[:size]), "\x00") {
+		if name == "" {
+			continue
+		}
+		valueSize, err := syscall.Getxattr(path, name, nil)
+		if err == syscall.ENODATA {
+			continue // Removed since it was listed
+		} else if err != nil {
+			return info, err
+		}
+		info.count++
+		info.bytes += int64(len(name) + valueSize)
+		if name == aclAccessXattr || name == aclDefaultXattr {
+			info.acl = true
+		}
+	}
+	return info, nil
+}
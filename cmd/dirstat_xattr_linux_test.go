@@ -0,0 +1,94 @@
+//go:build linux
+
+package cmd
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"amurru/filetools/internal/output"
+)
+
+func TestAnalyzeDirectoryXattrs(t *testing.T) {
+	tmpDir := createDirstatTree(t, map[string]string{
+		"plain.txt":  "a",
+		"tagged.txt": "b",
+	})
+	if err := syscall.Setxattr(filepath.Join(tmpDir, "tagged.txt"), "user.comment", []byte("hello"), 0); err != nil {
+		t.Skipf("extended attributes are not supported here: %v", err)
+	}
+
+	result, err := analyzeDirectory(tmpDir, nil, nil, dirstatOptions{xattrs: true, top: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Security modules may add their own attributes, so only check ours is counted
+	x := result.Xattrs
+	if x == nil {
+		t.Fatal("expected extended attribute statistics")
+	}
+	var tagged *output.XattrFile
+	for i := range x.Largest {
+		if x.Largest[i].Path == "tagged.txt" {
+			tagged = &x.Largest[i]
+		}
+	}
+	if tagged == nil {
+		t.Fatalf("tagged.txt not reported: %+v", x.Largest)
+	}
+	if tagged.Count < 1 || tagged.Bytes < int64(len("user.comment")+len("hello")) || tagged.ACL {
+		t.Errorf("tagged.txt = %+v, want at least 1 attribute of 17 bytes and no ACL", *tagged)
+	}
+	if x.FilesWithXattrs < 1 || x.TotalBytes < tagged.Bytes {
+		t.Errorf("summary = %+v, inconsistent with %+v", *x, *tagged)
+	}
+
+	// Without the option nothing is read
+	result, err = analyzeDirectory(tmpDir, nil, nil, dirstatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Xattrs != nil {
+		t.Errorf("xattrs = %+v, want nil", result.Xattrs)
+	}
+}
+
+func TestReadXattrsACL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An extended ACL granting user 1000 read access, in the kernel's format:
+	// a version header followed by (tag, permissions, id) entries
+	acl := binary.LittleEndian.AppendUint32(nil, 2)
+	for _, entry := range []struct {
+		tag, perm uint16
+		id        uint32
+	}{
+		{0x01, 6, 0xffffffff}, // Owning user
+		{0x02, 4, 1000},       // Named user
+		{0x04, 4, 0xffffffff}, // Owning group
+		{0x10, 4, 0xffffffff}, // Mask
+		{0x20, 4, 0xffffffff}, // Others
+	} {
+		acl = binary.LittleEndian.AppendUint16(acl, entry.tag)
+		acl = binary.LittleEndian.AppendUint16(acl, entry.perm)
+		acl = binary.LittleEndian.AppendUint32(acl, entry.id)
+	}
+	if err := syscall.Setxattr(path, aclAccessXattr, acl, 0); err != nil {
+		t.Skipf("POSIX ACLs are not supported here: %v", err)
+	}
+
+	info, err := readXattrs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.acl || info.count < 1 || info.bytes < int64(len(aclAccessXattr)+len(acl)) {
+		t.Errorf("readXattrs = %+v, want an ACL of at least %d bytes", info, len(aclAccessXattr)+len(acl))
+	}
+}
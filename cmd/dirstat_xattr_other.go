@@ -0,0 +1,11 @@
+//go:build !linux
+
+package cmd
+
+// xattrsSupported reports whether readXattrs is implemented on this platform
+const xattrsSupported = false
+
+// readXattrs is not supported on this platform
+func readXattrs(path string) (xattrInfo, error) {
+	return xattrInfo{}, nil
+}
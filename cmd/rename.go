@@ -10,17 +10,26 @@ import (
 
 	"amurru/filetools/internal/exclusions"
 	"amurru/filetools/internal/output"
+	"amurru/filetools/internal/pathmatch"
 	"github.com/spf13/cobra"
 )
 
 // renameCmd represents the rename command
 var renameCmd = &cobra.Command{
 	Use:   "rename [directory]",
-	Short: "Rename files in a directory using pattern matching and sed-like replacements",
+	Short: "Rename files in a directory using pattern matching and sed-like replacements or templates",
 	Long: `Rename files in a directory using pattern matching and sed-like replacements.
 
 This command will traverse the specified directory (or current directory if none provided)
 and rename files that match the given pattern using a sed-style replacement expression.
+--max-depth limits how deep it goes, 1 being the directory itself only, and
+--min-depth leaves the entries above a depth alone.
+
+Instead of --match, --match-regex matches names with a regular expression,
+and --match-path matches the path relative to the directory with a glob in
+which ** stands for any number of directories. With --sed-path, --sed is
+applied to the relative path instead of the name, and files are moved to
+other directories, which are created as needed.
 
 Note: Glob patterns must be quoted to prevent shell expansion.
 
@@ -34,26 +43,166 @@ Examples:
     # General replacement
     filetools rename --match "*.txt" --sed "s/draft/final/g" /docs
 
+    # Swap two parts of the name and capitalize the first
+    filetools rename --match "*-*.txt" --sed 's/^(\w+)-(\w+)/\u\2-\1/' /docs
+
+    # Sort 2023-01-report.txt into 2023/01-report.txt
+    filetools rename --match "*.txt" --sed-path --sed 's|^([0-9]{4})-|\1/|' /docs
+
+The --sed expression follows sed -E: any delimiter (s|a|b|), the flags g, i
+and N (replace the Nth match), & and \1-\9 in the replacement, \U, \L, \E,
+\u and \l for case conversion, and several expressions separated by ';'.
+Patterns use Go regular expression syntax.
+
+Instead of --sed, --template builds new names from the tokens {name}, {ext}
+(with the dot), {parent}, {n} or {n:03} (counter in walk order), {mtime} or
+{mtime:2006-01-02} (Go time layout), {size} and {hash} or {hash:8} (SHA-256).
+Values can be filtered with lower, upper and title: {name|lower}.
+
+Photos and videos add {taken} or {taken:2006-01-02} (EXIF DateTimeOriginal of
+JPEG and TIFF files, creation time of MP4 and MOV files), {model} (camera
+model) and {gps} or {gps:text} (the text if the file records a position).
+Files without the metadata are reported with an error and not renamed.
+--by-exif-date is a preset for the template ` + "`" + exifDateTemplate + "`" + `.
+
+    # Name photos by date and a counter
+    filetools rename --match "*.JPG" --template "{mtime:2006-01-02}_{n:03}{ext|lower}" /photos
+
+    # Name photos by the date they were taken
+    filetools rename --match "*.jpg" --by-exif-date /photos
+
+Only files are renamed unless --type is d (directories) or all. The contents
+of a directory are renamed before the directory, and are reported at their
+final path.
+
+    # Rename the draft folders and the files in them
+    filetools rename --type all --match "draft*" --sed "s/draft/final/" /docs
+
+For irregular renames, --edit opens the list of matched files in $VISUAL or
+$EDITOR, and --plan reads old and new paths from a CSV file or a JSON file,
+such as the JSON output of a dry run.
+
+    # Edit the names, review the dry run, then apply it
+    filetools rename --match "*.jpg" --edit --output json --file plan.json /photos
+    filetools rename --plan plan.json --force /photos
+
+All renames are planned before any file is touched, so the dry run shows what
+--force does. Renames that would give several files the same name, or that
+target a file that is not itself renamed away, are reported as errors and
+skipped. With --overwrite, existing files are replaced instead, and the
+renames replacing one are marked as such. Chains such as a→b, b→c are applied
+from their end, and swaps such as a→b, b→a go through a temporary name.
+
 Dry-run mode is enabled by default for safety. Use --force to perform actual renames.
+
+With --atomic, either all files are renamed or none: a plan with conflicts is
+refused as a whole, and if a rename fails, the renames already done are reverted in
+reverse order.
+
+Every run with --force writes an undo journal of the renames it performed, so
+they can be reverted with "filetools rename undo".
 `,
 	Run: runRename,
 }
 
+// matches reports whether the entry at a relative path is selected by the
+// --match, --match-regex or --match-path pattern
+func (o renameOptions) matches(relPath string) (bool, error) {
+	switch {
+	case o.matchPath != "":
+		return pathmatch.Match(o.matchPath, filepath.ToSlash(relPath))
+	case o.matchRegex != nil:
+		return o.matchRegex.MatchString(filepath.Base(relPath)), nil
+	}
+	return filepath.Match(o.match, filepath.Base(relPath))
+}
+
+// overwrites reports whether renames may replace existing files. A replaced
+// file could not be restored if an atomic run is rolled back, so atomic runs
+// always treat existing files as conflicts.
+func (o renameOptions) overwrites() bool {
+	return o.overwrite && !o.atomic
+}
+
+// renames reports whether entries of the type of info are renamed
+func (o renameOptions) renames(info os.FileInfo) bool {
+	switch o.entryType {
+	case "d":
+		return info.IsDir()
+	case "all":
+		return true
+	}
+	return !info.IsDir()
+}
+
+// exifDateTemplate is the template of --by-exif-date
+const exifDateTemplate = "{taken:2006-01-02_150405}{ext|lower}"
+
+// renameOptions holds the settings of a rename run
+type renameOptions struct {
+	match      string          // Glob matched against file names
+	matchRegex *regexp.Regexp  // Matched against file names instead of match if set
+	matchPath  string          // Glob matched against relative paths instead of match if set
+	entryType  string          // Entries renamed: f (files, the default), d (directories) or all
+	minDepth   int             // Entries above this depth are not renamed, 0 for no limit
+	maxDepth   int             // Entries below this depth are not walked, 0 for no limit
+	sed        sedScript       // Substitutions applied to file names
+	sedPath    bool            // Apply sed to relative paths, moving files between directories
+	template   *renameTemplate // New names built from file metadata, replaces sed if set
+	edit       bool            // New paths are edited in an editor, replaces sed if set
+
+	dryRun     bool   // Only plan the renames
+	overwrite  bool   // Replace existing files that are not renamed away
+	atomic     bool   // Rename all files or none
+	journalDir string // Where the undo journal is written, none if empty
+}
+
 var (
 	matchPattern   string
+	matchRegex     string
+	matchPath      string
 	sedExpression  string
+	sedPath        bool
+	editPlan       bool
+	planFile       string
+	nameTemplate   string
+	byExifDate     bool
+	renameType     string
+	minDepth       int
+	maxDepth       int
 	forceOverwrite bool
+	replaceFiles   bool
+	journalDir     string
+	noJournal      bool
+	atomicRename   bool
 )
 
 func init() {
 	rootCmd.AddCommand(renameCmd)
 
 	// Command-specific flags
-	renameCmd.Flags().StringVar(&matchPattern, "match", "", "File pattern to match (glob, required)")
-	renameCmd.Flags().StringVar(&sedExpression, "sed", "", "Sed-style replacement expression (e.g., s/old/new/g, required)")
+	renameCmd.Flags().StringVar(&matchPattern, "match", "", "File pattern to match against names (glob)")
+	renameCmd.Flags().StringVar(&matchRegex, "match-regex", "", "Regular expression to match against names")
+	renameCmd.Flags().StringVar(&matchPath, "match-path", "", "Pattern to match against paths relative to the directory (glob, ** for any directories)")
+	renameCmd.Flags().StringVar(&sedExpression, "sed", "", "Sed-style replacement expression (e.g., s/old/new/g)")
+	renameCmd.Flags().BoolVar(&sedPath, "sed-path", false, "Apply --sed to the path relative to the directory, moving files and creating directories as needed")
+	renameCmd.Flags().StringVar(&nameTemplate, "template", "", "Template for the new names (e.g., {mtime:2006-01-02}_{n:03}{ext|lower})")
+	renameCmd.Flags().BoolVar(&byExifDate, "by-exif-date", false, "Name files by the date they were taken (template "+exifDateTemplate+")")
+	renameCmd.Flags().BoolVar(&editPlan, "edit", false, "Edit the new paths of the matched files in $VISUAL or $EDITOR")
+	renameCmd.Flags().StringVar(&planFile, "plan", "", "Rename the files listed in a CSV or JSON file of old and new paths, instead of matching files")
+	renameCmd.Flags().StringVar(&renameType, "type", "f", "Entries to rename: f (files), d (directories) or all")
+	renameCmd.Flags().IntVar(&minDepth, "min-depth", 0, "Only rename entries at this depth or deeper, 1 being the entries of the directory (0 for no limit)")
+	renameCmd.Flags().IntVar(&maxDepth, "max-depth", 0, "Do not descend deeper than this, 1 being the entries of the directory only (0 for no limit)")
+	renameCmd.PersistentFlags().StringVar(&journalDir, "journal-dir", "", "Directory of the undo journals (default: filetools/rename-journal in the user configuration directory)")
+	renameCmd.PersistentFlags().BoolVar(&atomicRename, "atomic", false, "Rename all files or none: refuse plans with conflicts and revert all renames if one fails")
 	renameCmd.Flags().BoolVar(&forceOverwrite, "force", false, "Perform actual renames (disables dry-run)")
-	renameCmd.MarkFlagRequired("match")
-	renameCmd.MarkFlagRequired("sed")
+	renameCmd.Flags().BoolVar(&replaceFiles, "overwrite", false, "Replace existing files that are not renamed by the same run, instead of reporting them as conflicts")
+	renameCmd.Flags().BoolVar(&noJournal, "no-journal", false, "Do not write an undo journal")
+	renameCmd.MarkFlagsOneRequired("match", "match-regex", "match-path", "plan")
+	renameCmd.MarkFlagsMutuallyExclusive("match", "match-regex", "match-path", "plan")
+	renameCmd.MarkFlagsOneRequired("sed", "template", "by-exif-date", "edit", "plan")
+	renameCmd.MarkFlagsMutuallyExclusive("sed", "template", "by-exif-date", "edit", "plan")
+	renameCmd.MarkFlagsMutuallyExclusive("sed-path", "template", "by-exif-date", "edit", "plan")
 }
 
 // runRename executes the rename command
@@ -75,19 +224,95 @@ func runRename(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	// Parse sed expression
-	sedRegex, replacement, global, err := parseSedExpression(sedExpression)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing sed expression: %v\n", err)
+	// Validate entry type
+	if renameType != "f" && renameType != "d" && renameType != "all" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported type '%s'. Supported: f, d, all\n", renameType)
 		os.Exit(1)
 	}
 
+	// Validate depth limits
+	if minDepth < 0 || maxDepth < 0 {
+		fmt.Fprintf(os.Stderr, "Error: depth limits cannot be negative\n")
+		os.Exit(1)
+	}
+	if maxDepth > 0 && minDepth > maxDepth {
+		fmt.Fprintf(os.Stderr, "Error: --min-depth %d is greater than --max-depth %d\n", minDepth, maxDepth)
+		os.Exit(1)
+	}
+
+	// A file replaced by an atomic run could not be restored by a rollback
+	if replaceFiles && atomicRename {
+		fmt.Fprintf(os.Stderr, "Error: --overwrite cannot be used with --atomic\n")
+		os.Exit(1)
+	}
+
+	// --sed-path moves files between directories, which does not mix with
+	// renaming the directories themselves
+	if sedPath && renameType != "f" {
+		fmt.Fprintf(os.Stderr, "Error: --sed-path only moves files and cannot be used with --type %s\n", renameType)
+		os.Exit(1)
+	}
+
+	// Parse sed expression or template
+	opts := renameOptions{
+		match:     matchPattern,
+		matchPath: matchPath,
+		entryType: renameType,
+		minDepth:  minDepth,
+		maxDepth:  maxDepth,
+		sedPath:   sedPath,
+		edit:      editPlan,
+		dryRun:    isDryRun,
+		overwrite: replaceFiles,
+		atomic:    atomicRename,
+	}
+	templateText := nameTemplate
+	if byExifDate {
+		templateText = exifDateTemplate
+	}
+	var err error
+	if matchRegex != "" {
+		if opts.matchRegex, err = regexp.Compile(matchRegex); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid match regex: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if matchPath != "" {
+		if err := pathmatch.Validate(matchPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid match path: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if templateText != "" {
+		if opts.template, err = parseRenameTemplate(templateText); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing template: %v\n", err)
+			os.Exit(1)
+		}
+	} else if sedExpression != "" {
+		if opts.sed, err = parseSedExpression(sedExpression); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing sed expression: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Parse exclusion patterns
 	fileMatchers := exclusions.ParseExclusions(excludeFilePatterns, true)
 	dirMatchers := exclusions.ParseExclusions(excludeDirPatterns, false)
 
-	// Perform rename operations
-	operations, exclusionsList, err := performRenames(rootDir, matchPattern, sedRegex, replacement, global, isDryRun, forceOverwrite, fileMatchers, dirMatchers)
+	if !noJournal {
+		if opts.journalDir, err = renameJournalDir(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Perform rename operations, from the plan file if given
+	var result *output.RenameResult
+	if planFile != "" {
+		result, err = importRenames(rootDir, planFile, opts)
+	} else {
+		result, err = performRenames(rootDir, fileMatchers, dirMatchers, opts)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error performing renames: %v\n", err)
 		os.Exit(1)
@@ -106,11 +331,42 @@ func runRename(cmd *cobra.Command, args []string) {
 	formatter := output.NewFormatter(format)
 
 	// Create metadata
-	flags := []output.Flag{
-		{Name: "match", Value: matchPattern},
-		{Name: "sed", Value: sedExpression},
-		{Name: "dry-run", Value: fmt.Sprintf("%t", isDryRun)},
+	var flags []output.Flag
+	switch {
+	case planFile != "":
+		flags = append(flags, output.Flag{Name: "plan", Value: planFile})
+	case matchPath != "":
+		flags = append(flags, output.Flag{Name: "match-path", Value: matchPath})
+	case matchRegex != "":
+		flags = append(flags, output.Flag{Name: "match-regex", Value: matchRegex})
+	default:
+		flags = append(flags, output.Flag{Name: "match", Value: matchPattern})
+	}
+	switch {
+	case planFile != "":
+		// New paths come from the plan
+	case editPlan:
+		flags = append(flags, output.Flag{Name: "edit", Value: "true"})
+	case byExifDate:
+		flags = append(flags, output.Flag{Name: "by-exif-date", Value: "true"})
+	case nameTemplate != "":
+		flags = append(flags, output.Flag{Name: "template", Value: nameTemplate})
+	default:
+		flags = append(flags, output.Flag{Name: "sed", Value: sedExpression})
+	}
+	if sedPath {
+		flags = append(flags, output.Flag{Name: "sed-path", Value: "true"})
 	}
+	if renameType != "f" {
+		flags = append(flags, output.Flag{Name: "type", Value: renameType})
+	}
+	if minDepth > 0 {
+		flags = append(flags, output.Flag{Name: "min-depth", Value: fmt.Sprintf("%d", minDepth)})
+	}
+	if maxDepth > 0 {
+		flags = append(flags, output.Flag{Name: "max-depth", Value: fmt.Sprintf("%d", maxDepth)})
+	}
+	flags = append(flags, output.Flag{Name: "dry-run", Value: fmt.Sprintf("%t", isDryRun)})
 
 	// Add output flag
 	flags = append(flags, output.Flag{Name: "output", Value: string(format)})
@@ -127,8 +383,17 @@ func runRename(cmd *cobra.Command, args []string) {
 	if excludeDirPatterns != "" {
 		flags = append(flags, output.Flag{Name: "exclude-dir", Value: excludeDirPatterns})
 	}
+	if journalDir != "" {
+		flags = append(flags, output.Flag{Name: "journal-dir", Value: journalDir})
+	}
+	if noJournal {
+		flags = append(flags, output.Flag{Name: "no-journal", Value: "true"})
+	}
+	if atomicRename {
+		flags = append(flags, output.Flag{Name: "atomic", Value: "true"})
+	}
 
-	metadata := &output.Metadata{
+	result.Metadata = &output.Metadata{
 		ToolName:    "filetools",
 		SubCommand:  "rename",
 		Flags:       flags,
@@ -136,13 +401,6 @@ func runRename(cmd *cobra.Command, args []string) {
 		GeneratedAt: time.Now().Format(time.RFC3339),
 	}
 
-	result := &output.RenameResult{
-		Operations: operations,
-		DryRun:     isDryRun,
-		Exclusions: exclusionsList,
-		Metadata:   metadata,
-	}
-
 	// Output the results
 	if err := formatter.FormatRename(result, writer); err != nil {
 		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
@@ -150,43 +408,14 @@ func runRename(cmd *cobra.Command, args []string) {
 	}
 }
 
-// parseSedExpression parses a sed-style replacement expression
-// Format: s/pattern/replacement/flags
-// Supported flags: g (global)
-func parseSedExpression(expr string) (*regexp.Regexp, string, bool, error) {
-	if !strings.HasPrefix(expr, "s/") {
-		return nil, "", false, fmt.Errorf("invalid sed expression: must start with 's/'")
-	}
-
-	parts := strings.Split(expr[2:], "/")
-	if len(parts) < 2 || parts[1] == "" {
-		return nil, "", false, fmt.Errorf("invalid sed expression: missing replacement")
-	}
-
-	pattern := parts[0]
-	replacement := parts[1]
-	global := false
-
-	if len(parts) > 2 {
-		flags := parts[2]
-		if strings.Contains(flags, "g") {
-			global = true
-		}
-		// Other flags could be added here
-	}
-
-	re, err := regexp.Compile(pattern)
-	if err != nil {
-		return nil, "", false, fmt.Errorf("invalid regex pattern: %w", err)
-	}
-
-	return re, replacement, global, nil
-}
-
-// performRenames traverses the directory and performs rename operations
-func performRenames(rootDir, matchPattern string, sedRegex *regexp.Regexp, replacement string, global bool, isDryRun, forceOverwrite bool, fileMatchers, dirMatchers []exclusions.ExclusionMatcher) ([]output.RenameOperation, []output.Exclusion, error) {
+// performRenames traverses the directory, plans the rename operations and
+// performs them unless in dry-run mode
+func performRenames(rootDir string, fileMatchers, dirMatchers []exclusions.ExclusionMatcher, opts renameOptions) (*output.RenameResult, error) {
 	var operations []output.RenameOperation
 	var exclusionsList []output.Exclusion
+	matches := 0
+	newDirs := map[string]string{".": "."} // New paths of the directories walked
+	var selected []string                  // Paths matched for --edit
 
 	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -214,69 +443,121 @@ func performRenames(rootDir, matchPattern string, sedRegex *regexp.Regexp, repla
 			return nil
 		}
 
-		if info.IsDir() {
-			return nil // Skip directories for renaming
+		// Directories at the maximum depth are renamed but not entered
+		depth := pathDepth(relPath) + 1
+		var next error
+		if info.IsDir() && opts.maxDepth > 0 && depth >= opts.maxDepth {
+			next = filepath.SkipDir
 		}
 
-		// Check if file matches the pattern
-		matched, err := filepath.Match(matchPattern, filepath.Base(path))
-		if err != nil {
-			return fmt.Errorf("invalid match pattern: %w", err)
+		// Check if the entry is of a renamed type and depth and matches the pattern
+		oldName := filepath.Base(path)
+		matched := false
+		if opts.renames(info) && depth >= opts.minDepth {
+			if matched, err = opts.matches(relPath); err != nil {
+				return fmt.Errorf("invalid match pattern: %w", err)
+			}
 		}
-		if !matched {
-			return nil
+
+		// Paths to edit are collected, renamed once the walk is done
+		if matched && opts.edit {
+			selected = append(selected, relPath)
+			return next
 		}
 
-		// Apply sed replacement to the filename
-		oldName := filepath.Base(path)
-		var newName string
-		if global {
-			newName = sedRegex.ReplaceAllString(oldName, replacement)
-		} else {
-			// Replace only the first occurrence
-			loc := sedRegex.FindStringIndex(oldName)
-			if loc != nil {
-				newName = oldName[:loc[0]] + replacement + oldName[loc[1]:]
-			} else {
+		// Apply the sed replacement to the path, moving the file
+		if matched && opts.sedPath {
+			matches++
+			op := output.RenameOperation{OldPath: relPath}
+			if op.NewPath, err = sedPathTarget(opts.sed, relPath); err != nil {
+				op.NewPath = relPath
+				op.Error = err.Error()
+			}
+			if op.NewPath != relPath || op.Error != "" {
+				operations = append(operations, op)
+			}
+			return next
+		}
+
+		// Apply the template or sed replacement to the name
+		newName := oldName
+		if matched {
+			matches++
+			var nameErr error
+			if opts.template != nil {
+				newName, nameErr = opts.template.expand(path, info, matches)
+			} else if newName = opts.sed.apply(oldName); !validName(newName) {
+				nameErr = fmt.Errorf("sed expression produced an invalid name %q", newName)
+			}
+			if nameErr != nil {
+				operations = append(operations, output.RenameOperation{
+					OldPath: relPath,
+					NewPath: relPath,
+					Error:   nameErr.Error(),
+				})
 				newName = oldName
 			}
 		}
 
+		// Entries below a renamed directory end up below its new path
+		parent := newDirs[filepath.Dir(relPath)]
+		if info.IsDir() {
+			newDirs[relPath] = filepath.Join(parent, newName)
+		}
+
 		if oldName == newName {
 			// No change needed
-			return nil
+			return next
 		}
 
-		newPath := filepath.Join(filepath.Dir(path), newName)
-
-		op := output.RenameOperation{
+		operations = append(operations, output.RenameOperation{
 			OldPath: relPath,
-			NewPath: filepath.Join(filepath.Dir(relPath), newName),
-		}
-
-		// Check if target already exists
-		if _, err := os.Stat(newPath); err == nil {
-			if !forceOverwrite {
-				op.Error = "target file already exists"
-			}
-		} else if !os.IsNotExist(err) {
-			op.Error = fmt.Sprintf("cannot check target: %v", err)
+			NewPath: filepath.Join(parent, newName),
+		})
+		return next
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.edit {
+		if operations, err = editRenames(rootDir, selected); err != nil {
+			return nil, err
 		}
+	}
 
-		// Perform rename if not dry run and no error
-		if !isDryRun && op.Error == "" {
-			if err := os.Rename(path, newPath); err != nil {
-				op.Error = fmt.Sprintf("rename failed: %v", err)
-			}
-		}
+	// Validate the whole batch before anything is renamed
+	plan := newRenamePlan(rootDir, operations, opts.overwrites())
+	result := runRenamePlan(plan, opts.dryRun, opts.atomic, opts.journalDir)
+	result.Exclusions = exclusionsList
 
-		operations = append(operations, op)
-		return nil
-	})
+	return result, nil
+}
 
+// importRenames plans and performs the renames listed in a plan file
+func importRenames(rootDir, planFile string, opts renameOptions) (*output.RenameResult, error) {
+	entries, err := readRenamePlanFile(planFile)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
+	plan := newRenamePlan(rootDir, planOperations(rootDir, entries), opts.overwrites())
+	return runRenamePlan(plan, opts.dryRun, opts.atomic, opts.journalDir), nil
+}
 
-	return operations, exclusionsList, nil
+// validName reports whether a new name is a single path element, as
+// produced by --sed and --template without --sed-path. A backslash is only a
+// separator on Windows, elsewhere it may be part of a name.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsRune(name, '/') && !strings.ContainsRune(name, filepath.Separator)
+}
+
+// sedPathTarget applies a sed script to a relative path, written with
+// forward slashes, and checks that the new path stays in the directory
+func sedPathTarget(sed sedScript, relPath string) (string, error) {
+	replaced := sed.apply(filepath.ToSlash(relPath))
+	newPath := filepath.Clean(filepath.FromSlash(replaced))
+	if newPath == "." || !filepath.IsLocal(newPath) {
+		return "", fmt.Errorf("new path %q is outside of the directory", replaced)
+	}
+	return newPath, nil
 }
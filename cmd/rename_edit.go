@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"amurru/filetools/internal/output"
+)
+
+// editHeader explains the file opened by --edit
+const editHeader = `# Edit the paths below to rename the files, then save and quit.
+# The number on each line identifies the file and must be kept.
+# Lines that are deleted or left unchanged are not renamed.
+# Paths are relative to %s.
+`
+
+// editorCommand returns the editor set in VISUAL or EDITOR, split into the
+// program and its arguments
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editRenames lists the paths in a temporary file, one numbered line each,
+// opens it in the editor and returns the edited paths as operations. The
+// editor is not opened without paths.
+func editRenames(root string, paths []string) ([]output.RenameOperation, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	file, err := os.CreateTemp("", "filetools-rename-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	// Names with line breaks cannot be edited as lines
+	var operations []output.RenameOperation
+	writer := bufio.NewWriter(file)
+	fmt.Fprintf(writer, editHeader, root)
+	for i, path := range paths {
+		if strings.ContainsAny(path, "\r\n") {
+			operations = append(operations, output.RenameOperation{
+				OldPath: path,
+				NewPath: path,
+				Error:   "cannot edit a name with a line break",
+			})
+			continue
+		}
+		fmt.Fprintf(writer, "%d\t%s\n", i+1, path)
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	editor := editorCommand()
+	cmd := exec.Command(editor[0], append(editor[1:], file.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseEditedPaths(data, paths)
+	if err != nil {
+		return nil, err
+	}
+	return append(operations, planOperations(root, entries)...), nil
+}
+
+// parseEditedPaths reads the lines of an edited file back into old and new
+// paths. Comments and blank lines are skipped.
+func parseEditedPaths(data []byte, paths []string) ([]output.RenameOperation, error) {
+	var entries []output.RenameOperation
+	seen := make(map[int]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		number, newPath, ok := strings.Cut(text, "\t")
+		n, err := strconv.Atoi(strings.TrimSpace(number))
+		if !ok || err != nil {
+			return nil, fmt.Errorf("line %d: expected a file number, a tab and the new path", line)
+		}
+		if n < 1 || n > len(paths) {
+			return nil, fmt.Errorf("line %d: unknown file number %d", line, n)
+		}
+		if seen[n] {
+			return nil, fmt.Errorf("line %d: file %d is listed twice", line, n)
+		}
+		seen[n] = true
+		entries = append(entries, output.RenameOperation{OldPath: paths[n-1], NewPath: newPath})
+	}
+	return entries, scanner.Err()
+}
+
+// readRenamePlanFile reads old and new paths from a CSV file of two columns,
+// with an optional old_path,new_path header, or from a JSON file holding an
+// array of {"old_path", "new_path"} objects or the JSON output of rename
+func readRenamePlanFile(path string) ([]output.RenameOperation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var entries []output.RenameOperation
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			var result output.RenameResult
+			err = json.Unmarshal(data, &result)
+			entries = result.Operations
+		} else {
+			err = json.Unmarshal(data, &entries)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+		}
+		return entries, nil
+
+	case ".csv":
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = 2
+		var entries []output.RenameOperation
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+			}
+			if len(entries) == 0 && record[0] == "old_path" && record[1] == "new_path" {
+				continue // Header
+			}
+			entries = append(entries, output.RenameOperation{OldPath: record[0], NewPath: record[1]})
+		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("unsupported plan format %s: use a .csv or .json file", path)
+}
+
+// planOperations turns the entries of an edited or imported plan into
+// operations, checking that both paths are in the directory and that the
+// file exists. New paths are where files end up once all renames are done,
+// as in the rename output. Unchanged entries are left out.
+func planOperations(root string, entries []output.RenameOperation) []output.RenameOperation {
+	var operations []output.RenameOperation
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		op := output.RenameOperation{OldPath: entry.OldPath, NewPath: entry.OldPath}
+
+		oldPath, err := planPath(root, entry.OldPath)
+		if err != nil {
+			op.Error = err.Error()
+			operations = append(operations, op)
+			continue
+		}
+		op.OldPath, op.NewPath = oldPath, oldPath
+
+		newPath, err := planPath(root, entry.NewPath)
+		switch {
+		case err != nil:
+			op.Error = "new " + err.Error()
+		case seen[oldPath]:
+			op.Error = "file listed twice in the plan"
+		default:
+			if _, err := os.Lstat(filepath.Join(root, oldPath)); os.IsNotExist(err) {
+				op.Error = "file does not exist"
+			} else if err != nil {
+				op.Error = fmt.Sprintf("cannot check file: %v", err)
+			} else if newPath == oldPath {
+				continue
+			} else {
+				op.NewPath = newPath
+			}
+		}
+		seen[oldPath] = true
+		operations = append(operations, op)
+	}
+	return operations
+}
+
+// planPath converts a path of a plan, relative or absolute, to a clean path
+// relative to root
+func planPath(root, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+	rel := filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(rel) {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return "", err
+		}
+		if rel, err = filepath.Rel(absRoot, rel); err != nil {
+			return "", fmt.Errorf("path %q is outside of the directory", path)
+		}
+	}
+	if rel == "." || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %q is outside of the directory", path)
+	}
+	return rel, nil
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"amurru/filetools/internal/output"
+)
+
+func TestParseEditedPaths(t *testing.T) {
+	paths := []string{"a.txt", "b.txt", "c.txt"}
+	data := "# comment\n\n1\tA.txt\r\n3\tsub/c.txt\n"
+	entries, err := parseEditedPaths([]byte(data), paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []output.RenameOperation{{OldPath: "a.txt", NewPath: "A.txt"}, {OldPath: "c.txt", NewPath: "sub/c.txt"}}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	for _, data := range []string{"a.txt\n", "x\ta.txt\n", "4\td.txt\n", "0\ta.txt\n", "1\ta\n1\tb\n"} {
+		if _, err := parseEditedPaths([]byte(data), paths); err == nil {
+			t.Errorf("parseEditedPaths(%q) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestImportRenames(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a.txt", "b.txt", "c.txt", "d.txt")
+	plans := t.TempDir()
+
+	files := map[string]string{
+		"plan.csv":  "old_path,new_path\na.txt,x/a.txt\nb.txt,c.txt\nc.txt,b.txt\n",
+		"plan.json": `[{"old_path": "a.txt", "new_path": "x/a.txt"}, {"old_path": "b.txt", "new_path": "c.txt"}, {"old_path": "c.txt", "new_path": "b.txt"}]`,
+	}
+
+	// The JSON output of a rename run is a plan too
+	report, err := json.Marshal(&output.RenameResult{Operations: []output.RenameOperation{
+		{OldPath: "a.txt", NewPath: "x/a.txt"},
+		{OldPath: "b.txt", NewPath: "c.txt"},
+		{OldPath: "c.txt", NewPath: "b.txt", Error: "ignored"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["report.json"] = string(report)
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(plans, name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := importRenames(dir, path, renameOptions{dryRun: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Operations) != 3 {
+				t.Fatalf("operations = %+v, want 3", result.Operations)
+			}
+			for _, op := range result.Operations {
+				if op.Error != "" {
+					t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+				}
+			}
+		})
+	}
+
+	// Applying the plan moves a.txt into a new directory and swaps b and c
+	result, err := importRenames(dir, filepath.Join(plans, "plan.csv"), renameOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range result.Operations {
+		if op.Error != "" {
+			t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"x/a.txt": "a.txt", "b.txt": "c.txt", "c.txt": "b.txt"})
+
+	if _, err := importRenames(dir, filepath.Join(plans, "plan.txt"), renameOptions{}); err == nil {
+		t.Error("expected an error for an unsupported plan format")
+	}
+}
+
+func TestPlanOperationsErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a.txt", "b.txt", "sub/c.txt")
+
+	operations := planOperations(dir, []output.RenameOperation{
+		{OldPath: "a.txt", NewPath: "../a.txt"},
+		{OldPath: "missing.txt", NewPath: "m.txt"},
+		{OldPath: "../etc/passwd", NewPath: "p"},
+		{OldPath: "b.txt", NewPath: "b.txt"},
+		{OldPath: filepath.Join(dir, "sub", "c.txt"), NewPath: "c.txt"},
+		{OldPath: "sub/c.txt", NewPath: "d.txt"},
+		{OldPath: "b.txt", NewPath: ""},
+	})
+
+	want := []struct{ oldPath, newPath, err string }{
+		{"a.txt", "a.txt", "outside of the directory"},
+		{"missing.txt", "missing.txt", "file does not exist"},
+		{"../etc/passwd", "../etc/passwd", "outside of the directory"},
+		{filepath.Join("sub", "c.txt"), "c.txt", ""},
+		{filepath.Join("sub", "c.txt"), filepath.Join("sub", "c.txt"), "listed twice"},
+		{"b.txt", "b.txt", "path is empty"},
+	}
+	if len(operations) != len(want) {
+		t.Fatalf("operations = %+v, want %d", operations, len(want))
+	}
+	for i, w := range want {
+		op := operations[i]
+		if op.OldPath != w.oldPath || op.NewPath != w.newPath || (w.err == "") != (op.Error == "") || !strings.Contains(op.Error, w.err) {
+			t.Errorf("operation %d = %+v, want %s -> %s (%s)", i, op, w.oldPath, w.newPath, w.err)
+		}
+	}
+
+	// A file cannot be moved into a directory that is renamed away
+	plan := newRenamePlan(dir, planOperations(dir, []output.RenameOperation{
+		{OldPath: "sub", NewPath: "renamed"},
+		{OldPath: "a.txt", NewPath: "sub/a.txt"},
+		{OldPath: "b.txt", NewPath: "renamed/b.txt"},
+	}), false)
+	if !strings.Contains(plan.operations[1].Error, "inside sub") {
+		t.Errorf("a.txt: error = %q, want a renamed directory error", plan.operations[1].Error)
+	}
+	if plan.operations[2].Error != "" || plan.targets[2] != filepath.Join("sub", "b.txt") {
+		t.Errorf("b.txt: target %s, error %q", plan.targets[2], plan.operations[2].Error)
+	}
+
+	// b.txt is moved into sub before sub is renamed
+	plan.apply(false)
+	for _, i := range []int{0, 2} {
+		if op := plan.operations[i]; op.Error != "" {
+			t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"renamed/b.txt": "b.txt", "renamed/c.txt": "sub/c.txt", "a.txt": "a.txt"})
+}
+
+func TestPerformRenamesEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test editor is a shell script")
+	}
+
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "IMG_1.jpg", "IMG_2.jpg", "notes.txt")
+
+	// The editor renames the first file and moves the second
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\nsed -i -e 's/^1\tIMG_1/1\tbeach/' -e 's/^2\tIMG_2/2\tpark\\/IMG_2/' \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	result, err := performRenames(dir, nil, nil, renameOptions{match: "*.jpg", edit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Operations) != 2 {
+		t.Fatalf("operations = %+v, want 2", result.Operations)
+	}
+	for _, op := range result.Operations {
+		if op.Error != "" {
+			t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"beach.jpg": "IMG_1.jpg", "park/IMG_2.jpg": "IMG_2.jpg", "notes.txt": "notes.txt"})
+
+	// A failing editor aborts the run
+	t.Setenv("EDITOR", "false")
+	if _, err := performRenames(dir, nil, nil, renameOptions{match: "*.txt", edit: true}); err == nil {
+		t.Error("expected an error for a failing editor")
+	}
+}
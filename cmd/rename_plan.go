@@ -0,0 +1,431 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"amurru/filetools/internal/output"
+)
+
+// renameStep is a single os.Rename of a plan. An operation that is part of a
+// cycle takes two steps, through a temporary name.
+type renameStep struct {
+	op   int    // Index of the operation in the plan
+	from string // Paths relative to the plan root
+	to   string
+	temp bool // Moves the file to a temporary name, a later step completes the operation
+}
+
+// renamePlan is a batch of renames validated as a whole and ordered so that
+// no rename overwrites a file that another rename of the batch still has to
+// move away.
+//
+// Operations may rename directories as well as the files in them. Their new
+// paths are where the files end up once the whole plan is applied. Contents
+// are renamed before their directory, so each rename happens at the old path
+// of the directory: its target is the new path with the renamed directories
+// mapped back to their old paths.
+type renamePlan struct {
+	root       string
+	operations []output.RenameOperation // In the order they were planned, as reported
+	targets    []string                 // Where each operation renames its file to, below the old directories
+	steps      []renameStep             // In the order they are applied
+	blockers   []int                    // Operation moving away the file at each target, -1 if none
+	applied    []int                    // Operations completed by apply, in order
+	created    []string                 // Directories created by apply for moved files, parents first
+}
+
+// newRenamePlan validates the operations against each other and against the
+// filesystem and orders them. Conflicting operations get an error and are
+// left out of the steps, as are operations that already have an error;
+// nothing on disk is changed.
+func newRenamePlan(root string, operations []output.RenameOperation, overwrite bool) *renamePlan {
+	plan := &renamePlan{
+		root:       root,
+		operations: operations,
+		blockers:   make([]int, len(operations)),
+	}
+	plan.mapTargets()
+
+	sources := make(map[string]int, len(operations))
+	targets := make(map[string][]int, len(operations))
+	for i, op := range operations {
+		if _, ok := sources[filepath.Clean(op.OldPath)]; !ok {
+			sources[filepath.Clean(op.OldPath)] = i
+		}
+		if op.Error == "" {
+			// Operations that failed before planning stay in place
+			targets[plan.targets[i]] = append(targets[plan.targets[i]], i)
+		}
+	}
+
+	// Several files renamed to the same name would overwrite each other
+	for _, ops := range targets {
+		if len(ops) < 2 {
+			continue
+		}
+		for _, i := range ops {
+			var others []string
+			for _, j := range ops {
+				if j != i {
+					others = append(others, operations[j].OldPath)
+				}
+			}
+			plan.fail(i, fmt.Sprintf("target also planned for %s", strings.Join(others, ", ")))
+		}
+	}
+
+	// A target may only exist if the batch moves it away first. Failing one
+	// operation can leave the target of another in place, so repeat until
+	// nothing changes.
+	for i := range plan.blockers {
+		plan.blockers[i] = -1
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := range operations {
+			if operations[i].Error != "" {
+				continue
+			}
+			target := plan.targets[i]
+			if j, ok := sources[target]; ok && operations[j].Error == "" {
+				plan.blockers[i] = j
+				continue
+			}
+			plan.blockers[i] = -1
+
+			if _, err := os.Lstat(filepath.Join(root, target)); err == nil {
+				if overwrite {
+					operations[i].Overwrite = true
+				} else {
+					plan.fail(i, "target file already exists")
+					changed = true
+				}
+			} else if !os.IsNotExist(err) {
+				plan.fail(i, fmt.Sprintf("cannot check target: %v", err))
+				changed = true
+			}
+		}
+	}
+
+	plan.order()
+	return plan
+}
+
+// mapTargets computes the target of every operation from its new path, by
+// replacing its deepest renamed directory with the old path of the directory.
+// A new path below the old path of a renamed directory is an error, as the
+// file would be renamed away with the directory.
+func (p *renamePlan) mapTargets() {
+	renamedDirs := make(map[string]int, len(p.operations))
+	movedDirs := make(map[string]int, len(p.operations))
+	for i, op := range p.operations {
+		if op.Error == "" && filepath.Clean(op.OldPath) != filepath.Clean(op.NewPath) {
+			renamedDirs[filepath.Clean(op.NewPath)] = i
+			movedDirs[filepath.Clean(op.OldPath)] = i
+		}
+	}
+
+	for i, op := range p.operations {
+		for dir := filepath.Dir(filepath.Clean(op.NewPath)); dir != "."; dir = filepath.Dir(dir) {
+			if _, ok := renamedDirs[dir]; ok {
+				break
+			}
+			if j, ok := movedDirs[dir]; ok {
+				p.fail(i, fmt.Sprintf("target is inside %s, which is renamed", p.operations[j].OldPath))
+				break
+			}
+		}
+	}
+
+	p.targets = make([]string, len(p.operations))
+	for i, op := range p.operations {
+		p.targets[i] = replaceDir(filepath.Clean(op.NewPath), renamedDirs, func(j int) string {
+			return filepath.Clean(p.operations[j].OldPath)
+		})
+	}
+}
+
+// settle updates the new path of every operation to where its file is once
+// the renames in effect are done, after some operations on directories
+// failed. Without failures, the new paths are unchanged.
+func (p *renamePlan) settle(inEffect func(i int) bool) {
+	renamedDirs := make(map[string]int, len(p.operations))
+	for i, op := range p.operations {
+		if inEffect(i) {
+			renamedDirs[filepath.Clean(op.OldPath)] = i
+		}
+	}
+
+	// Directories are settled before their contents
+	final := make([]string, len(p.operations))
+	var settlePath func(i int) string
+	settlePath = func(i int) string {
+		if final[i] == "" {
+			final[i] = replaceDir(p.targets[i], renamedDirs, settlePath)
+		}
+		return final[i]
+	}
+	for i := range p.operations {
+		if filepath.Clean(p.operations[i].NewPath) != filepath.Clean(p.operations[i].OldPath) {
+			p.operations[i].NewPath = settlePath(i)
+		}
+	}
+}
+
+// fail records why an operation cannot be performed, keeping the first reason
+func (p *renamePlan) fail(i int, reason string) {
+	if p.operations[i].Error == "" {
+		p.operations[i].Error = reason
+	}
+}
+
+// order computes the steps. Operations are started from the deepest old or
+// new path, so files are renamed, or moved in or out of a directory, before
+// the directory itself is renamed. Every operation waits for
+// its blocker, so chains such as a→b, b→c are applied from their end.
+// Blockers form chains and cycles only, as sources and targets are unique; a
+// cycle such as a→b, b→a is broken by first moving one file to a temporary
+// name.
+func (p *renamePlan) order() {
+	done := make([]bool, len(p.operations))
+	onPath := make([]bool, len(p.operations))
+	reserved := make(map[string]bool)
+	for i, op := range p.operations {
+		reserved[filepath.Clean(op.OldPath)] = true
+		reserved[p.targets[i]] = true
+	}
+
+	starts := make([]int, len(p.operations))
+	for i := range starts {
+		starts[i] = i
+	}
+	depth := func(i int) int {
+		return max(pathDepth(p.operations[i].OldPath), pathDepth(p.targets[i]))
+	}
+	sort.SliceStable(starts, func(a, b int) bool {
+		if depth(starts[a]) != depth(starts[b]) {
+			return depth(starts[a]) > depth(starts[b])
+		}
+		return pathDepth(p.operations[starts[a]].OldPath) > pathDepth(p.operations[starts[b]].OldPath)
+	})
+
+	for _, start := range starts {
+		if done[start] || p.operations[start].Error != "" {
+			continue
+		}
+
+		// Follow the blockers until the end of the chain, an operation
+		// ordered earlier, or back to the start of a cycle
+		path := []int{start}
+		onPath[start] = true
+		cycle := false
+		for i := start; ; {
+			next := p.blockers[i]
+			if next < 0 || done[next] {
+				break
+			}
+			if onPath[next] {
+				cycle = true
+				break
+			}
+			path = append(path, next)
+			onPath[next] = true
+			i = next
+		}
+
+		var temp string
+		if cycle {
+			first := p.operations[start]
+			temp = p.tempName(first.OldPath, reserved)
+			p.steps = append(p.steps, renameStep{op: start, from: first.OldPath, to: temp, temp: true})
+			path = path[1:]
+		}
+		for k := len(path) - 1; k >= 0; k-- {
+			op := p.operations[path[k]]
+			p.steps = append(p.steps, renameStep{op: path[k], from: op.OldPath, to: p.targets[path[k]]})
+		}
+		if cycle {
+			p.steps = append(p.steps, renameStep{op: start, from: temp, to: p.targets[start]})
+		}
+
+		for _, i := range append(path, start) {
+			done[i] = true
+			onPath[i] = false
+		}
+	}
+}
+
+// replaceDir replaces the deepest directory of path found in dirs with the
+// path returned for its operation
+func replaceDir(path string, dirs map[string]int, replace func(j int) string) string {
+	for dir := filepath.Dir(path); dir != "."; dir = filepath.Dir(dir) {
+		if j, ok := dirs[dir]; ok {
+			rel, _ := filepath.Rel(dir, path)
+			return filepath.Join(replace(j), rel)
+		}
+	}
+	return path
+}
+
+// pathDepth returns the number of directories above a relative path
+func pathDepth(path string) int {
+	return strings.Count(filepath.Clean(path), string(filepath.Separator))
+}
+
+// tempName returns an unused name next to path for breaking a rename cycle
+func (p *renamePlan) tempName(path string, reserved map[string]bool) string {
+	dir, base := filepath.Split(path)
+	for n := 1; ; n++ {
+		name := filepath.Join(dir, fmt.Sprintf(".filetools-rename-%d-%s", n, base))
+		if reserved[name] {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(p.root, name)); os.IsNotExist(err) {
+			reserved[name] = true
+			return name
+		}
+	}
+}
+
+// apply performs the steps in order. An operation is skipped if its blocker
+// failed to move out of the way, and an operation whose file was moved to a
+// temporary name is moved back if it cannot be completed. In atomic mode the
+// first failure stops the plan instead, and every step already done is
+// reverted, last first.
+func (p *renamePlan) apply(atomic bool) {
+	vacated := make([]bool, len(p.operations))
+	var done []renameStep
+	for _, step := range p.steps {
+		op := &p.operations[step.op]
+		if op.Error != "" {
+			continue
+		}
+
+		if !step.temp {
+			if blocker := p.blockers[step.op]; blocker >= 0 && !vacated[blocker] {
+				op.Error = fmt.Sprintf("not renamed: %s was not moved out of the way", p.operations[blocker].OldPath)
+				if step.from != op.OldPath {
+					p.restore(op, step.from)
+				}
+				continue
+			}
+		}
+
+		err := p.makeDirs(filepath.Dir(step.to))
+		if err == nil {
+			err = os.Rename(filepath.Join(p.root, step.from), filepath.Join(p.root, step.to))
+		}
+		if err != nil {
+			op.Error = fmt.Sprintf("rename failed: %v", err)
+			if atomic {
+				p.rollback(done)
+				return
+			}
+			if step.from != op.OldPath {
+				p.restore(op, step.from)
+			}
+			continue
+		}
+		done = append(done, step)
+		if step.from == op.OldPath {
+			vacated[step.op] = true
+		}
+		if !step.temp {
+			p.applied = append(p.applied, step.op)
+		}
+	}
+}
+
+// makeDirs creates the missing directories of a relative path, for files
+// moved to another directory
+func (p *renamePlan) makeDirs(dir string) error {
+	var missing []string
+	for ; dir != "."; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(filepath.Join(p.root, dir)); err == nil {
+			break
+		}
+		missing = append(missing, dir)
+	}
+
+	for k := len(missing) - 1; k >= 0; k-- {
+		if err := os.Mkdir(filepath.Join(p.root, missing[k]), 0755); err != nil {
+			return err
+		}
+		p.created = append(p.created, missing[k])
+	}
+	return nil
+}
+
+// rollback reverts the steps done by an aborted apply, last first, and
+// records the outcome for each operation. Operations that were never
+// started are marked as such.
+func (p *renamePlan) rollback(done []renameStep) {
+	started := make([]bool, len(p.operations))
+	for k := len(done) - 1; k >= 0; k-- {
+		step := done[k]
+		op := &p.operations[step.op]
+		started[step.op] = true
+
+		if err := os.Rename(filepath.Join(p.root, step.to), filepath.Join(p.root, step.from)); err != nil {
+			op.Rollback = output.RollbackFailed
+			op.RollbackError = err.Error()
+		} else if op.Rollback == "" {
+			op.Rollback = output.RollbackDone
+		}
+	}
+
+	// Directories created for moved files are removed again if empty
+	for k := len(p.created) - 1; k >= 0; k-- {
+		os.Remove(filepath.Join(p.root, p.created[k]))
+	}
+
+	applied := p.applied[:0]
+	for _, i := range p.applied {
+		if p.operations[i].Rollback == output.RollbackFailed {
+			applied = append(applied, i)
+		}
+	}
+	p.applied = applied
+
+	for i := range p.operations {
+		if !started[i] && p.operations[i].Error == "" {
+			p.operations[i].Error = "not renamed: another rename failed"
+		}
+	}
+}
+
+// requireAll fails every operation if any of them has a conflict, so that
+// an atomic run renames nothing. It reports whether the plan can proceed.
+func (p *renamePlan) requireAll() bool {
+	conflict := false
+	for _, op := range p.operations {
+		if op.Error != "" {
+			conflict = true
+		}
+	}
+	if !conflict {
+		return true
+	}
+
+	for i := range p.operations {
+		p.fail(i, "not renamed: another rename of the plan conflicts")
+	}
+	p.steps = nil
+	return false
+}
+
+// restore moves a file back from its temporary name after its operation failed
+func (p *renamePlan) restore(op *output.RenameOperation, temp string) {
+	oldPath := filepath.Join(p.root, op.OldPath)
+	if _, err := os.Lstat(oldPath); err == nil {
+		op.Error += fmt.Sprintf(" (file left at %s)", temp)
+		return
+	}
+	if err := os.Rename(filepath.Join(p.root, temp), oldPath); err != nil {
+		op.Error += fmt.Sprintf(" (file left at %s)", temp)
+	}
+}
@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"amurru/filetools/internal/output"
+)
+
+// writeRenameFiles creates files in dir whose content is their name
+func writeRenameFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkRenameContents verifies which original file each name now holds
+func checkRenameContents(t *testing.T, dir string, want map[string]string) {
+	t.Helper()
+	for name, content := range want {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s holds %s, want %s", name, data, content)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".filetools-rename-") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestRenamePlanChain(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a", "b", "c")
+
+	// Each target is vacated by the next rename, so they run back to front
+	plan := newRenamePlan(dir, []output.RenameOperation{
+		{OldPath: "a", NewPath: "b"},
+		{OldPath: "b", NewPath: "c"},
+		{OldPath: "c", NewPath: "d"},
+	}, false)
+
+	var order []string
+	for _, step := range plan.steps {
+		order = append(order, step.from)
+	}
+	if got := strings.Join(order, ","); got != "c,b,a" {
+		t.Errorf("steps from %s, want c,b,a", got)
+	}
+
+	plan.apply(false)
+	for _, op := range plan.operations {
+		if op.Error != "" {
+			t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"b": "a", "c": "b", "d": "c"})
+}
+
+func TestRenamePlanCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a", "b", "x", "y", "z")
+
+	plan := newRenamePlan(dir, []output.RenameOperation{
+		{OldPath: "a", NewPath: "b"},
+		{OldPath: "b", NewPath: "a"},
+		{OldPath: "x", NewPath: "y"},
+		{OldPath: "y", NewPath: "z"},
+		{OldPath: "z", NewPath: "x"},
+	}, false)
+	if len(plan.steps) != 7 {
+		t.Errorf("got %d steps, want 7 with one temporary name per cycle", len(plan.steps))
+	}
+
+	plan.apply(false)
+	for _, op := range plan.operations {
+		if op.Error != "" {
+			t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"a": "b", "b": "a", "x": "z", "y": "x", "z": "y"})
+}
+
+func TestRenamePlanConflicts(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a", "b", "c", "d", "kept", "e")
+
+	plan := newRenamePlan(dir, []output.RenameOperation{
+		{OldPath: "a", NewPath: "same"},
+		{OldPath: "b", NewPath: "same"},
+		{OldPath: "c", NewPath: "kept"},
+		// d can only move once e is gone, but e cannot move onto kept
+		{OldPath: "d", NewPath: "e"},
+		{OldPath: "e", NewPath: "kept"},
+	}, false)
+
+	want := map[string]string{
+		"a": "target also planned for b",
+		"b": "target also planned for a",
+		"c": "target also planned for e",
+		"d": "target file already exists",
+		"e": "target also planned for c",
+	}
+	for _, op := range plan.operations {
+		if op.Error != want[op.OldPath] {
+			t.Errorf("%s: error %q, want %q", op.OldPath, op.Error, want[op.OldPath])
+		}
+	}
+	if len(plan.steps) != 0 {
+		t.Errorf("got %d steps, want none", len(plan.steps))
+	}
+
+	// Planning never touches the files
+	plan.apply(false)
+	checkRenameContents(t, dir, map[string]string{"a": "a", "b": "b", "c": "c", "d": "d", "e": "e", "kept": "kept"})
+}
+
+func TestRenamePlanExistingTarget(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a", "b")
+
+	plan := newRenamePlan(dir, []output.RenameOperation{{OldPath: "a", NewPath: "b"}}, false)
+	if plan.operations[0].Error != "target file already exists" {
+		t.Errorf("error %q, want target file already exists", plan.operations[0].Error)
+	}
+
+	plan = newRenamePlan(dir, []output.RenameOperation{{OldPath: "a", NewPath: "b"}}, true)
+	if plan.operations[0].Error != "" {
+		t.Errorf("unexpected error %q when overwriting", plan.operations[0].Error)
+	}
+	plan.apply(false)
+	checkRenameContents(t, dir, map[string]string{"b": "a"})
+}
+
+func TestRenamePlanAtomic(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a", "b", "c", "d/keep", "e")
+
+	// Renaming a file onto a non-empty directory fails even with overwrite
+	plan := newRenamePlan(dir, []output.RenameOperation{
+		{OldPath: "a", NewPath: "x"},
+		{OldPath: "b", NewPath: "y"},
+		{OldPath: "c", NewPath: "d"},
+		{OldPath: "e", NewPath: "z"},
+	}, true)
+	journals := t.TempDir()
+	result := runRenamePlan(plan, false, true, journals)
+
+	if !result.RolledBack {
+		t.Error("result not marked as rolled back")
+	}
+	if result.Journal != "" {
+		t.Errorf("journal %s written for reverted renames", result.Journal)
+	}
+	want := map[string]struct{ err, rollback string }{
+		"a": {"", output.RollbackDone},
+		"b": {"", output.RollbackDone},
+		"c": {"rename failed", ""},
+		"e": {"not renamed: another rename failed", ""},
+	}
+	for _, op := range result.Operations {
+		w := want[op.OldPath]
+		if !strings.HasPrefix(op.Error, w.err) || (w.err == "" && op.Error != "") {
+			t.Errorf("%s: error %q, want %q", op.OldPath, op.Error, w.err)
+		}
+		if op.Rollback != w.rollback {
+			t.Errorf("%s: rollback %q, want %q", op.OldPath, op.Rollback, w.rollback)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"a": "a", "b": "b", "c": "c", "d/keep": "d/keep", "e": "e"})
+	for _, name := range []string{"x", "y", "z"} {
+		if _, err := os.Lstat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s exists after the rollback", name)
+		}
+	}
+}
+
+func TestRenamePlanAtomicCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a", "b", "c", "d/keep")
+
+	// The cycle is applied through a temporary name before c fails
+	plan := newRenamePlan(dir, []output.RenameOperation{
+		{OldPath: "a", NewPath: "b"},
+		{OldPath: "b", NewPath: "a"},
+		{OldPath: "c", NewPath: "d"},
+	}, true)
+	result := runRenamePlan(plan, false, true, "")
+
+	for _, op := range result.Operations[:2] {
+		if op.Rollback != output.RollbackDone {
+			t.Errorf("%s: rollback %q, want %q", op.OldPath, op.Rollback, output.RollbackDone)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"a": "a", "b": "b", "c": "c"})
+}
+
+func TestRenamePlanAtomicConflicts(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a", "b", "taken")
+
+	// One conflict keeps the whole plan from being applied
+	plan := newRenamePlan(dir, []output.RenameOperation{
+		{OldPath: "a", NewPath: "x"},
+		{OldPath: "b", NewPath: "taken"},
+	}, false)
+	result := runRenamePlan(plan, false, true, "")
+
+	if result.RolledBack {
+		t.Error("nothing was applied, so nothing was rolled back")
+	}
+	if got := result.Operations[0].Error; got != "not renamed: another rename of the plan conflicts" {
+		t.Errorf("a: error %q", got)
+	}
+	if got := result.Operations[1].Error; got != "target file already exists" {
+		t.Errorf("b: error %q", got)
+	}
+	checkRenameContents(t, dir, map[string]string{"a": "a", "b": "b", "taken": "taken"})
+}
+
+func TestPerformRenamesOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a.log", "b.log", "c.txt", "d/keep")
+	if err := os.WriteFile(filepath.Join(dir, "b.log"), []byte("KEEP"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Renaming a.log over b.log would lose b.log when the failing move of
+	// c.txt onto a non-empty directory is rolled back, so with --atomic the
+	// existing file is a conflict even with --overwrite
+	sed := mustParseSed(t, "s/^a\\.log$/b.log/;s/^c\\.txt$/d/")
+	result, err := performRenames(dir, nil, nil, renameOptions{
+		match:     "[ac].*",
+		sed:       sed,
+		overwrite: true,
+		atomic:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op := result.Operations[0]; op.Error != "target file already exists" || op.Overwrite {
+		t.Errorf("a.log = %+v, want a target file already exists error", op)
+	}
+	checkRenameContents(t, dir, map[string]string{"a.log": "a.log", "c.txt": "c.txt", "d/keep": "d/keep"})
+	if data, err := os.ReadFile(filepath.Join(dir, "b.log")); err != nil || string(data) != "KEEP" {
+		t.Errorf("b.log = %q, %v, want KEEP", data, err)
+	}
+
+	// By default the existing file is a conflict too
+	result, err = performRenames(dir, nil, nil, renameOptions{match: "a.log", sed: sed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op := result.Operations[0]; op.Error != "target file already exists" || op.Overwrite {
+		t.Errorf("a.log = %+v, want a target file already exists error", op)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "b.log")); err != nil || string(data) != "KEEP" {
+		t.Errorf("b.log = %q, %v, want KEEP", data, err)
+	}
+
+	// With --overwrite, the dry run reports that b.log is replaced
+	for _, dryRun := range []bool{true, false} {
+		result, err = performRenames(dir, nil, nil, renameOptions{match: "a.log", sed: sed, dryRun: dryRun, overwrite: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Operations) != 1 || result.Operations[0].Error != "" || !result.Operations[0].Overwrite {
+			t.Fatalf("dry run %v: operations = %+v, want one overwrite", dryRun, result.Operations)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"b.log": "a.log"})
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sedScript is a list of substitutions applied to a name one after another
+type sedScript []*sedSubstitution
+
+// sedSubstitution is a single s command of a sed expression
+type sedSubstitution struct {
+	re          *regexp.Regexp
+	replacement []sedPart
+	global      bool // Replace every match from the occurrence on
+	occurrence  int  // First match replaced, counting from 1
+}
+
+// sedPart is a piece of a replacement: literal text, a reference to a
+// group of the match, or a case conversion
+type sedPart struct {
+	literal string
+	group   int  // Group inserted, 0 for the whole match, -1 for none
+	caseOp  rune // One of U, L, E, u, l for a case conversion, 0 for none
+}
+
+// parseSedExpression parses sed-style substitutions, as understood by sed -E.
+// Format: s/pattern/replacement/flags, several separated by ';'
+// Any character can be used as delimiter instead of '/', and is escaped with
+// a backslash inside the pattern and replacement. Patterns use Go regular
+// expression syntax.
+// Replacement escapes: & or \0 (whole match), \1-\9 (groups), \U, \L (upper
+// or lower case until \E), \u, \l (next character), \n (newline)
+// Supported flags: g (global), i or I (case-insensitive), N (Nth match)
+func parseSedExpression(expr string) (sedScript, error) {
+	var script sedScript
+	rest := expr
+	for {
+		rest = strings.TrimLeft(rest, " \t\n;")
+		if rest == "" {
+			break
+		}
+
+		sub, remaining, err := parseSedSubstitution(rest)
+		if err != nil {
+			return nil, err
+		}
+		script = append(script, sub)
+		rest = remaining
+	}
+
+	if len(script) == 0 {
+		return nil, fmt.Errorf("invalid sed expression: empty expression")
+	}
+	return script, nil
+}
+
+// parseSedSubstitution parses the s command at the start of expr and
+// returns the rest of the expression
+func parseSedSubstitution(expr string) (*sedSubstitution, string, error) {
+	if !strings.HasPrefix(expr, "s") || len(expr) < 2 {
+		return nil, "", fmt.Errorf("invalid sed expression: must start with 's' followed by a delimiter")
+	}
+	delim, size := utf8.DecodeRuneInString(expr[1:])
+	if delim == '\\' || delim == '\n' || delim == ';' || unicode.IsSpace(delim) {
+		return nil, "", fmt.Errorf("invalid sed expression: %q cannot be used as delimiter", delim)
+	}
+	rest := expr[1+size:]
+
+	pattern, rest, ok := splitSedField(rest, delim, true)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid sed expression: unterminated pattern")
+	}
+	replacement, rest, ok := splitSedField(rest, delim, false)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid sed expression: missing replacement")
+	}
+	if pattern == "" {
+		return nil, "", fmt.Errorf("invalid sed expression: empty pattern")
+	}
+
+	sub := &sedSubstitution{occurrence: 1}
+	caseInsensitive := false
+	number := ""
+	for rest != "" && rest[0] != ';' && !unicode.IsSpace(rune(rest[0])) {
+		flag := rest[0]
+		rest = rest[1:]
+		switch {
+		case flag == 'g':
+			sub.global = true
+		case flag == 'i' || flag == 'I':
+			caseInsensitive = true
+		case flag >= '0' && flag <= '9':
+			number += string(flag)
+		default:
+			return nil, "", fmt.Errorf("invalid sed expression: unknown flag '%c'", flag)
+		}
+	}
+	if number != "" {
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 1 {
+			return nil, "", fmt.Errorf("invalid sed expression: invalid occurrence %s", number)
+		}
+		sub.occurrence = n
+	}
+
+	if caseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid regex pattern: %w", err)
+	}
+	sub.re = re
+
+	sub.replacement, err = parseSedReplacement(replacement, re.NumSubexp())
+	if err != nil {
+		return nil, "", err
+	}
+	return sub, rest, nil
+}
+
+// splitSedField returns the text up to the next unescaped delimiter and the
+// text after it. An escaped delimiter stands for the delimiter itself, with
+// its regular meaning in a pattern. Other escapes are kept for the regular
+// expression or replacement parser.
+func splitSedField(s string, delim rune, pattern bool) (string, string, bool) {
+	var field strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == delim:
+			return field.String(), s[i+size:], true
+		case r == '\\' && i+size < len(s):
+			next, nextSize := utf8.DecodeRuneInString(s[i+size:])
+			if next == delim {
+				if !pattern && delim == '&' {
+					field.WriteRune('\\')
+				}
+				field.WriteRune(next)
+			} else {
+				field.WriteRune(r)
+				field.WriteRune(next)
+			}
+			i += size + nextSize
+		default:
+			field.WriteRune(r)
+			i += size
+		}
+	}
+	return "", "", false
+}
+
+// parseSedReplacement splits a replacement into its parts, checking that
+// referenced groups exist in the pattern
+func parseSedReplacement(s string, groups int) ([]sedPart, error) {
+	var parts []sedPart
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, sedPart{literal: literal.String(), group: -1})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		if r == '&' {
+			flush()
+			parts = append(parts, sedPart{group: 0})
+			continue
+		}
+		if r != '\\' {
+			literal.WriteRune(r)
+			continue
+		}
+		if i == len(s) {
+			return nil, fmt.Errorf("invalid sed expression: trailing backslash in replacement")
+		}
+
+		next, nextSize := utf8.DecodeRuneInString(s[i:])
+		i += nextSize
+		switch {
+		case next >= '0' && next <= '9':
+			group := int(next - '0')
+			if group > groups {
+				return nil, fmt.Errorf("invalid sed expression: reference \\%d to a missing group", group)
+			}
+			flush()
+			parts = append(parts, sedPart{group: group})
+		case next == 'U' || next == 'L' || next == 'E' || next == 'u' || next == 'l':
+			flush()
+			parts = append(parts, sedPart{group: -1, caseOp: next})
+		case next == 'n':
+			literal.WriteByte('\n')
+		case next == 't':
+			literal.WriteByte('\t')
+		default:
+			// Escaped characters such as \& and \\ stand for themselves
+			literal.WriteRune(next)
+		}
+	}
+	flush()
+	return parts, nil
+}
+
+// apply runs every substitution of the script on s in order
+func (script sedScript) apply(s string) string {
+	for _, sub := range script {
+		s = sub.apply(s)
+	}
+	return s
+}
+
+// apply replaces the selected matches of the substitution in s
+func (sub *sedSubstitution) apply(s string) string {
+	matches := sub.re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) < sub.occurrence {
+		return s
+	}
+	matches = matches[sub.occurrence-1:]
+	if !sub.global {
+		matches = matches[:1]
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range matches {
+		sb.WriteString(s[last:match[0]])
+		sub.expand(&sb, s, match)
+		last = match[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// expand writes the replacement of one match, applying case conversions as
+// GNU sed does: \U, \L and \E cancel a pending \u or \l
+func (sub *sedSubstitution) expand(sb *strings.Builder, s string, match []int) {
+	var mode, next rune
+	for _, part := range sub.replacement {
+		text := part.literal
+		switch {
+		case part.caseOp == 'u' || part.caseOp == 'l':
+			next = part.caseOp
+			continue
+		case part.caseOp != 0:
+			mode, next = part.caseOp, 0
+			continue
+		case part.group >= 0:
+			start, end := match[2*part.group], match[2*part.group+1]
+			if start < 0 {
+				continue // Group did not participate in the match
+			}
+			text = s[start:end]
+		}
+		if text == "" {
+			continue
+		}
+
+		switch mode {
+		case 'U':
+			text = strings.ToUpper(text)
+		case 'L':
+			text = strings.ToLower(text)
+		}
+		if next != 0 {
+			r, size := utf8.DecodeRuneInString(text)
+			if next == 'u' {
+				r = unicode.ToUpper(r)
+			} else {
+				r = unicode.ToLower(r)
+			}
+			text = string(r) + text[size:]
+			next = 0
+		}
+		sb.WriteString(text)
+	}
+}
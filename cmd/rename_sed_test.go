@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mustParseSed parses a sed expression for a test
+func mustParseSed(t *testing.T, expr string) sedScript {
+	t.Helper()
+	script, err := parseSedExpression(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+// The expected names are the output of GNU sed -E for the same expression
+func TestParseSedExpression(t *testing.T) {
+	tests := []struct {
+		expr string
+		name string
+		want string
+	}{
+		{`s/old/new/`, `old_old.txt`, `new_old.txt`},
+		{`s/old/new/g`, `old_old.txt`, `new_new.txt`},
+		{`s/(.+)/prefix_\1/`, `a.txt`, `prefix_a.txt`},
+		{`s/(.+)/prefix_$1/`, `a.txt`, `prefix_$1`},
+		{`s|a\|b|X|`, `a|b.txt`, `X|b.txt`},
+		{`s,\,,_,g`, `a,b,c`, `a_b_c`},
+		{`s/\//_/`, `a/b`, `a_b`},
+		{`s/x/\//`, `axb`, `a/b`},
+		{`s#\.jpeg$#.jpg#`, `photo.jpeg`, `photo.jpg`},
+		{`s/img/pic/i`, `photo_IMG_001.JPG`, `photo_pic_001.JPG`},
+		{`s/a/b/2`, `aaaa`, `abaa`},
+		{`s/a/b/2g`, `aaaa`, `abbb`},
+		{`s/a/b/5`, `aaaa`, `aaaa`},
+		{`s/(\w+) (\w+)/\U\1\E-\u\2/`, `hello world`, `HELLO-World`},
+		{`s/.*/\L&/`, `Foo Bar`, `foo bar`},
+		{`s/(\w+)/\L\u\1/`, `FOO`, `Foo`},
+		{`s/(\w+)/\u\L\1/`, `FOO`, `foo`},
+		{`s/b/[&]/;s/a//`, `abc`, `[b]c`},
+		{`s/_old//`, `photo_old.jpg`, `photo.jpg`},
+		{`s/o/\&\\/`, `foo`, `f&\o`},
+		{`s/x*/-/g`, `abc`, `-a-b-c-`},
+		{`s/(a)/\1\0/`, `ab`, `aab`},
+		{`s/a/b/ ; s/b/c/g`, `ab`, `cc`},
+		{`s/b/\q/`, `abc`, `aqc`},
+		{`s/(b)?c/[\1]/`, `ac`, `a[]`},
+		{`s/(\w+)\.(\w+)/\2.\1/`, `report.txt`, `txt.report`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			script, err := parseSedExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("parseSedExpression() error = %v", err)
+			}
+			if got := script.apply(tt.name); got != tt.want {
+				t.Errorf("apply(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSedExpressionErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"invalid",
+		"s/old/",
+		"s//x/",
+		"s/a/b/q",
+		`s/a/\1/`,
+		"s/(/x/",
+		"s/a/b/0",
+		"s/a/b/;x",
+		`s\a\b\`,
+	}
+
+	for _, expr := range tests {
+		if _, err := parseSedExpression(expr); err == nil {
+			t.Errorf("parseSedExpression(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestPerformRenamesSedInvalidName(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "root")
+	writeRenameFiles(t, dir, "a.txt", "b.txt", "c.txt", "d.txt")
+
+	// A replacement with an escaped or alternate delimiter can contain a
+	// separator, which must not move files out of the directory or into
+	// new directories
+	tests := map[string]string{
+		"a.txt": `s|a|..\/escaped|`,
+		"b.txt": `s/b/sub\/x/`,
+		"c.txt": `s/.*//`,
+		"d.txt": `s/.*/../`,
+	}
+	for name, expr := range tests {
+		result, err := performRenames(dir, nil, nil, renameOptions{match: name, sed: mustParseSed(t, expr)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Operations) != 1 || !strings.Contains(result.Operations[0].Error, "invalid name") {
+			t.Errorf("%s with %s: operations = %+v, want an invalid name error", name, expr, result.Operations)
+		}
+	}
+
+	checkRenameContents(t, dir, map[string]string{"a.txt": "a.txt", "b.txt": "b.txt", "c.txt": "c.txt", "d.txt": "d.txt"})
+	for _, path := range []string{filepath.Join(parent, "escaped.txt"), filepath.Join(dir, "sub")} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was created", path)
+		}
+	}
+
+	// A backslash is a separator on Windows only
+	result, err := performRenames(dir, nil, nil, renameOptions{match: "a.txt", sed: mustParseSed(t, `s/\./\\./`), dryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invalid := strings.Contains(result.Operations[0].Error, "invalid name"); invalid != (filepath.Separator == '\\') {
+		t.Errorf("operations = %+v, want an invalid name error on Windows only", result.Operations)
+	}
+}
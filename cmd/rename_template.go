@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"amurru/filetools/internal/media"
+)
+
+// renameTemplate is a parsed --template, evaluated for every matched file
+type renameTemplate struct {
+	parts []templatePart
+}
+
+// templatePart is literal text or a token such as {n:03} or {name|lower}
+type templatePart struct {
+	literal string
+	token   string   // Token name, empty for literal text
+	arg     string   // Text after ':', the format of the token
+	filters []string // Case filters applied to the value in order
+}
+
+// templateTokens lists the supported tokens and whether they take an argument
+var templateTokens = map[string]bool{
+	"name":   false,
+	"ext":    false,
+	"parent": false,
+	"n":      true,
+	"mtime":  true,
+	"size":   false,
+	"hash":   true,
+	"taken":  true,
+	"model":  false,
+	"gps":    true,
+}
+
+// templateFilters converts the case of token values
+var templateFilters = map[string]func(string) string{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"title": titleCase,
+}
+
+// parseRenameTemplate parses a template of literal text and {token:arg|filter}
+// tokens. Braces are written literally as {{ and }}.
+func parseRenameTemplate(s string) (*renameTemplate, error) {
+	t := &renameTemplate{}
+	var literal strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{") || strings.HasPrefix(s[i:], "}}"):
+			literal.WriteByte(s[i])
+			i++
+		case s[i] == '}':
+			return nil, fmt.Errorf("invalid template: unmatched '}'")
+		case s[i] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid template: unterminated token")
+			}
+			part, err := parseTemplateToken(s[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			if literal.Len() > 0 {
+				t.parts = append(t.parts, templatePart{literal: literal.String()})
+				literal.Reset()
+			}
+			t.parts = append(t.parts, part)
+			i += end
+		default:
+			literal.WriteByte(s[i])
+		}
+	}
+	if literal.Len() > 0 {
+		t.parts = append(t.parts, templatePart{literal: literal.String()})
+	}
+
+	if len(t.parts) == 0 {
+		return nil, fmt.Errorf("invalid template: empty template")
+	}
+	return t, nil
+}
+
+// parseTemplateToken parses the text between the braces of a token
+func parseTemplateToken(s string) (templatePart, error) {
+	fields := strings.Split(s, "|")
+	part := templatePart{token: fields[0], filters: fields[1:]}
+	if name, arg, ok := strings.Cut(part.token, ":"); ok {
+		part.token, part.arg = name, arg
+	}
+
+	takesArg, known := templateTokens[part.token]
+	if !known {
+		return part, fmt.Errorf("invalid template: unknown token {%s}", part.token)
+	}
+	if part.arg != "" && !takesArg {
+		return part, fmt.Errorf("invalid template: {%s} does not take a format", part.token)
+	}
+	for _, filter := range part.filters {
+		if _, ok := templateFilters[filter]; !ok {
+			return part, fmt.Errorf("invalid template: unknown filter '%s' in {%s}", filter, s)
+		}
+	}
+
+	switch part.token {
+	case "n":
+		if part.arg != "" {
+			if width, err := strconv.Atoi(part.arg); err != nil || width < 0 || width > 20 {
+				return part, fmt.Errorf("invalid template: {n:%s} needs a width such as {n:03}", part.arg)
+			}
+		}
+	case "hash":
+		if part.arg != "" {
+			if length, err := strconv.Atoi(part.arg); err != nil || length < 1 || length > 64 {
+				return part, fmt.Errorf("invalid template: {hash:%s} needs a length from 1 to 64", part.arg)
+			}
+		}
+	case "mtime", "taken":
+		if part.arg == "" {
+			part.arg = "2006-01-02"
+		}
+	case "gps":
+		if part.arg == "" {
+			part.arg = "gps"
+		}
+	}
+	return part, nil
+}
+
+// expand evaluates the template for a file, which is the nth file matched.
+// The result is the new file name, without directory.
+func (t *renameTemplate) expand(path string, info os.FileInfo, n int) (string, error) {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	if ext == base {
+		ext = "" // Hidden file such as .bashrc
+	}
+
+	// Metadata is only read for templates that use it, once per file
+	var meta *media.Metadata
+	readMetadata := func() (*media.Metadata, error) {
+		if meta == nil {
+			m, err := media.Read(path)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read metadata: %w", err)
+			}
+			meta = m
+		}
+		return meta, nil
+	}
+
+	var sb strings.Builder
+	for _, part := range t.parts {
+		if part.token == "" {
+			sb.WriteString(part.literal)
+			continue
+		}
+
+		var value string
+		switch part.token {
+		case "name":
+			value = strings.TrimSuffix(base, ext)
+		case "ext":
+			value = ext
+		case "parent":
+			parent, err := filepath.Abs(filepath.Dir(path))
+			if err != nil {
+				return "", err
+			}
+			value = filepath.Base(parent)
+		case "n":
+			width, _ := strconv.Atoi(part.arg)
+			value = fmt.Sprintf("%0*d", width, n)
+		case "mtime":
+			value = info.ModTime().Format(part.arg)
+		case "size":
+			value = strconv.FormatInt(info.Size(), 10)
+		case "hash":
+			hash, err := calculateHash(path, "sha256")
+			if err != nil {
+				return "", fmt.Errorf("cannot hash file: %w", err)
+			}
+			if part.arg != "" {
+				length, _ := strconv.Atoi(part.arg)
+				hash = hash[:length]
+			}
+			value = hash
+		case "taken":
+			meta, err := readMetadata()
+			if err != nil {
+				return "", err
+			}
+			if meta.Taken.IsZero() {
+				return "", fmt.Errorf("no date taken in metadata")
+			}
+			value = meta.Taken.Format(part.arg)
+		case "model":
+			meta, err := readMetadata()
+			if err != nil {
+				return "", err
+			}
+			if meta.Model == "" {
+				return "", fmt.Errorf("no camera model in metadata")
+			}
+			value = meta.Model
+		case "gps":
+			meta, err := readMetadata()
+			if err != nil {
+				return "", err
+			}
+			if meta.GPS {
+				value = part.arg
+			}
+		}
+
+		for _, filter := range part.filters {
+			value = templateFilters[filter](value)
+		}
+		sb.WriteString(value)
+	}
+
+	name := sb.String()
+	if !validName(name) {
+		return "", fmt.Errorf("template produced an invalid name %q", name)
+	}
+	return name, nil
+}
+
+// titleCase upper-cases the first letter of every word and lower-cases the rest
+func titleCase(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start {
+				runes[i] = unicode.ToUpper(r)
+			} else {
+				runes[i] = unicode.ToLower(r)
+			}
+			start = false
+		} else {
+			start = true
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenameTemplateExpand(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Holiday")
+	writeRenameFiles(t, dir, "IMG_0042.JPG", ".bashrc", "notes")
+	mtime := time.Date(2024, 7, 14, 9, 30, 0, 0, time.Local)
+	for _, name := range []string{"IMG_0042.JPG", ".bashrc", "notes"} {
+		if err := os.Chtimes(filepath.Join(dir, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		template string
+		file     string
+		n        int
+		want     string
+	}{
+		{"{name}{ext}", "IMG_0042.JPG", 1, "IMG_0042.JPG"},
+		{"{name|lower}{ext|lower}", "IMG_0042.JPG", 1, "img_0042.jpg"},
+		{"{mtime:2006-01-02}_{n:03}{ext|lower}", "IMG_0042.JPG", 7, "2024-07-14_007.jpg"},
+		{"{mtime}", "IMG_0042.JPG", 1, "2024-07-14"},
+		{"{mtime:20060102-1504}", "IMG_0042.JPG", 1, "20240714-0930"},
+		{"{parent|lower}-{n}{ext}", "IMG_0042.JPG", 12, "holiday-12.JPG"},
+		{"{name}_{size}b", "IMG_0042.JPG", 1, "IMG_0042_12b"},
+		{"{hash:8}{ext}", "notes", 1, "ab5aa970"},
+		{"{name|title}", "notes", 1, "Notes"},
+		{"{name|upper|title}", "IMG_0042.JPG", 1, "Img_0042"},
+		{"{{{n}}}", "notes", 3, "{3}"},
+		{"{name}.bak", ".bashrc", 1, ".bashrc.bak"},
+		{"x{ext}", ".bashrc", 1, "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := parseRenameTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parseRenameTemplate() error = %v", err)
+			}
+			path := filepath.Join(dir, tt.file)
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tmpl.expand(path, info, tt.n)
+			if err != nil {
+				t.Fatalf("expand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRenameTemplateErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"{name",
+		"name}",
+		"{unknown}",
+		"{name:3}",
+		"{name|reverse}",
+		"{n:abc}",
+		"{hash:0}",
+		"{hash:65}",
+	}
+
+	for _, template := range tests {
+		if _, err := parseRenameTemplate(template); err == nil {
+			t.Errorf("parseRenameTemplate(%q) succeeded, want an error", template)
+		}
+	}
+}
+
+func TestPerformRenamesTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "b.JPG", "a.JPG", "sub/c.JPG", "same.txt")
+
+	tmpl, err := parseRenameTemplate("photo_{n:02}{ext|lower}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := performRenames(dir, nil, nil, renameOptions{match: "*.JPG", template: tmpl})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files are numbered in walk order
+	want := map[string]string{
+		"a.JPG":     "photo_01.jpg",
+		"b.JPG":     "photo_02.jpg",
+		"sub/c.JPG": "sub/photo_03.jpg",
+	}
+	if len(result.Operations) != len(want) {
+		t.Fatalf("got %d operations, want %d", len(result.Operations), len(want))
+	}
+	for _, op := range result.Operations {
+		if op.Error != "" {
+			t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+		}
+		if op.NewPath != want[op.OldPath] {
+			t.Errorf("%s -> %s, want %s", op.OldPath, op.NewPath, want[op.OldPath])
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"photo_01.jpg": "a.JPG", "photo_02.jpg": "b.JPG", "sub/photo_03.jpg": "sub/c.JPG"})
+
+	// A template without a counter gives files the same name, which the
+	// plan refuses, and a name with a separator is invalid
+	tmpl, err = parseRenameTemplate("{size}{ext}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = performRenames(dir, nil, nil, renameOptions{match: "photo_0[12].jpg", template: tmpl, dryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range result.Operations {
+		if op.Error == "" {
+			t.Errorf("%s -> %s: expected a collision error", op.OldPath, op.NewPath)
+		}
+	}
+
+	tmpl, err = parseRenameTemplate("{mtime:2006/01}{ext}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = performRenames(dir, nil, nil, renameOptions{match: "same.txt", template: tmpl, dryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Operations) != 1 || result.Operations[0].Error == "" {
+		t.Errorf("operations = %+v, want an invalid name error", result.Operations)
+	}
+}
+
+// writeExifJPEG writes a JPEG file whose EXIF records a camera model and a
+// date, either of which may be empty
+func writeExifJPEG(t *testing.T, path, model, date string) {
+	t.Helper()
+	var fields [][2]any
+	if model != "" {
+		fields = append(fields, [2]any{uint16(0x0110), model})
+	}
+	if date != "" {
+		fields = append(fields, [2]any{uint16(0x0132), date})
+	}
+
+	// Big-endian TIFF with one directory, its ASCII values after it
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(len(fields)))
+	data := 8 + 2 + 12*len(fields) + 4
+	var values []byte
+	for _, field := range fields {
+		value := field[1].(string) + "\x00"
+		tiff = binary.BigEndian.AppendUint16(tiff, field[0].(uint16))
+		tiff = binary.BigEndian.AppendUint16(tiff, 2)
+		tiff = binary.BigEndian.AppendUint32(tiff, uint32(len(value)))
+		tiff = binary.BigEndian.AppendUint32(tiff, uint32(data+len(values)))
+		values = append(values, value...)
+	}
+	tiff = append(append(tiff, 0, 0, 0, 0), values...)
+
+	exif := append([]byte("Exif\x00\x00"), tiff...)
+	jpeg := []byte{0xff, 0xd8, 0xff, 0xe1}
+	jpeg = binary.BigEndian.AppendUint16(jpeg, uint16(len(exif)+2))
+	jpeg = append(append(jpeg, exif...), 0xff, 0xda, 0x00, 0x02, 0xff, 0xd9)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, jpeg, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenameTemplateMetadata(t *testing.T) {
+	dir := t.TempDir()
+	writeExifJPEG(t, filepath.Join(dir, "IMG_1.JPG"), "Pixel 7", "2023:08:05 14:03:09")
+	writeExifJPEG(t, filepath.Join(dir, "IMG_2.JPG"), "", "2023:08:05 14:03:09")
+	writeExifJPEG(t, filepath.Join(dir, "IMG_3.JPG"), "Pixel 7", "")
+	writeRenameFiles(t, dir, "notes.txt")
+
+	tests := []struct {
+		template string
+		file     string
+		want     string
+		wantErr  string
+	}{
+		{"{taken}{ext}", "IMG_1.JPG", "2023-08-05.JPG", ""},
+		{"{taken:20060102_150405}_{model|lower}{ext|lower}", "IMG_1.JPG", "20230805_140309_pixel 7.jpg", ""},
+		{"{name}{gps:_geo}", "IMG_1.JPG", "IMG_1", ""},
+		{"{model}", "IMG_2.JPG", "", "no camera model in metadata"},
+		{"{taken}", "IMG_3.JPG", "", "no date taken in metadata"},
+		{"{taken}", "notes.txt", "", "cannot read metadata: unsupported file format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+"/"+tt.file, func(t *testing.T) {
+			tmpl, err := parseRenameTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parseRenameTemplate() error = %v", err)
+			}
+			path := filepath.Join(dir, tt.file)
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tmpl.expand(path, info, 1)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("expand() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerformRenamesByExifDate(t *testing.T) {
+	dir := t.TempDir()
+	writeExifJPEG(t, filepath.Join(dir, "a.JPG"), "", "2023:08:05 14:03:09")
+	writeExifJPEG(t, filepath.Join(dir, "b.jpg"), "", "2023:08:06 09:00:00")
+	writeExifJPEG(t, filepath.Join(dir, "c.jpg"), "", "2023:08:06 09:00:00")
+	writeRenameFiles(t, dir, "d.jpg")
+
+	tmpl, err := parseRenameTemplate(exifDateTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := performRenames(dir, nil, nil, renameOptions{match: "*.[Jj][Pp][Gg]", template: tmpl, dryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Shots of the same second collide, files without metadata fail
+	want := map[string]struct{ newPath, err string }{
+		"a.JPG": {"2023-08-05_140309.jpg", ""},
+		"b.jpg": {"2023-08-06_090000.jpg", "target also planned for"},
+		"c.jpg": {"2023-08-06_090000.jpg", "target also planned for"},
+		"d.jpg": {"d.jpg", "cannot read metadata: unsupported file format"},
+	}
+	if len(result.Operations) != len(want) {
+		t.Fatalf("got %d operations, want %d", len(result.Operations), len(want))
+	}
+	for _, op := range result.Operations {
+		w := want[op.OldPath]
+		if op.NewPath != w.newPath {
+			t.Errorf("%s -> %s, want %s", op.OldPath, op.NewPath, w.newPath)
+		}
+		if (w.err == "") != (op.Error == "") || !strings.HasPrefix(op.Error, w.err) {
+			t.Errorf("%s: error = %q, want %q", op.OldPath, op.Error, w.err)
+		}
+	}
+}
@@ -1,49 +1,15 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
-)
 
-func TestParseSedExpression(t *testing.T) {
-	tests := []struct {
-		input    string
-		wantRe   string
-		wantRepl string
-		wantGlob bool
-		wantErr  bool
-	}{
-		{"s/old/new/", "old", "new", false, false},
-		{"s/old/new/g", "old", "new", true, false},
-		{"s/(.+)/prefix_$1/", "(.+)", "prefix_$1", false, false},
-		{"invalid", "", "", false, true},
-		{"s/old/", "", "", false, true},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			re, repl, glob, err := parseSedExpression(tt.input)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseSedExpression() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if err != nil {
-				return
-			}
-			if re.String() != tt.wantRe {
-				t.Errorf("parseSedExpression() re = %v, want %v", re.String(), tt.wantRe)
-			}
-			if repl != tt.wantRepl {
-				t.Errorf("parseSedExpression() repl = %v, want %v", repl, tt.wantRepl)
-			}
-			if glob != tt.wantGlob {
-				t.Errorf("parseSedExpression() glob = %v, want %v", glob, tt.wantGlob)
-			}
-		})
-	}
-}
+	"amurru/filetools/internal/exclusions"
+)
 
 func TestPerformRenames(t *testing.T) {
 	// Create temporary directory
@@ -63,11 +29,16 @@ func TestPerformRenames(t *testing.T) {
 	}
 
 	// Test rename
-	re, _ := regexp.Compile("test")
-	ops, excl, err := performRenames(tmpDir, "*.jpg", re, "renamed", false, true, false, nil, nil)
+	script, err := parseSedExpression("s/test/renamed/")
 	if err != nil {
 		t.Fatal(err)
 	}
+	result, err := performRenames(tmpDir, nil, nil, renameOptions{match: "*.jpg", sed: script, dryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := result.Operations
+	excl := result.Exclusions
 
 	if len(excl) != 0 {
 		t.Errorf("expected no exclusions, got %d", len(excl))
@@ -107,11 +78,15 @@ func TestPerformRenamesDryRun(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	re, _ := regexp.Compile("old")
-	ops, _, err := performRenames(tmpDir, "*.jpg", re, "new", false, true, false, nil, nil)
+	script, err := parseSedExpression("s/old/new/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := performRenames(tmpDir, nil, nil, renameOptions{match: "*.jpg", sed: script, dryRun: true})
 	if err != nil {
 		t.Fatal(err)
 	}
+	ops := result.Operations
 
 	if len(ops) != 1 {
 		t.Errorf("expected 1 operation, got %d", len(ops))
@@ -147,11 +122,16 @@ func TestPerformRenamesMultipleDirs(t *testing.T) {
 	}
 
 	// Test rename
-	re, _ := regexp.Compile("test")
-	ops, excl, err := performRenames(tmpDir, "*.jpg", re, "renamed", false, true, false, nil, nil)
+	script, err := parseSedExpression("s/test/renamed/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := performRenames(tmpDir, nil, nil, renameOptions{match: "*.jpg", sed: script, dryRun: true})
 	if err != nil {
 		t.Fatal(err)
 	}
+	ops := result.Operations
+	excl := result.Exclusions
 
 	if len(excl) != 0 {
 		t.Errorf("expected no exclusions, got %d", len(excl))
@@ -177,3 +157,252 @@ func TestPerformRenamesMultipleDirs(t *testing.T) {
 		}
 	}
 }
+
+func TestPerformRenamesDirectories(t *testing.T) {
+	tests := []struct {
+		entryType string
+		want      map[string]string // New path of each old path
+	}{
+		{"f", map[string]string{
+			"draft/draft.txt":           "draft/final.txt",
+			"draft/draft-notes/old.txt": "draft/draft-notes/old.txt",
+		}},
+		{"d", map[string]string{
+			"draft":             "final",
+			"draft/draft-notes": "final/final-notes",
+		}},
+		{"all", map[string]string{
+			"draft":             "final",
+			"draft/draft.txt":   "final/final.txt",
+			"draft/draft-notes": "final/final-notes",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entryType, func(t *testing.T) {
+			dir := t.TempDir()
+			writeRenameFiles(t, dir, "draft/draft.txt", "draft/draft-notes/old.txt")
+
+			result, err := performRenames(dir, nil, nil, renameOptions{
+				match:     "draft*",
+				entryType: tt.entryType,
+				sed:       mustParseSed(t, "s/draft/final/"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]string)
+			for _, op := range result.Operations {
+				if op.Error != "" {
+					t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+				}
+				got[filepath.ToSlash(op.OldPath)] = filepath.ToSlash(op.NewPath)
+			}
+			for oldPath, newPath := range tt.want {
+				if oldPath != newPath && got[oldPath] != newPath {
+					t.Errorf("%s -> %s, want %s", oldPath, got[oldPath], newPath)
+				}
+			}
+
+			// Every file is found at the reported path
+			for _, op := range result.Operations {
+				if _, err := os.Lstat(filepath.Join(dir, op.NewPath)); err != nil {
+					t.Errorf("%s: %v", op.NewPath, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPerformRenamesDirectoryConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a/a.txt", "b/keep.txt")
+
+	// The directory cannot be renamed onto b, the file in it still is
+	result, err := performRenames(dir, nil, nil, renameOptions{
+		match:     "a*",
+		entryType: "all",
+		sed:       mustParseSed(t, "s/^a/b/"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range result.Operations {
+		switch op.OldPath {
+		case "a":
+			if op.Error == "" {
+				t.Errorf("a -> %s: expected a conflict", op.NewPath)
+			}
+		case filepath.Join("a", "a.txt"):
+			if op.Error != "" || op.NewPath != filepath.Join("a", "b.txt") {
+				t.Errorf("a/a.txt -> %s (%s), want a/b.txt", op.NewPath, op.Error)
+			}
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"a/b.txt": "a/a.txt", "b/keep.txt": "b/keep.txt"})
+}
+
+func TestPerformRenamesDepth(t *testing.T) {
+	tests := []struct {
+		minDepth, maxDepth int
+		want               []string
+	}{
+		{0, 0, []string{"a.txt", "d1/a.txt", "d1/d2/a.txt", "d1/d2/d3/a.txt"}},
+		{0, 1, []string{"a.txt"}},
+		{0, 2, []string{"a.txt", "d1/a.txt"}},
+		{2, 0, []string{"d1/a.txt", "d1/d2/a.txt", "d1/d2/d3/a.txt"}},
+		{2, 3, []string{"d1/a.txt", "d1/d2/a.txt"}},
+		{3, 3, []string{"d1/d2/a.txt"}},
+		{0, 4, []string{"a.txt", "d1/a.txt", "d1/d2/a.txt", "d1/d2/d3/a.txt"}},
+	}
+
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a.txt", "d1/a.txt", "d1/d2/a.txt", "d1/d2/d3/a.txt", "d1/d2/d3/skip/a.txt")
+	dirMatchers := exclusions.ParseExclusions("skip", false)
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("min%d-max%d", tt.minDepth, tt.maxDepth), func(t *testing.T) {
+			result, err := performRenames(dir, nil, dirMatchers, renameOptions{
+				match:    "a.txt",
+				sed:      mustParseSed(t, "s/a/b/"),
+				minDepth: tt.minDepth,
+				maxDepth: tt.maxDepth,
+				dryRun:   true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, op := range result.Operations {
+				got = append(got, filepath.ToSlash(op.OldPath))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("renamed %v, want %v", got, tt.want)
+			}
+
+			// The walk stops at the maximum depth instead of filtering, so
+			// the excluded directory below it is never reached
+			if reached := len(result.Exclusions) > 0; reached != (tt.maxDepth == 0 || tt.maxDepth >= 4) {
+				t.Errorf("exclusions = %+v", result.Exclusions)
+			}
+		})
+	}
+
+	// A directory at the maximum depth is itself renamed
+	result, err := performRenames(dir, nil, nil, renameOptions{
+		match:     "d*",
+		entryType: "d",
+		sed:       mustParseSed(t, "s/d/e/"),
+		maxDepth:  1,
+		dryRun:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Operations) != 1 || result.Operations[0].NewPath != "e1" {
+		t.Errorf("operations = %+v, want d1 -> e1", result.Operations)
+	}
+}
+
+func TestPerformRenamesMatching(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a.txt", "b.TXT", "drafts/c.txt", "docs/drafts/d.txt", "docs/e.txt")
+
+	tests := []struct {
+		name string
+		opts renameOptions
+		want []string
+	}{
+		{"glob", renameOptions{match: "*.txt"}, []string{"a.txt", "docs/drafts/d.txt", "docs/e.txt", "drafts/c.txt"}},
+		{"regex", renameOptions{matchRegex: regexp.MustCompile(`(?i)^[ab]\.txt$`)}, []string{"a.txt", "b.TXT"}},
+		{"path", renameOptions{matchPath: "**/drafts/*.txt"}, []string{"docs/drafts/d.txt", "drafts/c.txt"}},
+		{"path top level", renameOptions{matchPath: "*.txt"}, []string{"a.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.sed = mustParseSed(t, "s/$/.bak/")
+			tt.opts.dryRun = true
+			result, err := performRenames(dir, nil, nil, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, op := range result.Operations {
+				got = append(got, filepath.ToSlash(op.OldPath))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerformRenamesSedPath(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "2023-01-a.txt", "2023-02-b.txt", "2024-01-c.txt", "x/2024-05-d.txt")
+
+	// Files are moved into year directories, which are created
+	result, err := performRenames(dir, nil, nil, renameOptions{
+		match:   "*.txt",
+		sed:     mustParseSed(t, `s|^(x/)?([0-9]{4})-|\2/|`),
+		sedPath: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range result.Operations {
+		if op.Error != "" {
+			t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{
+		"2023/01-a.txt": "2023-01-a.txt",
+		"2023/02-b.txt": "2023-02-b.txt",
+		"2024/01-c.txt": "2024-01-c.txt",
+		"2024/05-d.txt": "x/2024-05-d.txt",
+	})
+
+	// Paths leaving the directory are refused
+	result, err = performRenames(dir, nil, nil, renameOptions{
+		match:   "01-a.txt",
+		sed:     mustParseSed(t, "s|^|../|"),
+		sedPath: true,
+		dryRun:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Operations) != 1 || !strings.Contains(result.Operations[0].Error, "outside of the directory") {
+		t.Errorf("operations = %+v, want an outside of the directory error", result.Operations)
+	}
+}
+
+func TestPerformRenamesSedPathAtomic(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a.txt", "b.txt", "new/sub/b.txt/keep")
+
+	// Moving b.txt fails as new/sub/b.txt is a directory that is not empty;
+	// the move of a.txt is reverted and the directories it needed removed
+	result, err := performRenames(dir, nil, nil, renameOptions{
+		match:     "*.txt",
+		sed:       mustParseSed(t, "s|^|new/sub/|;s|^new/sub/a|new/made/a|"),
+		sedPath:   true,
+		dryRun:    true,
+		overwrite: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = runRenamePlan(newRenamePlan(dir, result.Operations, true), false, true, "")
+	if !result.RolledBack {
+		t.Fatalf("expected a rollback, got %+v", result.Operations)
+	}
+	checkRenameContents(t, dir, map[string]string{"a.txt": "a.txt", "b.txt": "b.txt"})
+	if _, err := os.Lstat(filepath.Join(dir, "new", "made")); !os.IsNotExist(err) {
+		t.Errorf("created directory left behind: %v", err)
+	}
+}
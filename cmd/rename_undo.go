@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"amurru/filetools/internal/journal"
+	"amurru/filetools/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// renameUndoCmd represents the rename undo command
+var renameUndoCmd = &cobra.Command{
+	Use:   "undo [journal]",
+	Short: "Revert the renames recorded in an undo journal",
+	Long: `Revert the renames recorded in an undo journal written by rename --force.
+
+The journal is replayed in reverse, renaming every file back to its old name.
+Before anything is renamed, every file is checked to still be where the
+journal expects it, with the same size and modification time. Files that
+were moved, deleted or modified since are reported and left alone, as are
+old names that have been taken by another file since and renames that
+replaced a file with --overwrite, as the replaced file cannot be restored.
+
+If no journal is specified, the most recent journal in the journal directory
+is used. Reverting writes a journal of its own, so an undo can be undone.
+
+Dry-run mode is enabled by default for safety. Use --force to revert the renames.
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runRenameUndo,
+}
+
+var forceUndo bool
+
+func init() {
+	renameCmd.AddCommand(renameUndoCmd)
+
+	renameUndoCmd.Flags().BoolVar(&forceUndo, "force", false, "Perform actual renames (disables dry-run)")
+}
+
+// renameJournalDir returns the journal directory given with --journal-dir,
+// or the default
+func renameJournalDir() (string, error) {
+	if journalDir != "" {
+		return journalDir, nil
+	}
+	return journal.DefaultDir()
+}
+
+// runRenamePlan applies a plan unless in dry-run mode and reports the
+// outcome. The successful renames are recorded in a journal in dir, unless
+// dir is empty or nothing was renamed. The renames are done at that point,
+// so a journal that cannot be written is only a warning. An atomic plan
+// with conflicts is not applied at all. New paths are reported where the
+// files end up, which is below the old path of a directory whose rename
+// failed.
+func runRenamePlan(plan *renamePlan, dryRun, atomic bool, dir string) *output.RenameResult {
+	result := &output.RenameResult{DryRun: dryRun}
+	proceed := !atomic || plan.requireAll()
+	if dryRun && proceed {
+		plan.settle(func(i int) bool { return plan.operations[i].Error == "" })
+	}
+	if !dryRun && proceed {
+		plan.apply(atomic)
+		inEffect := make(map[int]bool, len(plan.applied))
+		for _, i := range plan.applied {
+			inEffect[i] = true
+		}
+		plan.settle(func(i int) bool { return inEffect[i] })
+		for _, op := range plan.operations {
+			if op.Rollback != "" {
+				result.RolledBack = true
+			}
+		}
+
+		if dir != "" && len(plan.applied) > 0 {
+			path, err := writeRenameJournal(plan, dir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not write undo journal: %v\n", err)
+			}
+			result.Journal = path
+		}
+	}
+	// Only the renames that go ahead replace a file
+	for i := range plan.operations {
+		if plan.operations[i].Error != "" {
+			plan.operations[i].Overwrite = false
+		}
+	}
+	result.Operations = plan.operations
+	return result
+}
+
+// writeRenameJournal records the applied renames of a plan in a new journal in dir
+func writeRenameJournal(plan *renamePlan, dir string) (string, error) {
+	root, err := filepath.Abs(plan.root)
+	if err != nil {
+		return "", err
+	}
+
+	record := &journal.Journal{Time: time.Now().UTC(), Root: root}
+	for _, i := range plan.applied {
+		op := plan.operations[i]
+		rename := journal.Rename{OldPath: op.OldPath, NewPath: op.NewPath, Overwrote: op.Overwrite}
+		if info, err := os.Lstat(filepath.Join(root, op.NewPath)); err == nil {
+			rename.Dir = info.IsDir()
+			rename.Size = info.Size()
+			rename.ModTime = info.ModTime().UTC()
+		}
+		record.Renames = append(record.Renames, rename)
+	}
+	return journal.Write(dir, record)
+}
+
+// undoRenames plans renaming the files of a journal back, last rename first,
+// and performs it unless in dry-run mode. Files that changed since the
+// journal was written are reported as errors and left alone; directories
+// are only checked to still be directories. Renames that replaced a file are
+// left alone too, as the replaced file cannot be brought back.
+func undoRenames(record *journal.Journal, dryRun, atomic bool, dir string) *output.RenameResult {
+	var operations []output.RenameOperation
+	for i := len(record.Renames) - 1; i >= 0; i-- {
+		rename := record.Renames[i]
+		op := output.RenameOperation{OldPath: rename.NewPath, NewPath: rename.OldPath}
+
+		info, err := os.Lstat(filepath.Join(record.Root, rename.NewPath))
+		switch {
+		case rename.Overwrote:
+			op.Error = "replaced file not restorable"
+		case os.IsNotExist(err):
+			op.Error = "file no longer exists"
+		case err != nil:
+			op.Error = fmt.Sprintf("cannot check file: %v", err)
+		case info.IsDir() != rename.Dir:
+			op.Error = "file changed since the rename"
+		case rename.Dir:
+			// Directories change whenever their contents do
+		case info.Size() != rename.Size || !info.ModTime().Equal(rename.ModTime):
+			op.Error = "file changed since the rename"
+		}
+		operations = append(operations, op)
+	}
+
+	return runRenamePlan(newRenamePlan(record.Root, operations, false), dryRun, atomic, dir)
+}
+
+// runRenameUndo executes the rename undo command
+func runRenameUndo(cmd *cobra.Command, args []string) {
+	dir, err := renameJournalDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	} else if path, err = journal.Latest(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	record, err := journal.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	result := undoRenames(record, !forceUndo, atomicRename, dir)
+
+	// Get output writer (file or stdout)
+	writer, cleanup, err := getOutputWriter(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	// Get output format and create formatter
+	format := getOutputFormat(cmd)
+	formatter := output.NewFormatter(format)
+
+	// Create metadata
+	flags := []output.Flag{
+		{Name: "journal", Value: path},
+		{Name: "dry-run", Value: fmt.Sprintf("%t", !forceUndo)},
+		{Name: "output", Value: string(format)},
+	}
+	if outputFile != "" {
+		flags = append(flags, output.Flag{Name: "file", Value: outputFile})
+	}
+	if journalDir != "" {
+		flags = append(flags, output.Flag{Name: "journal-dir", Value: journalDir})
+	}
+	if atomicRename {
+		flags = append(flags, output.Flag{Name: "atomic", Value: "true"})
+	}
+
+	result.Metadata = &output.Metadata{
+		ToolName:    "filetools",
+		SubCommand:  "rename undo",
+		Flags:       flags,
+		Version:     version,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+	}
+
+	if err := formatter.FormatRename(result, writer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"amurru/filetools/internal/journal"
+)
+
+func TestRenameUndoRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	journals := t.TempDir()
+	writeRenameFiles(t, dir, "a.txt", "aa.txt", "sub/a.txt", "keep.txt")
+
+	// aa → aaa must happen before a → aa, and the undo reverses the chain
+	result, err := performRenames(dir, nil, nil, renameOptions{
+		match:      "a*.txt",
+		sed:        mustParseSed(t, "s/^a/aa/"),
+		journalDir: journals,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Journal == "" {
+		t.Fatal("no journal written")
+	}
+	checkRenameContents(t, dir, map[string]string{"aa.txt": "a.txt", "aaa.txt": "aa.txt", "sub/aa.txt": "sub/a.txt"})
+
+	latest, err := journal.Latest(journals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != result.Journal {
+		t.Errorf("latest journal %s, want %s", latest, result.Journal)
+	}
+	record, err := journal.Load(latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A dry run reports the plan without renaming anything
+	undo := undoRenames(record, true, false, journals)
+	if len(undo.Operations) != 3 || undo.Journal != "" {
+		t.Errorf("dry run = %+v", undo)
+	}
+	checkRenameContents(t, dir, map[string]string{"aa.txt": "a.txt"})
+
+	undo = undoRenames(record, false, false, journals)
+	for _, op := range undo.Operations {
+		if op.Error != "" {
+			t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"a.txt": "a.txt", "aa.txt": "aa.txt", "sub/a.txt": "sub/a.txt", "keep.txt": "keep.txt"})
+
+	// The undo wrote a journal of its own
+	if undo.Journal == "" || undo.Journal == result.Journal {
+		t.Errorf("undo journal = %q", undo.Journal)
+	}
+}
+
+func TestRenameUndoChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeRenameFiles(t, dir, "a.txt", "b.txt", "c.txt")
+
+	result, err := performRenames(dir, nil, nil, renameOptions{
+		match:      "*.txt",
+		sed:        mustParseSed(t, `s/\.txt$/.md/`),
+		journalDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := journal.Load(result.Journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Modify one renamed file, remove another and take the old name of the last
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "b.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeRenameFiles(t, dir, "c.txt")
+
+	undo := undoRenames(record, false, false, "")
+	want := map[string]string{
+		"a.md": "file changed since the rename",
+		"b.md": "file no longer exists",
+		"c.md": "target file already exists",
+	}
+	for _, op := range undo.Operations {
+		if op.Error != want[op.OldPath] {
+			t.Errorf("%s: error %q, want %q", op.OldPath, op.Error, want[op.OldPath])
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"a.md": "edited", "c.md": "c.txt", "c.txt": "c.txt"})
+	if undo.Journal != "" {
+		t.Errorf("journal %s written without renames", undo.Journal)
+	}
+}
+
+func TestRenameUndoDirectories(t *testing.T) {
+	dir := t.TempDir()
+	journals := t.TempDir()
+	writeRenameFiles(t, dir, "2023/2023-01.txt", "2023/misc/2023.txt")
+
+	result, err := performRenames(dir, nil, nil, renameOptions{
+		match:      "2023*",
+		entryType:  "all",
+		sed:        mustParseSed(t, "s/2023/2024/"),
+		journalDir: journals,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRenameContents(t, dir, map[string]string{"2024/2024-01.txt": "2023/2023-01.txt", "2024/misc/2024.txt": "2023/misc/2023.txt"})
+
+	// Adding a file changes the directory, which does not prevent the undo
+	writeRenameFiles(t, dir, "2024/new.txt")
+
+	record, err := journal.Load(result.Journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	undo := undoRenames(record, false, false, journals)
+	for _, op := range undo.Operations {
+		if op.Error != "" {
+			t.Errorf("%s: unexpected error %s", op.OldPath, op.Error)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"2023/2023-01.txt": "2023/2023-01.txt", "2023/misc/2023.txt": "2023/misc/2023.txt", "2023/new.txt": "2024/new.txt"})
+}
+
+func TestRenameUndoOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	journals := t.TempDir()
+	writeRenameFiles(t, dir, "a.log", "b.log", "c.txt")
+
+	// a.log replaces b.log, which the journal cannot bring back
+	result, err := performRenames(dir, nil, nil, renameOptions{
+		match:      "[ac].*",
+		sed:        mustParseSed(t, "s/^a/b/;s/^c/d/"),
+		overwrite:  true,
+		journalDir: journals,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRenameContents(t, dir, map[string]string{"b.log": "a.log", "d.txt": "c.txt"})
+
+	record, err := journal.Load(result.Journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overwrote := make(map[string]bool)
+	for _, rename := range record.Renames {
+		overwrote[rename.OldPath] = rename.Overwrote
+	}
+	if !overwrote["a.log"] || overwrote["c.txt"] {
+		t.Errorf("journal renames = %+v, want only a.log marked as overwriting", record.Renames)
+	}
+
+	undo := undoRenames(record, false, false, journals)
+	for _, op := range undo.Operations {
+		want := ""
+		if op.OldPath == "b.log" {
+			want = "replaced file not restorable"
+		}
+		if op.Error != want {
+			t.Errorf("%s: error %q, want %q", op.OldPath, op.Error, want)
+		}
+	}
+	checkRenameContents(t, dir, map[string]string{"b.log": "a.log", "c.txt": "c.txt"})
+}
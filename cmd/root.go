@@ -53,7 +53,7 @@ func init() {
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.filetools.yaml)")
 
 	// Output format flags
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, xml, html")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, xml, html, openmetrics")
 	rootCmd.PersistentFlags().BoolP("json", "j", false, "Output in JSON format (shortcut for -o json)")
 	rootCmd.PersistentFlags().BoolP("xml", "x", false, "Output in XML format (shortcut for -o xml)")
 	rootCmd.PersistentFlags().BoolP("html", "w", false, "Output in HTML format (shortcut for -o html)")
@@ -91,6 +91,8 @@ func getOutputFormat(cmd *cobra.Command) output.OutputFormat {
 		return output.FormatXML
 	case "html":
 		return output.FormatHTML
+	case "openmetrics":
+		return output.FormatOpenMetrics
 	case "text":
 		fallthrough
 	default:
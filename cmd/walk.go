@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// walkParallel walks the file tree rooted at root like filepath.Walk, but
+// reads up to limit directories concurrently. walkFn may be called from
+// several goroutines at once and must synchronize access to shared state.
+// A directory's entries are only visited after walkFn has returned for the
+// directory itself, so returning filepath.SkipDir works as with filepath.Walk.
+func walkParallel(root string, limit int, walkFn filepath.WalkFunc) error {
+	if limit < 1 {
+		limit = 1
+	}
+
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walkFn(root, info, nil)
+	}
+	if err != nil {
+		if err == filepath.SkipDir || err == filepath.SkipAll {
+			return nil
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	w := &parallelWalker{
+		walkFn:  walkFn,
+		queue:   []queuedDir{{path: root, info: info}},
+		pending: 1,
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	var wg sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+
+	if w.err == filepath.SkipAll {
+		return nil
+	}
+	return w.err
+}
+
+// queuedDir is a directory waiting to be read by a parallelWalker
+type queuedDir struct {
+	path string
+	info os.FileInfo
+}
+
+// parallelWalker holds the shared state of the workers of walkParallel
+type parallelWalker struct {
+	walkFn filepath.WalkFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []queuedDir
+	pending int // Directories queued or being read
+	err     error
+}
+
+// work reads queued directories until the whole tree has been walked
+func (w *parallelWalker) work() {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && w.pending > 0 {
+			w.cond.Wait()
+		}
+		if w.pending == 0 {
+			w.mu.Unlock()
+			return
+		}
+		// Take the most recently queued directory to keep the queue short
+		dir := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+		w.mu.Unlock()
+
+		subdirs, err := w.readDir(dir)
+
+		w.mu.Lock()
+		if err != nil && w.err == nil {
+			// Stop the walk: drop queued work, in-flight reads finish on their own
+			w.err = err
+			w.pending -= len(w.queue)
+			w.queue = nil
+		}
+		if w.err == nil {
+			w.queue = append(w.queue, subdirs...)
+			w.pending += len(subdirs)
+		}
+		w.pending--
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// readDir visits the entries of a directory and returns its subdirectories
+// that still need to be read
+func (w *parallelWalker) readDir(dir queuedDir) ([]queuedDir, error) {
+	entries, err := os.ReadDir(dir.path)
+	if err != nil {
+		if err := w.walkFn(dir.path, dir.info, err); err != nil && err != filepath.SkipDir {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	var subdirs []queuedDir
+	for _, entry := range entries {
+		path := filepath.Join(dir.path, entry.Name())
+
+		info, err := entry.Info()
+		if err != nil {
+			if err := w.walkFn(path, nil, err); err != nil && err != filepath.SkipDir {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := w.walkFn(path, info, nil); err != nil {
+			if err != filepath.SkipDir {
+				return nil, err
+			}
+			if !info.IsDir() {
+				// SkipDir on a file skips the remaining entries of its directory
+				break
+			}
+			continue
+		}
+
+		if info.IsDir() {
+			subdirs = append(subdirs, queuedDir{path: path, info: info})
+		}
+	}
+
+	return subdirs, nil
+}
+
+// walkOrderLess reports whether relative path a is visited before b by
+// filepath.Walk, which walks depth-first with entries in lexical order
+func walkOrderLess(a, b string) bool {
+	partsA := strings.Split(a, string(filepath.Separator))
+	partsB := strings.Split(b, string(filepath.Separator))
+
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		if partsA[i] != partsB[i] {
+			return partsA[i] < partsB[i]
+		}
+	}
+	return len(partsA) < len(partsB)
+}
+
+// sortWalkOrder sorts relative paths in the order filepath.Walk visits them
+func sortWalkOrder(paths []string) {
+	sort.Slice(paths, func(i, j int) bool {
+		return walkOrderLess(paths[i], paths[j])
+	})
+}
@@ -0,0 +1,142 @@
+package categories
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"amurru/filetools/internal/pathmatch"
+)
+
+// Uncategorised is the bucket for files that match no configured category
+const Uncategorised = "uncategorised"
+
+// Category is a user-defined group of files. A file belongs to the category
+// if it matches any of the globs, extensions or path prefixes.
+type Category struct {
+	Name       string   `json:"name"`
+	Globs      []string `json:"globs,omitempty"`      // Without a slash matched against the file name, otherwise against the relative path
+	Extensions []string `json:"extensions,omitempty"` // Case-insensitive file name suffixes such as ".log" or ".tar.gz"
+	Prefixes   []string `json:"prefixes,omitempty"`   // Relative directory paths such as "build" or "web/dist"
+}
+
+// Config is an ordered list of categories, the first matching category wins
+type Config struct {
+	Categories []Category `json:"categories"`
+}
+
+// Load reads and validates a category configuration file
+func Load(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return config, nil
+}
+
+// Parse decodes and validates a JSON category configuration
+func Parse(data []byte) (*Config, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var config Config
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid category configuration: %w", err)
+	}
+	if len(config.Categories) == 0 {
+		return nil, fmt.Errorf("no categories defined")
+	}
+
+	seen := make(map[string]bool)
+	for i := range config.Categories {
+		category := &config.Categories[i]
+		category.Name = strings.TrimSpace(category.Name)
+		if category.Name == "" {
+			return nil, fmt.Errorf("category %d has no name", i+1)
+		}
+		if strings.EqualFold(category.Name, Uncategorised) {
+			return nil, fmt.Errorf("category name %q is reserved", category.Name)
+		}
+		if seen[category.Name] {
+			return nil, fmt.Errorf("duplicate category %q", category.Name)
+		}
+		seen[category.Name] = true
+
+		for _, glob := range category.Globs {
+			if err := pathmatch.Validate(glob); err != nil {
+				return nil, fmt.Errorf("category %q: invalid glob %q: %w", category.Name, glob, err)
+			}
+		}
+		for j, ext := range category.Extensions {
+			ext = strings.ToLower(strings.TrimSpace(ext))
+			if ext != "" && !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			category.Extensions[j] = ext
+		}
+		for j, prefix := range category.Prefixes {
+			category.Prefixes[j] = strings.Trim(path.Clean(filepath.ToSlash(prefix)), "/")
+		}
+	}
+
+	return &config, nil
+}
+
+// Names returns the configured category names in configuration order
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Categories))
+	for _, category := range c.Categories {
+		names = append(names, category.Name)
+	}
+	return names
+}
+
+// Match returns the name of the first category containing the file at the
+// given relative path, or Uncategorised
+func (c *Config) Match(relPath string) string {
+	relPath = filepath.ToSlash(relPath)
+	for _, category := range c.Categories {
+		if category.matches(relPath) {
+			return category.Name
+		}
+	}
+	return Uncategorised
+}
+
+// matches reports whether a slash-separated relative path belongs to the category
+func (c *Category) matches(relPath string) bool {
+	base := path.Base(relPath)
+
+	lowerBase := strings.ToLower(base)
+	for _, ext := range c.Extensions {
+		if ext != "" && strings.HasSuffix(lowerBase, ext) {
+			return true
+		}
+	}
+
+	for _, prefix := range c.Prefixes {
+		if prefix == "." || relPath == prefix || strings.HasPrefix(relPath, prefix+"/") {
+			return true
+		}
+	}
+
+	for _, glob := range c.Globs {
+		name := relPath
+		if !strings.Contains(glob, "/") {
+			name = base
+		}
+		if matched, _ := pathmatch.Match(glob, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
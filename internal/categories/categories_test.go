@@ -0,0 +1,75 @@
+package categories
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `{
+	"categories": [
+		{"name": "build artifacts", "prefixes": ["build/", "./web/dist"], "globs": ["**/target/**", "*.o"]},
+		{"name": "logs", "extensions": ["log", ".LOG.gz"]},
+		{"name": "media", "extensions": [".jpg", ".mp4"]},
+		{"name": "source", "globs": ["src/**/*.go", "Makefile"]}
+	]
+}`
+
+func TestMatch(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"build/app", "build artifacts"},
+		{"builder/app", Uncategorised},
+		{"web/dist/bundle.js", "build artifacts"},
+		{"java/target/classes/A.class", "build artifacts"},
+		{"lib/util.o", "build artifacts"},
+		{"var/app.log", "logs"},
+		{"var/app.log.gz", "logs"},
+		{"photos/IMG.JPG", "media"},
+		{"src/main.go", "source"},
+		{"src/cmd/tool/main.go", "source"},
+		{"tools/main.go", Uncategorised},
+		{"Makefile", "source"},
+		{"build/debug.log", "build artifacts"}, // The first matching category wins
+		{filepath.Join("build", "nested", "x"), "build artifacts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := config.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"empty", `{"categories": []}`, "no categories"},
+		{"unnamed", `{"categories": [{"globs": ["*"]}]}`, "has no name"},
+		{"reserved", `{"categories": [{"name": "Uncategorised"}]}`, "reserved"},
+		{"duplicate", `{"categories": [{"name": "a"}, {"name": "a"}]}`, "duplicate"},
+		{"bad glob", `{"categories": [{"name": "a", "globs": ["[a-"]}]}`, "invalid glob"},
+		{"unknown field", `{"categories": [{"name": "a", "glob": ["*"]}]}`, "unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.config))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package filetype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Content categories reported for detected file types
+const (
+	CategoryImage    = "image"
+	CategoryVideo    = "video"
+	CategoryAudio    = "audio"
+	CategoryArchive  = "archive"
+	CategorySource   = "source"
+	CategoryDocument = "document"
+	CategoryBinary   = "binary"
+	CategoryEmpty    = "empty"
+)
+
+// sniffLen is the number of leading bytes read for detection. It covers
+// both net/http's 512 byte sniffing window and the tar header magic.
+const sniffLen = 512
+
+// Info describes the detected content type of a file
+type Info struct {
+	MIMEType string
+	Category string
+}
+
+// magic describes a signature found at a fixed offset in a file
+type magic struct {
+	offset   int
+	sig      []byte
+	mimeType string
+}
+
+// magicTable lists signatures for common formats that net/http does not
+// detect, or detects only as a generic type
+var magicTable = []magic{
+	{0, []byte("\x7fELF"), "application/x-executable"},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xce}, "application/x-mach-binary"},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xcf}, "application/x-mach-binary"},
+	{0, []byte{0xce, 0xfa, 0xed, 0xfe}, "application/x-mach-binary"},
+	{0, []byte{0xcf, 0xfa, 0xed, 0xfe}, "application/x-mach-binary"},
+	{0, []byte{0xca, 0xfe, 0xba, 0xbe}, "application/java-vm"},
+	{0, []byte("\x00asm"), "application/wasm"},
+	{0, []byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+	{0, []byte("BZh"), "application/x-bzip2"},
+	{0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "application/x-xz"},
+	{0, []byte{0x28, 0xb5, 0x2f, 0xfd}, "application/zstd"},
+	{0, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, "application/x-7z-compressed"},
+	{0, []byte("Rar!\x1a\x07"), "application/x-rar-compressed"},
+	{257, []byte("ustar"), "application/x-tar"},
+	{0, []byte("II*\x00"), "image/tiff"},
+	{0, []byte("MM\x00*"), "image/tiff"},
+	{4, []byte("ftypheic"), "image/heic"},
+	{4, []byte("ftypqt"), "video/quicktime"},
+	{0, []byte("fLaC"), "audio/flac"},
+	{0, []byte{0x1a, 0x45, 0xdf, 0xa3}, "video/x-matroska"},
+}
+
+// officeExtensions maps zip-based document formats to their MIME types
+var officeExtensions = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+	".epub": "application/epub+zip",
+}
+
+// sourceExtensions lists extensions of text files that are treated as source code
+var sourceExtensions = map[string]bool{
+	".c": true, ".cc": true, ".cpp": true, ".cs": true, ".css": true, ".go": true,
+	".h": true, ".hpp": true, ".html": true, ".java": true, ".js": true, ".json": true,
+	".kt": true, ".lua": true, ".m": true, ".php": true, ".pl": true, ".py": true,
+	".rb": true, ".rs": true, ".scala": true, ".sh": true, ".sql": true, ".swift": true,
+	".toml": true, ".ts": true, ".tsx": true, ".xml": true, ".yaml": true, ".yml": true,
+}
+
+// archiveTypes lists MIME types of archives and compressed files
+var archiveTypes = map[string]bool{
+	"application/zip":              true,
+	"application/x-gzip":           true,
+	"application/gzip":             true,
+	"application/x-bzip2":          true,
+	"application/x-xz":             true,
+	"application/zstd":             true,
+	"application/x-7z-compressed":  true,
+	"application/x-rar-compressed": true,
+	"application/x-tar":            true,
+}
+
+// documentTypes lists non-text MIME types that are documents
+var documentTypes = map[string]bool{
+	"application/pdf":        true,
+	"application/postscript": true,
+	"application/rtf":        true,
+	"text/rtf":               true,
+}
+
+// Detect reads the first bytes of the file at path and classifies its content
+func Detect(path string) (Info, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Info{}, err
+	}
+
+	return DetectBytes(path, buf[:n]), nil
+}
+
+// DetectBytes classifies content from its leading bytes, using the file
+// name only to refine ambiguous results such as zip-based documents or
+// plain text source files
+func DetectBytes(name string, data []byte) Info {
+	if len(data) == 0 {
+		return Info{MIMEType: "inode/x-empty", Category: CategoryEmpty}
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	mimeType := sniff(data)
+
+	if mimeType == "application/zip" {
+		if officeType, ok := officeExtensions[ext]; ok {
+			mimeType = officeType
+		}
+	}
+
+	return Info{MIMEType: mimeType, Category: categorize(mimeType, ext, data)}
+}
+
+// sniff returns the MIME type of data, preferring the magic table over net/http
+func sniff(data []byte) string {
+	if isPE(data) {
+		return "application/vnd.microsoft.portable-executable"
+	}
+	for _, m := range magicTable {
+		end := m.offset + len(m.sig)
+		if len(data) >= end && bytes.Equal(data[m.offset:end], m.sig) {
+			return m.mimeType
+		}
+	}
+
+	// Strip parameters such as "; charset=utf-8"
+	mimeType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	return strings.TrimSpace(mimeType)
+}
+
+// isPE reports whether data starts with the DOS header of a Windows
+// executable, "MZ" followed at the offset stored at 0x3C by the PE signature.
+// If the signature lies beyond the data, the data must not be text, as text
+// may start with "MZ" too.
+func isPE(data []byte) bool {
+	if len(data) < 0x40 || !bytes.HasPrefix(data, []byte("MZ")) {
+		return false
+	}
+	offset := int64(binary.LittleEndian.Uint32(data[0x3c:]))
+	if offset+4 <= int64(len(data)) {
+		return bytes.Equal(data[offset:offset+4], []byte("PE\x00\x00"))
+	}
+	return !strings.HasPrefix(http.DetectContentType(data), "text/")
+}
+
+// categorize maps a MIME type to a broad content category
+func categorize(mimeType, ext string, data []byte) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return CategoryImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return CategoryVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return CategoryAudio
+	case archiveTypes[mimeType]:
+		return CategoryArchive
+	case documentTypes[mimeType], strings.HasPrefix(mimeType, "application/vnd.openxmlformats"),
+		strings.HasPrefix(mimeType, "application/vnd.oasis"), mimeType == "application/epub+zip":
+		return CategoryDocument
+	case strings.HasPrefix(mimeType, "text/"):
+		if sourceExtensions[ext] || bytes.HasPrefix(data, []byte("#!")) {
+			return CategorySource
+		}
+		return CategoryDocument
+	default:
+		return CategoryBinary
+	}
+}
@@ -0,0 +1,67 @@
+package filetype
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectBytes(t *testing.T) {
+	tarHeader := make([]byte, 512)
+	copy(tarHeader[257:], "ustar")
+
+	// A DOS header pointing to the PE signature at 0x80
+	exe := make([]byte, 0x100)
+	copy(exe, "MZ")
+	exe[0x3c] = 0x80
+	copy(exe[0x80:], "PE\x00\x00")
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantMIME     string
+		wantCategory string
+	}{
+		{"photo", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), "image/jpeg", CategoryImage},
+		{"image.dat", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png", CategoryImage},
+		{"program", []byte("\x7fELF\x02\x01\x01\x00"), "application/x-executable", CategoryBinary},
+		{"setup.exe", exe, "application/vnd.microsoft.portable-executable", CategoryBinary},
+		{"mz.txt", []byte("MZ notes about the release, long enough to hold a DOS header offset.\n"), "text/plain", CategoryDocument},
+		{"backup", tarHeader, "application/x-tar", CategoryArchive},
+		{"data.gz", []byte("\x1f\x8b\x08\x00\x00\x00"), "application/x-gzip", CategoryArchive},
+		{"report.docx", []byte("PK\x03\x04\x14\x00\x06\x00"), officeExtensions[".docx"], CategoryDocument},
+		{"bundle.zip", []byte("PK\x03\x04\x14\x00\x06\x00"), "application/zip", CategoryArchive},
+		{"paper", []byte("%PDF-1.7\n"), "application/pdf", CategoryDocument},
+		{"main.go", []byte("package main\n"), "text/plain", CategorySource},
+		{"build", []byte("#!/bin/sh\necho hi\n"), "text/plain", CategorySource},
+		{"notes", []byte("just some notes\n"), "text/plain", CategoryDocument},
+		{"empty.txt", nil, "inode/x-empty", CategoryEmpty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := DetectBytes(tt.name, tt.data)
+			if info.MIMEType != tt.wantMIME {
+				t.Errorf("DetectBytes() MIME = %s, want %s", info.MIMEType, tt.wantMIME)
+			}
+			if info.Category != tt.wantCategory {
+				t.Errorf("DetectBytes() category = %s, want %s", info.Category, tt.wantCategory)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "noext")
+	if err := os.WriteFile(path, []byte("GIF89a\x01\x00\x01\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Detect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MIMEType != "image/gif" || info.Category != CategoryImage {
+		t.Errorf("Detect() = %+v, want image/gif image", info)
+	}
+}
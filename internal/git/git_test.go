@@ -0,0 +1,135 @@
+package git
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"testing"
+)
+
+// buildIndex encodes paths as a git index of the given version
+func buildIndex(t *testing.T, version uint32, paths []string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	buf.WriteString("DIRC")
+	binary.Write(&buf, binary.BigEndian, version)
+	binary.Write(&buf, binary.BigEndian, uint32(len(paths)))
+
+	previous := ""
+	for _, name := range paths {
+		entry := make([]byte, indexEntrySize)
+		binary.BigEndian.PutUint32(entry[24:28], 0100644)
+		nameLen := len(name)
+		if nameLen > flagNameMask {
+			nameLen = flagNameMask
+		}
+		binary.BigEndian.PutUint16(entry[60:62], uint16(nameLen))
+		buf.Write(entry)
+
+		if version == 4 {
+			common := 0
+			for common < len(previous) && common < len(name) && previous[common] == name[common] {
+				common++
+			}
+			strip := len(previous) - common
+			if strip >= 0x80 {
+				t.Fatal("test helper only encodes single byte offsets")
+			}
+			buf.WriteByte(byte(strip))
+			buf.WriteString(name[common:])
+			buf.WriteByte(0)
+		} else {
+			buf.WriteString(name)
+			buf.Write(make([]byte, 8-(indexEntrySize+len(name))%8))
+		}
+		previous = name
+	}
+
+	buf.WriteString("TREE") // Extensions and the checksum are not read
+	return buf.Bytes()
+}
+
+func TestReadIndex(t *testing.T) {
+	long := string(bytes.Repeat([]byte("d/"), 2100)) + "file.txt"
+	paths := []string{"README.md", "cmd/root.go", "cmd/walk.go", "internal/output/text.go", long}
+
+	for _, version := range []uint32{2, 3, 4} {
+		index, err := ReadIndex(bytes.NewReader(buildIndex(t, version, paths)))
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if index.Len() != len(paths) {
+			t.Errorf("version %d: %d entries, want %d", version, index.Len(), len(paths))
+		}
+		for _, name := range paths {
+			if !index.Contains(name) {
+				t.Errorf("version %d: %.40s not tracked", version, name)
+			}
+		}
+		if index.Contains("cmd") || index.Contains("missing.go") {
+			t.Errorf("version %d: untracked path reported as tracked", version)
+		}
+	}
+
+	if _, err := ReadIndex(bytes.NewReader([]byte("PACK\x00\x00\x00\x02\x00\x00\x00\x00"))); err == nil {
+		t.Error("ReadIndex() accepted a file without the index signature")
+	}
+
+	// A corrupt entry count fails on the missing entries, without allocating
+	// for all of them first
+	corrupt := buildIndex(t, 2, paths)
+	binary.BigEndian.PutUint32(corrupt[8:12], 0xffffffff)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := ReadIndex(bytes.NewReader(corrupt)); err == nil {
+		t.Error("ReadIndex() accepted an index with a corrupt entry count")
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("ReadIndex() allocated %d bytes for a corrupt entry count", allocated)
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	patterns := ParseIgnore([]byte(`
+# build output
+*.o
+/bin
+build/
+!keep.o
+docs/**/*.pdf
+trailing\ 
+`), "")
+	patterns = append(patterns, ParseIgnore([]byte("*.tmp\n!important.tmp\n"), "sub")...)
+
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{"main.o", false, true},
+		{"deep/nested/main.o", false, true},
+		{"keep.o", false, false},
+		{"bin", true, true},
+		{"src/bin", true, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"docs/manual.pdf", false, true},
+		{"docs/a/b/manual.pdf", false, true},
+		{"manual.pdf", false, false},
+		{"trailing ", false, true},
+		{"sub/scratch.tmp", false, true},
+		{"sub/important.tmp", false, false},
+		{"scratch.tmp", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Ignored(patterns, tt.name, tt.isDir); got != tt.want {
+				t.Errorf("Ignored(%q, %v) = %v, want %v", tt.name, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
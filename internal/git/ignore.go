@@ -0,0 +1,119 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"strings"
+
+	"amurru/filetools/internal/pathmatch"
+)
+
+// IgnorePattern is a single rule from a .gitignore or info/exclude file
+type IgnorePattern struct {
+	pattern  string
+	base     string // Directory of the ignore file relative to the worktree, "" for the root
+	negate   bool
+	dirOnly  bool
+	anchored bool // Matched against the path relative to base rather than the name
+}
+
+// LoadIgnore reads the patterns of an ignore file located in the worktree
+// directory base. A missing file yields no patterns.
+func LoadIgnore(filename, base string) ([]IgnorePattern, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseIgnore(data, base), nil
+}
+
+// ParseIgnore parses gitignore patterns that apply below the slash-separated
+// worktree directory base
+func ParseIgnore(data []byte, base string) []IgnorePattern {
+	if base == "." {
+		base = ""
+	}
+
+	var patterns []IgnorePattern
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := trimTrailingSpace(strings.TrimSuffix(scanner.Text(), "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := IgnorePattern{base: base}
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A slash anywhere but at the end anchors the pattern to the ignore file's directory
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if pathmatch.Validate(line) != nil {
+			continue // Git silently ignores malformed patterns too
+		}
+		p.pattern = line
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// trimTrailingSpace removes unescaped trailing spaces
+func trimTrailingSpace(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// Match reports whether the pattern matches a slash-separated path relative to the worktree
+func (p IgnorePattern) Match(name string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	rel := name
+	if p.base != "" {
+		if !strings.HasPrefix(name, p.base+"/") {
+			return false
+		}
+		rel = name[len(p.base)+1:]
+	}
+
+	if !p.anchored {
+		matched, _ := path.Match(p.pattern, path.Base(rel))
+		return matched
+	}
+	matched, _ := pathmatch.Match(p.pattern, rel)
+	return matched
+}
+
+// Ignored reports whether a path is ignored by a list of patterns ordered
+// from lowest to highest precedence, where the last matching pattern wins.
+// It does not consider whether a parent directory is ignored.
+func Ignored(patterns []IgnorePattern, name string, isDir bool) bool {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if patterns[i].Match(name, isDir) {
+			return !patterns[i].negate
+		}
+	}
+	return false
+}
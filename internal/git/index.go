@@ -0,0 +1,195 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Index is the set of paths tracked in a git index file. The zero Index tracks nothing.
+type Index struct {
+	paths      map[string]bool
+	sparseDirs []string // Directories collapsed into a single entry by a sparse index, with a trailing slash
+}
+
+// Contains reports whether a slash-separated path relative to the worktree is tracked
+func (ix *Index) Contains(path string) bool {
+	if ix.paths[path] {
+		return true
+	}
+	for _, dir := range ix.sparseDirs {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of index entries
+func (ix *Index) Len() int {
+	return len(ix.paths) + len(ix.sparseDirs)
+}
+
+// indexEntrySize is the size of the fixed part of an index entry: ctime,
+// mtime, dev, ino, mode, uid, gid, size, object name and flags
+const indexEntrySize = 62
+
+// Index entry flags
+const (
+	flagExtended  = 0x4000
+	flagNameMask  = 0x0fff
+	modeDirectory = 0040000
+)
+
+// LoadIndex reads a git index file
+func LoadIndex(filename string) (*Index, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	index, err := ReadIndex(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return index, nil
+}
+
+// ReadIndex parses the entries of a git index in format version 2, 3 or 4.
+// Extensions following the entries are not needed and left unread.
+func ReadIndex(r io.Reader) (*Index, error) {
+	var header struct {
+		Signature [4]byte
+		Version   uint32
+		Entries   uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("reading index header: %w", err)
+	}
+	if string(header.Signature[:]) != "DIRC" {
+		return nil, errors.New("not a git index file")
+	}
+	if header.Version < 2 || header.Version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", header.Version)
+	}
+
+	// The entry count of a corrupt header must not size the map
+	index := &Index{paths: make(map[string]bool, min(header.Entries, 1<<16))}
+	entry := make([]byte, indexEntrySize)
+	var previous []byte
+
+	for i := uint32(0); i < header.Entries; i++ {
+		if _, err := io.ReadFull(r, entry); err != nil {
+			return nil, fmt.Errorf("reading index entry %d: %w", i, err)
+		}
+		mode := binary.BigEndian.Uint32(entry[24:28])
+		flags := binary.BigEndian.Uint16(entry[60:62])
+		size := indexEntrySize
+
+		if flags&flagExtended != 0 {
+			if header.Version < 3 {
+				return nil, fmt.Errorf("index entry %d: extended flags in version %d index", i, header.Version)
+			}
+			if _, err := io.ReadFull(r, make([]byte, 2)); err != nil {
+				return nil, fmt.Errorf("reading index entry %d: %w", i, err)
+			}
+			size += 2
+		}
+
+		var name []byte
+		if header.Version == 4 {
+			// The name replaces a suffix of the previous entry's name
+			strip, err := readOffset(r)
+			if err != nil {
+				return nil, fmt.Errorf("reading index entry %d: %w", i, err)
+			}
+			if strip > uint64(len(previous)) {
+				return nil, fmt.Errorf("index entry %d: invalid path compression", i)
+			}
+			suffix, err := readString(r)
+			if err != nil {
+				return nil, fmt.Errorf("reading index entry %d: %w", i, err)
+			}
+			name = append(append([]byte{}, previous[:uint64(len(previous))-strip]...), suffix...)
+		} else {
+			nameLen := int(flags & flagNameMask)
+			if nameLen < flagNameMask {
+				// Known length: the name, then 1 to 8 NUL bytes padding the entry to a multiple of 8
+				name = make([]byte, nameLen)
+				if _, err := io.ReadFull(r, name); err != nil {
+					return nil, fmt.Errorf("reading index entry %d: %w", i, err)
+				}
+				padding := 8 - (size+nameLen)%8
+				if _, err := io.ReadFull(r, make([]byte, padding)); err != nil {
+					return nil, fmt.Errorf("reading index entry %d: %w", i, err)
+				}
+			} else {
+				// Long names are NUL-terminated, padded the same way
+				var err error
+				if name, err = readString(r); err != nil {
+					return nil, fmt.Errorf("reading index entry %d: %w", i, err)
+				}
+				padding := 8 - (size+len(name))%8 - 1
+				if _, err := io.ReadFull(r, make([]byte, padding)); err != nil {
+					return nil, fmt.Errorf("reading index entry %d: %w", i, err)
+				}
+			}
+		}
+		previous = name
+
+		if mode&0170000 == modeDirectory {
+			index.sparseDirs = append(index.sparseDirs, strings.TrimSuffix(string(name), "/")+"/")
+		} else {
+			index.paths[string(name)] = true
+		}
+	}
+
+	return index, nil
+}
+
+// readOffset reads the variable-length integer used by version 4 indexes
+func readOffset(r io.Reader) (uint64, error) {
+	b, err := readByte(r)
+	if err != nil {
+		return 0, err
+	}
+	value := uint64(b & 0x7f)
+	for b&0x80 != 0 {
+		if b, err = readByte(r); err != nil {
+			return 0, err
+		}
+		value = ((value + 1) << 7) | uint64(b&0x7f)
+	}
+	return value, nil
+}
+
+// readString reads a NUL-terminated string, consuming the terminator
+func readString(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		b, err := readByte(r)
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return buf.Bytes(), nil
+		}
+		buf.WriteByte(b)
+	}
+}
+
+// readByte reads a single byte
+func readByte(r io.Reader) (byte, error) {
+	if br, ok := r.(io.ByteReader); ok {
+		return br.ReadByte()
+	}
+	var b [1]byte
+	_, err := io.ReadFull(r, b[:])
+	return b[0], err
+}
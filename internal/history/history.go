@@ -0,0 +1,134 @@
+// Package history stores the summaries of dirstat runs for growth trends.
+//
+// The history is an append-only JSON Lines file with one record per run.
+// Records keep the directory sizes of the first levels of the tree only, so
+// they stay small however large the tree is. Reading a history scans the
+// whole file; records of other directories are skipped without decoding
+// their directory sizes.
+//
+// Each record is written with a single append. On local filesystems,
+// concurrent runs appending to the same file do not interleave, but on
+// network filesystems such as NFS appends are not atomic, and concurrent runs
+// may corrupt each other's records. Runs that may overlap there should use
+// separate history files.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"amurru/filetools/internal/output"
+)
+
+// Record is the summary of one dirstat run. A history file holds one record
+// per line as JSON, appended in the order the runs finished.
+type Record struct {
+	Time        time.Time              `json:"time"`
+	Root        string                 `json:"root"` // Absolute path of the analyzed directory
+	TotalFiles  int                    `json:"total_files"`
+	TotalSize   int64                  `json:"total_size"`
+	TotalInodes int                    `json:"total_inodes"`
+	Filesystem  *output.FilesystemInfo `json:"filesystem,omitempty"`
+	Depth       int                    `json:"depth,omitempty"` // Deepest directories recorded, 0 for all
+	Directories map[string]int64       `json:"directories"`     // Size of the files stored directly in each directory, "." for the root, including deeper directories at Depth
+}
+
+// DefaultPath returns the history file used when none is configured,
+// filetools/dirstat-history.jsonl in the user's configuration directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the history file: %w", err)
+	}
+	return filepath.Join(dir, "filetools", "dirstat-history.jsonl"), nil
+}
+
+// Append adds a record to the history file, creating it and its directory
+// if needed. The record is written with a single append, so concurrent runs
+// do not interleave.
+func Append(path string, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history '%s': %w", path, err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write history '%s': %w", path, err)
+	}
+	return file.Close()
+}
+
+// Load reads the records of root from the history file, oldest first. All
+// records are returned if root is empty.
+func Load(path, root string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	records, err := Read(file, root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return records, nil
+}
+
+// Read decodes the records of root from history data, oldest first. A final
+// line without a newline is skipped if it is incomplete, as left behind by
+// an interrupted run.
+func Read(r io.Reader, root string) ([]Record, error) {
+	var records []Record
+	reader := bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		complete := err == nil
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			// The root is decoded first, so that the directories of other
+			// roots are not
+			var header struct {
+				Root string `json:"root"`
+			}
+			decodeErr := json.Unmarshal(line, &header)
+			var record Record
+			if decodeErr == nil && (root == "" || header.Root == root) {
+				if decodeErr = json.Unmarshal(line, &record); decodeErr == nil {
+					records = append(records, record)
+				}
+			}
+			if decodeErr != nil && complete {
+				return nil, fmt.Errorf("line %d: invalid record: %w", lineNumber, decodeErr)
+			}
+		}
+
+		if !complete {
+			break
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
@@ -0,0 +1,112 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"amurru/filetools/internal/output"
+)
+
+func TestAppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "history.jsonl")
+
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: day.AddDate(0, 0, 1), Root: "/srv", TotalFiles: 2, TotalSize: 200, Directories: map[string]int64{".": 200}},
+		{Time: day, Root: "/srv", TotalFiles: 1, TotalSize: 100, Directories: map[string]int64{".": 100},
+			Filesystem: &output.FilesystemInfo{TotalBytes: 1000, AvailableBytes: 900}},
+		{Time: day, Root: "/home", TotalFiles: 5, TotalSize: 500, Directories: map[string]int64{"a": 500}},
+	}
+	for _, record := range records {
+		if err := Append(path, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Load(path, "/srv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Records are returned oldest first
+	want := []Record{records[1], records[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+
+	all, err := Load(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("got %d records, want 3", len(all))
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.jsonl"), ""); err == nil {
+		t.Error("expected an error for a missing history file")
+	}
+}
+
+func TestReadIncompleteLine(t *testing.T) {
+	data := `{"time":"2024-03-01T00:00:00Z","root":"/srv","total_size":1}` + "\n" +
+		"\n" +
+		`{"time":"2024-03-02T00:00:00Z","root":"/srv","total_size":2}` + "\n" +
+		`{"time":"2024-03-03T00:00:00Z","ro`
+
+	records, err := Read(strings.NewReader(data), "/srv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].TotalSize != 2 {
+		t.Errorf("Read = %+v, want the two complete records", records)
+	}
+}
+
+func TestReadInvalidLine(t *testing.T) {
+	data := `{"time":"2024-03-01T00:00:00Z","root":"/srv"}` + "\n" +
+		"not json\n" +
+		`{"time":"2024-03-02T00:00:00Z","root":"/srv"}` + "\n"
+
+	_, err := Read(strings.NewReader(data), "")
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Read error = %v, want an error for line 2", err)
+	}
+}
+
+func TestReadOtherRoots(t *testing.T) {
+	// The directories of other roots are not decoded
+	data := `{"time":"2024-03-01T00:00:00Z","root":"/srv","directories":{".":1}}` + "\n" +
+		`{"time":"2024-03-01T00:00:00Z","root":"/home","directories":"not a map"}` + "\n"
+
+	records, err := Read(strings.NewReader(data), "/srv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Directories["."] != 1 {
+		t.Errorf("Read = %+v, want the /srv record", records)
+	}
+
+	if _, err := Read(strings.NewReader(data), ""); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Read error = %v, want an error for line 2", err)
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+
+	path, err := DefaultPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "dirstat-history.jsonl" || filepath.Base(filepath.Dir(path)) != "filetools" {
+		t.Errorf("DefaultPath = %s", path)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Error("DefaultPath must not create the directory")
+	}
+}
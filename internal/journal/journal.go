@@ -0,0 +1,115 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rename is a file or directory renamed by a run, with paths relative to the
+// journal root. The size and modification time of files are recorded after
+// the rename, so an undo can tell whether the file changed since.
+type Rename struct {
+	OldPath   string    `json:"old_path"`
+	NewPath   string    `json:"new_path"`
+	Dir       bool      `json:"dir,omitempty"`
+	Overwrote bool      `json:"overwrote,omitempty"` // The rename replaced a file, which is not kept
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+}
+
+// Journal records the successful renames of one run in the order they were
+// applied
+type Journal struct {
+	Time    time.Time `json:"time"`
+	Root    string    `json:"root"` // Absolute path of the renamed directory
+	Renames []Rename  `json:"renames"`
+}
+
+// Journal files are named after the time of the run, so they sort by age
+const (
+	filePrefix = "rename-"
+	fileSuffix = ".json"
+	timeLayout = "20060102-150405.000000000"
+)
+
+// DefaultDir returns the directory journals are written to when none is
+// configured, filetools/rename-journal in the user's configuration directory
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the journal directory: %w", err)
+	}
+	return filepath.Join(dir, "filetools", "rename-journal"), nil
+}
+
+// Write stores a journal in dir, creating the directory if needed, and
+// returns the path of the new journal file
+func Write(dir string, journal *Journal) (string, error) {
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	pattern := filePrefix + journal.Time.UTC().Format(timeLayout) + "-*" + fileSuffix
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create journal: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write journal '%s': %w", file.Name(), err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write journal '%s': %w", file.Name(), err)
+	}
+	return file.Name(), nil
+}
+
+// Load reads a journal file
+func Load(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal '%s': %w", path, err)
+	}
+	var journal Journal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("%s: invalid journal: %w", path, err)
+	}
+	if !filepath.IsAbs(journal.Root) {
+		return nil, fmt.Errorf("%s: invalid journal: root must be an absolute path", path)
+	}
+	return &journal, nil
+}
+
+// Latest returns the path of the most recently written journal in dir
+func Latest(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no rename journal in %s", dir)
+		}
+		return "", fmt.Errorf("failed to list journals: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no rename journal in %s", dir)
+	}
+	sort.Strings(names)
+	return filepath.Join(dir, names[len(names)-1]), nil
+}
@@ -0,0 +1,97 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested")
+
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	journal := &Journal{
+		Time: day,
+		Root: "/photos",
+		Renames: []Rename{
+			{OldPath: "b.jpg", NewPath: "c.jpg", Size: 10, ModTime: day.Add(-time.Hour)},
+			{OldPath: "a.jpg", NewPath: "b.jpg", Size: 20, ModTime: day.Add(-2 * time.Hour)},
+		},
+	}
+	path, err := Write(dir, journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), "rename-20240301-120000") {
+		t.Errorf("Write path = %s", path)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, journal) {
+		t.Errorf("Load = %+v, want %+v", got, journal)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing journal")
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(invalid); err == nil || !strings.Contains(err.Error(), "invalid journal") {
+		t.Errorf("Load error = %v, want an invalid journal error", err)
+	}
+
+	relative := filepath.Join(dir, "relative.json")
+	if err := os.WriteFile(relative, []byte(`{"root":"photos","renames":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(relative); err == nil {
+		t.Error("expected an error for a relative root")
+	}
+}
+
+func TestLatest(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Latest(dir); err == nil {
+		t.Error("expected an error without journals")
+	}
+	if _, err := Latest(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var newest string
+	for _, at := range []time.Time{day.Add(time.Millisecond), day.Add(2 * time.Millisecond), day} {
+		path, err := Write(dir, &Journal{Time: at, Root: "/photos"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if at.Equal(day.Add(2 * time.Millisecond)) {
+			newest = path
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "rename-notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Latest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != newest {
+		t.Errorf("Latest = %s, want %s", got, newest)
+	}
+}
@@ -0,0 +1,213 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// TIFF tags read from the image and EXIF directories
+const (
+	tagModel            = 0x0110
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitude     = 0x0004
+)
+
+// TIFF field types used by the tags above
+const (
+	typeASCII = 2
+	typeShort = 3
+	typeLong  = 4
+)
+
+// exifTimeLayout is the format of EXIF dates, in the camera's local time
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// maxIFDEntries bounds the entries read from one directory of a corrupt file
+const maxIFDEntries = 1000
+
+// readJPEG finds the EXIF segment of a JPEG file and reads it. Only the
+// segments before the image data are scanned.
+func readJPEG(r io.Reader) (*Metadata, error) {
+	reader := bufio.NewReader(r)
+	if _, err := reader.Discard(2); err != nil {
+		return nil, err
+	}
+
+	for {
+		// Markers may be preceded by any number of fill bytes
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, ErrNoMetadata
+		}
+		if b != 0xff {
+			return nil, errCorrupt("expected a JPEG marker")
+		}
+		marker := byte(0xff)
+		for marker == 0xff {
+			if marker, err = reader.ReadByte(); err != nil {
+				return nil, ErrNoMetadata
+			}
+		}
+
+		switch {
+		case marker == 0xd8 || marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			continue // Markers without a segment
+		case marker == 0xda || marker == 0xd9:
+			return nil, ErrNoMetadata // Image data or end of image
+		}
+
+		var length uint16
+		if err := binary.Read(reader, binary.BigEndian, &length); err != nil || length < 2 {
+			return nil, errCorrupt("truncated JPEG segment")
+		}
+		if marker != 0xe1 {
+			if _, err := reader.Discard(int(length) - 2); err != nil {
+				return nil, ErrNoMetadata
+			}
+			continue
+		}
+
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(reader, segment); err != nil {
+			return nil, errCorrupt("truncated JPEG segment")
+		}
+		if tiff, ok := bytes.CutPrefix(segment, []byte("Exif\x00\x00")); ok {
+			return readTIFF(bytes.NewReader(tiff), int64(len(tiff)))
+		}
+		// Other APP1 segments, such as XMP, are skipped
+	}
+}
+
+// tiffReader reads the directories of TIFF data, the container of EXIF
+type tiffReader struct {
+	r     io.ReaderAt
+	size  int64
+	order binary.ByteOrder
+}
+
+// ifdEntry is a field of a TIFF image file directory
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte // The 4 byte value or offset field
+}
+
+// readTIFF reads the metadata of TIFF data, a TIFF file or the EXIF segment
+// of a JPEG file
+func readTIFF(r io.ReaderAt, size int64) (*Metadata, error) {
+	t := &tiffReader{r: r, size: size}
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, errCorrupt("truncated TIFF header")
+	}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, errCorrupt("invalid TIFF byte order")
+	}
+	if t.order.Uint16(header[2:]) != 42 {
+		return nil, errCorrupt("invalid TIFF header")
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(header[4:]))
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{}
+	meta.Model = t.ascii(ifd0[tagModel])
+
+	// DateTimeOriginal is the capture time; DateTime is the time the file
+	// was last changed and is only a fallback
+	taken := t.ascii(ifd0[tagDateTime])
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		if exif, err := t.readIFD(t.long(entry)); err == nil {
+			if original := t.ascii(exif[tagDateTimeOriginal]); original != "" {
+				taken = original
+			}
+		}
+	}
+	if at, err := time.ParseInLocation(exifTimeLayout, taken, time.Local); err == nil {
+		meta.Taken = at
+	}
+
+	if entry, ok := ifd0[tagGPSIFD]; ok {
+		if gps, err := t.readIFD(t.long(entry)); err == nil {
+			_, hasLatitude := gps[tagGPSLatitude]
+			_, hasLongitude := gps[tagGPSLongitude]
+			meta.GPS = hasLatitude && hasLongitude
+		}
+	}
+
+	if meta.Taken.IsZero() && meta.Model == "" && !meta.GPS {
+		return nil, ErrNoMetadata
+	}
+	return meta, nil
+}
+
+// readIFD reads the entries of the directory at offset
+func (t *tiffReader) readIFD(offset uint32) (map[uint16]ifdEntry, error) {
+	countBytes := make([]byte, 2)
+	if _, err := t.r.ReadAt(countBytes, int64(offset)); err != nil {
+		return nil, errCorrupt("directory outside of the file")
+	}
+	count := int(t.order.Uint16(countBytes))
+	if count > maxIFDEntries {
+		return nil, errCorrupt("directory with %d entries", count)
+	}
+
+	data := make([]byte, 12*count)
+	if _, err := t.r.ReadAt(data, int64(offset)+2); err != nil {
+		return nil, errCorrupt("truncated directory")
+	}
+	entries := make(map[uint16]ifdEntry, count)
+	for i := 0; i < count; i++ {
+		field := data[12*i : 12*i+12]
+		entries[t.order.Uint16(field)] = ifdEntry{
+			typ:   t.order.Uint16(field[2:]),
+			count: t.order.Uint32(field[4:]),
+			value: field[8:12],
+		}
+	}
+	return entries, nil
+}
+
+// ascii returns the text of an ASCII field, empty if it is missing or invalid
+func (t *tiffReader) ascii(entry ifdEntry) string {
+	if entry.typ != typeASCII || entry.count == 0 || entry.count > 1024 {
+		return ""
+	}
+
+	data := entry.value[:min(int(entry.count), 4)]
+	if entry.count > 4 {
+		offset := int64(t.order.Uint32(entry.value))
+		if offset+int64(entry.count) > t.size {
+			return ""
+		}
+		data = make([]byte, entry.count)
+		if _, err := t.r.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+			return ""
+		}
+	}
+	return strings.TrimSpace(strings.TrimRight(string(data), "\x00"))
+}
+
+// long returns the value of a LONG or SHORT field, such as a directory offset
+func (t *tiffReader) long(entry ifdEntry) uint32 {
+	if entry.typ == typeShort {
+		return uint32(t.order.Uint16(entry.value))
+	}
+	return t.order.Uint32(entry.value)
+}
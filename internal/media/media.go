@@ -0,0 +1,64 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Metadata is what a photo or video records about its capture
+type Metadata struct {
+	Taken time.Time // EXIF DateTimeOriginal or video creation time, zero if not recorded
+	Model string    // Camera model, empty if not recorded
+	GPS   bool      // The file records a GPS position
+}
+
+var (
+	// ErrUnsupported is returned for files that are not JPEG, TIFF, MP4 or MOV
+	ErrUnsupported = errors.New("unsupported file format")
+	// ErrNoMetadata is returned for supported files without metadata
+	ErrNoMetadata = errors.New("no metadata found")
+)
+
+// Read detects the format of a file from its content and reads its metadata
+func Read(path string) (*Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ReadFrom(file, info.Size())
+}
+
+// ReadFrom reads the metadata of a file of the given size
+func ReadFrom(r io.ReaderAt, size int64) (*Metadata, error) {
+	head := make([]byte, 12)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0xff, 0xd8}):
+		return readJPEG(io.NewSectionReader(r, 0, size))
+	case bytes.HasPrefix(head, []byte("II*\x00")) || bytes.HasPrefix(head, []byte("MM\x00*")):
+		return readTIFF(r, size)
+	case len(head) >= 8 && isMP4Box(string(head[4:8])):
+		return readMP4(r, size)
+	}
+	return nil, ErrUnsupported
+}
+
+// errCorrupt reports metadata that cannot be parsed
+func errCorrupt(format string, args ...any) error {
+	return fmt.Errorf("corrupt metadata: "+format, args...)
+}
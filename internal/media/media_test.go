@@ -0,0 +1,240 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// tiffField is a field of a test TIFF directory: ASCII text or a sub-directory
+type tiffField struct {
+	tag   uint16
+	ascii string
+	sub   []tiffField
+}
+
+// buildTIFF lays out TIFF data with the fields in its first directory
+func buildTIFF(order binary.ByteOrder, fields []tiffField) []byte {
+	buf := make([]byte, 8)
+	if order == binary.LittleEndian {
+		copy(buf, "II")
+	} else {
+		copy(buf, "MM")
+	}
+	order.PutUint16(buf[2:], 42)
+
+	var writeIFD func(fields []tiffField) uint32
+	writeIFD = func(fields []tiffField) uint32 {
+		offset := len(buf)
+		buf = append(buf, make([]byte, 2+12*len(fields)+4)...)
+		order.PutUint16(buf[offset:], uint16(len(fields)))
+		for i, field := range fields {
+			entry := offset + 2 + 12*i
+			order.PutUint16(buf[entry:], field.tag)
+			if field.sub != nil {
+				sub := writeIFD(field.sub)
+				order.PutUint16(buf[entry+2:], typeLong)
+				order.PutUint32(buf[entry+4:], 1)
+				order.PutUint32(buf[entry+8:], sub)
+				continue
+			}
+
+			data := []byte(field.ascii + "\x00")
+			order.PutUint16(buf[entry+2:], typeASCII)
+			order.PutUint32(buf[entry+4:], uint32(len(data)))
+			if len(data) <= 4 {
+				copy(buf[entry+8:], data)
+			} else {
+				order.PutUint32(buf[entry+8:], uint32(len(buf)))
+				buf = append(buf, data...)
+			}
+		}
+		return uint32(offset)
+	}
+	ifd0 := writeIFD(fields)
+	order.PutUint32(buf[4:], ifd0)
+	return buf
+}
+
+// buildJPEG wraps TIFF data in the EXIF segment of a JPEG file
+func buildJPEG(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xff, 0xd8})
+	buf.Write([]byte{0xff, 0xe0, 0x00, 0x07})
+	buf.WriteString("JFIF\x00")
+
+	exif := append([]byte("Exif\x00\x00"), tiff...)
+	buf.Write([]byte{0xff, 0xe1})
+	binary.Write(&buf, binary.BigEndian, uint16(len(exif)+2))
+	buf.Write(exif)
+
+	buf.Write([]byte{0xff, 0xda, 0x00, 0x02, 0x12, 0x34, 0xff, 0xd9})
+	return buf.Bytes()
+}
+
+// box encodes an MP4 box
+func box(typ string, content ...[]byte) []byte {
+	data := bytes.Join(content, nil)
+	buf := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	return append(append(buf, typ...), data...)
+}
+
+// buildMP4 lays out an MP4 file with a movie header of the given version
+func buildMP4(version byte, seconds uint64) []byte {
+	mvhd := []byte{version, 0, 0, 0}
+	if version == 1 {
+		mvhd = binary.BigEndian.AppendUint64(mvhd, seconds)
+		mvhd = binary.BigEndian.AppendUint64(mvhd, seconds)
+	} else {
+		mvhd = binary.BigEndian.AppendUint32(mvhd, uint32(seconds))
+		mvhd = binary.BigEndian.AppendUint32(mvhd, uint32(seconds))
+	}
+	mvhd = append(mvhd, make([]byte, 80)...)
+
+	return bytes.Join([][]byte{
+		box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2")),
+		box("mdat", make([]byte, 64)),
+		box("moov", box("mvhd", mvhd), box("trak")),
+	}, nil)
+}
+
+func readBytes(t *testing.T, data []byte) (*Metadata, error) {
+	t.Helper()
+	return ReadFrom(bytes.NewReader(data), int64(len(data)))
+}
+
+func TestReadEXIF(t *testing.T) {
+	taken := time.Date(2023, 8, 5, 14, 3, 9, 0, time.Local)
+	fields := []tiffField{
+		{tag: tagModel, ascii: "Pixel 7"},
+		{tag: tagDateTime, ascii: "2024:01:01 00:00:00"},
+		{tag: tagExifIFD, sub: []tiffField{{tag: tagDateTimeOriginal, ascii: "2023:08:05 14:03:09"}}},
+		{tag: tagGPSIFD, sub: []tiffField{{tag: tagGPSLatitude, ascii: "N"}, {tag: tagGPSLongitude, ascii: "E"}}},
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		tiff := buildTIFF(order, fields)
+		for name, data := range map[string][]byte{"tiff": tiff, "jpeg": buildJPEG(tiff)} {
+			t.Run(order.String()+"/"+name, func(t *testing.T) {
+				meta, err := readBytes(t, data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !meta.Taken.Equal(taken) {
+					t.Errorf("Taken = %v, want %v", meta.Taken, taken)
+				}
+				if meta.Model != "Pixel 7" {
+					t.Errorf("Model = %q, want Pixel 7", meta.Model)
+				}
+				if !meta.GPS {
+					t.Error("GPS position not detected")
+				}
+			})
+		}
+	}
+}
+
+func TestReadEXIFPartial(t *testing.T) {
+	// Without DateTimeOriginal the modification date is used
+	meta, err := readBytes(t, buildJPEG(buildTIFF(binary.LittleEndian, []tiffField{
+		{tag: tagModel, ascii: "X"},
+		{tag: tagDateTime, ascii: "2024:01:02 03:04:05"},
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local); !meta.Taken.Equal(want) {
+		t.Errorf("Taken = %v, want %v", meta.Taken, want)
+	}
+	if meta.Model != "X" || meta.GPS {
+		t.Errorf("meta = %+v", meta)
+	}
+
+	// A GPS directory without coordinates does not count
+	meta, err = readBytes(t, buildTIFF(binary.BigEndian, []tiffField{
+		{tag: tagModel, ascii: "Camera"},
+		{tag: tagGPSIFD, sub: []tiffField{{tag: 0x0000, ascii: "2"}}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Taken.IsZero() || meta.GPS {
+		t.Errorf("meta = %+v", meta)
+	}
+}
+
+func TestReadMP4(t *testing.T) {
+	// 2021-06-01 12:00:00 UTC
+	seconds := uint64(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC).Sub(mp4Epoch) / time.Second)
+	want := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, version := range []byte{0, 1} {
+		meta, err := readBytes(t, buildMP4(version, seconds))
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if !meta.Taken.Equal(want) {
+			t.Errorf("version %d: Taken = %v, want %v", version, meta.Taken, want)
+		}
+	}
+
+	if _, err := readBytes(t, buildMP4(0, 0)); !errors.Is(err, ErrNoMetadata) {
+		t.Errorf("zero creation time: error = %v, want ErrNoMetadata", err)
+	}
+	if _, err := readBytes(t, buildMP4(1, math.MaxUint64)); !errors.Is(err, ErrNoMetadata) {
+		t.Errorf("out of range creation time: error = %v, want ErrNoMetadata", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := map[string]struct {
+		data []byte
+		want error
+	}{
+		"text":          {[]byte("hello world"), ErrUnsupported},
+		"empty":         {nil, ErrUnsupported},
+		"jpeg no exif":  {[]byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x04, 0, 0, 0xff, 0xda, 0, 2}, ErrNoMetadata},
+		"tiff no tags":  {buildTIFF(binary.LittleEndian, nil), ErrNoMetadata},
+		"mp4 no moov":   {box("ftyp", []byte("isom")), ErrNoMetadata},
+		"truncated mp4": {append(box("ftyp", []byte("isom")), 0, 0, 1, 0, 'm', 'o', 'o', 'v'), ErrNoMetadata},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := readBytes(t, tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Corrupt offsets are reported, not followed
+	corrupt := buildTIFF(binary.LittleEndian, nil)
+	binary.LittleEndian.PutUint32(corrupt[4:], 1<<20)
+	if _, err := readBytes(t, corrupt); err == nil {
+		t.Error("expected an error for a directory outside of the file")
+	}
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	data := buildJPEG(buildTIFF(binary.LittleEndian, []tiffField{{tag: tagModel, ascii: "Pixel 7"}}))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Model != "Pixel 7" {
+		t.Errorf("Model = %q, want Pixel 7", meta.Model)
+	}
+	if _, err := Read(filepath.Join(t.TempDir(), "missing.jpg")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
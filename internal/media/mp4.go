@@ -0,0 +1,89 @@
+package media
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// mp4Epoch is the origin of MP4 and QuickTime timestamps
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// isMP4Box reports whether a box type can start an MP4 or QuickTime file
+func isMP4Box(typ string) bool {
+	switch typ {
+	case "ftyp", "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+// readMP4 reads the creation time from the movie header of an MP4 or MOV
+// file. The media data is skipped, not read.
+func readMP4(r io.ReaderAt, size int64) (*Metadata, error) {
+	moovStart, moovEnd, ok := findBox(r, 0, size, "moov")
+	if !ok {
+		return nil, ErrNoMetadata
+	}
+	mvhdStart, mvhdEnd, ok := findBox(r, moovStart, moovEnd, "mvhd")
+	if !ok {
+		return nil, ErrNoMetadata
+	}
+
+	header := make([]byte, 12)
+	if mvhdEnd-mvhdStart < int64(len(header)) {
+		return nil, errCorrupt("truncated movie header")
+	}
+	if _, err := r.ReadAt(header, mvhdStart); err != nil {
+		return nil, errCorrupt("truncated movie header")
+	}
+
+	// Version 1 headers have 64 bit times, after the version and flags
+	var seconds uint64
+	if header[0] == 1 {
+		seconds = binary.BigEndian.Uint64(header[4:])
+	} else {
+		seconds = uint64(binary.BigEndian.Uint32(header[4:]))
+	}
+
+	// Many encoders leave the time at zero when the clock is not set. Times
+	// beyond the range of a time.Duration, about 292 years after 1904, come
+	// from corrupt headers.
+	if seconds == 0 || seconds > math.MaxInt64/uint64(time.Second) {
+		return nil, ErrNoMetadata
+	}
+	return &Metadata{Taken: mp4Epoch.Add(time.Duration(seconds) * time.Second).Local()}, nil
+}
+
+// findBox returns the content range of the first box of a type between
+// start and end
+func findBox(r io.ReaderAt, start, end int64, typ string) (int64, int64, bool) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return 0, 0, false
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = end - offset // The box extends to the end
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return 0, 0, false
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+		if size < headerSize || offset+size > end {
+			return 0, 0, false
+		}
+
+		if string(header[4:8]) == typ {
+			return offset + headerSize, offset + size, true
+		}
+		offset += size
+	}
+	return 0, 0, false
+}
@@ -44,12 +44,99 @@ type FileInfo struct {
 
 // FileType represents statistics for files of a specific type/extension
 type FileType struct {
-	Extension  string  `json:"extension" xml:"extension"`
+	Extension    string          `json:"extension" xml:"extension"`
+	Count        int             `json:"count" xml:"count"`
+	TotalSize    int64           `json:"total_size" xml:"totalSize"`
+	Percentage   float64         `json:"percentage" xml:"percentage"`
+	LargestFiles []FileInfo      `json:"largest_files,omitempty" xml:"largestFile,omitempty"` // Largest files of this type, see dirstat --type-top
+	Directories  []DirectoryInfo `json:"directories,omitempty" xml:"directory,omitempty"`     // Directories directly holding most of this type, percentages relative to its size
+}
+
+// ContentType represents statistics for files sharing a detected MIME type
+type ContentType struct {
+	MIMEType   string  `json:"mime_type" xml:"mimeType"`
+	Category   string  `json:"category" xml:"category"`
 	Count      int     `json:"count" xml:"count"`
 	TotalSize  int64   `json:"total_size" xml:"totalSize"`
 	Percentage float64 `json:"percentage" xml:"percentage"`
 }
 
+// ContentCategory represents statistics for a broad content category (image, video, archive, ...)
+type ContentCategory struct {
+	Category   string  `json:"category" xml:"category"`
+	Count      int     `json:"count" xml:"count"`
+	TotalSize  int64   `json:"total_size" xml:"totalSize"`
+	Percentage float64 `json:"percentage" xml:"percentage"`
+}
+
+// CategoryInfo represents statistics for a user-defined category
+type CategoryInfo struct {
+	Name       string  `json:"name" xml:"name"`
+	Count      int     `json:"count" xml:"count"`
+	TotalSize  int64   `json:"total_size" xml:"totalSize"`
+	Percentage float64 `json:"percentage" xml:"percentage"`
+}
+
+// CompressionFileType represents the estimated compressibility of a file type
+type CompressionFileType struct {
+	Extension         string  `json:"extension" xml:"extension"`
+	Count             int     `json:"count" xml:"count"`
+	TotalSize         int64   `json:"total_size" xml:"totalSize"`
+	CompressedSize    int64   `json:"compressed_size" xml:"compressedSize"`
+	CompressionRatio  float64 `json:"compression_ratio" xml:"compressionRatio"`
+	SavingsPercentage float64 `json:"savings_percentage" xml:"savingsPercentage"`
+}
+
+// CompressionEstimate represents the projected effect of filesystem compression
+type CompressionEstimate struct {
+	SampleSize        int64                 `json:"sample_size" xml:"sampleSize"` // Bytes compressed per file
+	TotalSize         int64                 `json:"total_size" xml:"totalSize"`
+	CompressedSize    int64                 `json:"compressed_size" xml:"compressedSize"`
+	CompressionRatio  float64               `json:"compression_ratio" xml:"compressionRatio"`
+	SavingsPercentage float64               `json:"savings_percentage" xml:"savingsPercentage"`
+	FileTypes         []CompressionFileType `json:"file_types" xml:"fileTypes>fileType"`
+}
+
+// GitCounts represents the files of git worktrees by their git status
+type GitCounts struct {
+	TrackedFiles   int   `json:"tracked_files" xml:"trackedFiles"`
+	TrackedSize    int64 `json:"tracked_size" xml:"trackedSize"`
+	UntrackedFiles int   `json:"untracked_files" xml:"untrackedFiles"`
+	UntrackedSize  int64 `json:"untracked_size" xml:"untrackedSize"`
+	IgnoredFiles   int   `json:"ignored_files" xml:"ignoredFiles"`
+	IgnoredSize    int64 `json:"ignored_size" xml:"ignoredSize"`
+	GitDirFiles    int   `json:"git_dir_files" xml:"gitDirFiles"` // Files inside .git directories
+	GitDirSize     int64 `json:"git_dir_size" xml:"gitDirSize"`
+}
+
+// GitRepository represents the git statistics of a single worktree
+type GitRepository struct {
+	Path string `json:"path" xml:"path"`
+	GitCounts
+}
+
+// GitStats represents the git statistics of all worktrees in the analyzed tree
+type GitStats struct {
+	GitCounts
+	Repositories []GitRepository `json:"repositories" xml:"repositories>repository"`
+}
+
+// RuleViolation represents a threshold rule broken by the analyzed tree
+type RuleViolation struct {
+	Rule       string   `json:"rule" xml:"rule"`
+	Value      int64    `json:"value" xml:"value"`                              // Measured value, or the number of matching files
+	Limit      int64    `json:"limit" xml:"limit"`                              // Threshold of comparison rules
+	Unit       string   `json:"unit" xml:"unit"`                                // "bytes" or "count"
+	Matches    []string `json:"matches,omitempty" xml:"matches>path,omitempty"` // First matching files of presence rules
+	MatchCount int      `json:"match_count,omitempty" xml:"matchCount,omitempty"`
+}
+
+// RuleReport represents the outcome of checking threshold rules
+type RuleReport struct {
+	Checked    int             `json:"checked" xml:"checked"`
+	Violations []RuleViolation `json:"violations" xml:"violations>violation"`
+}
+
 // DirectoryInfo represents statistics for a subdirectory
 type DirectoryInfo struct {
 	Path       string  `json:"path" xml:"path"`
@@ -58,6 +145,77 @@ type DirectoryInfo struct {
 	Percentage float64 `json:"percentage" xml:"percentage"`
 }
 
+// OwnerInfo represents file statistics attributed to a single user or group
+type OwnerInfo struct {
+	Name       string  `json:"name" xml:"name"`
+	ID         string  `json:"id" xml:"id"`
+	FileCount  int     `json:"file_count" xml:"fileCount"`
+	TotalSize  int64   `json:"total_size" xml:"totalSize"`
+	Percentage float64 `json:"percentage" xml:"percentage"`
+}
+
+// PermissionAudit lists entries with noteworthy permissions
+type PermissionAudit struct {
+	WorldWritable []string `json:"world_writable" xml:"worldWritable>path"`
+	Setuid        []string `json:"setuid" xml:"setuid>path"`
+	Setgid        []string `json:"setgid" xml:"setgid>path"`
+	Unreadable    []string `json:"unreadable" xml:"unreadable>path"`
+}
+
+// SymlinkInfo represents a symbolic link and the target it points to
+type SymlinkInfo struct {
+	Path   string `json:"path" xml:"path"`
+	Target string `json:"target" xml:"target"`
+}
+
+// EntryTypeCount represents the number of entries of one file type, such as
+// "regular", "directory" or "named_pipe"
+type EntryTypeCount struct {
+	Type  string `json:"type" xml:"type"`
+	Count int    `json:"count" xml:"count"`
+}
+
+// SpecialFile represents a named pipe, socket, device node or other entry
+// that is neither a regular file, a directory nor a symlink. Special files
+// are not counted in file totals or sizes.
+type SpecialFile struct {
+	Path string `json:"path" xml:"path"`
+	Type string `json:"type" xml:"type"`
+}
+
+// XattrFile represents a file or directory carrying extended attributes
+type XattrFile struct {
+	Path  string `json:"path" xml:"path"`
+	Count int    `json:"count" xml:"count"` // Number of extended attributes
+	Bytes int64  `json:"bytes" xml:"bytes"` // Size of attribute names and values
+	ACL   bool   `json:"acl" xml:"acl"`     // Carries a POSIX access or default ACL
+}
+
+// XattrStats summarizes the extended attributes and POSIX ACLs in the tree
+type XattrStats struct {
+	FilesWithXattrs int         `json:"files_with_xattrs" xml:"filesWithXattrs"`
+	FilesWithACLs   int         `json:"files_with_acls" xml:"filesWithACLs"`
+	TotalBytes      int64       `json:"total_bytes" xml:"totalBytes"`
+	Largest         []XattrFile `json:"largest" xml:"largest>file"` // Files with the most attribute bytes
+}
+
+// DirectoryEntries represents the number of entries (files, directories,
+// symlinks, ...) in a directory, each of which consumes an inode
+type DirectoryEntries struct {
+	Path              string `json:"path" xml:"path"`
+	DirectEntries     int    `json:"direct_entries" xml:"directEntries"`
+	CumulativeEntries int    `json:"cumulative_entries" xml:"cumulativeEntries"`
+}
+
+// FilesystemInfo represents the capacity of the filesystem holding the analyzed directory
+type FilesystemInfo struct {
+	TotalBytes     uint64 `json:"total_bytes" xml:"totalBytes"`
+	FreeBytes      uint64 `json:"free_bytes" xml:"freeBytes"`
+	AvailableBytes uint64 `json:"available_bytes" xml:"availableBytes"` // Free bytes usable by unprivileged users
+	TotalInodes    uint64 `json:"total_inodes" xml:"totalInodes"`
+	FreeInodes     uint64 `json:"free_inodes" xml:"freeInodes"`
+}
+
 // Exclusion represents a file or directory that was excluded from processing
 type Exclusion struct {
 	Path   string `json:"path" xml:"path"`
@@ -66,20 +224,114 @@ type Exclusion struct {
 
 // RenameOperation represents a single rename operation
 type RenameOperation struct {
-	OldPath string `json:"old_path" xml:"oldPath"`
-	NewPath string `json:"new_path" xml:"newPath"`
-	Error   string `json:"error,omitempty" xml:"error,omitempty"`
+	OldPath       string `json:"old_path" xml:"oldPath"`
+	NewPath       string `json:"new_path" xml:"newPath"`
+	Error         string `json:"error,omitempty" xml:"error,omitempty"`
+	Overwrite     bool   `json:"overwrite,omitempty" xml:"overwrite,omitempty"` // Set when the rename replaces an existing file
+	Rollback      string `json:"rollback,omitempty" xml:"rollback,omitempty"`   // Set when an atomic run reverted the rename
+	RollbackError string `json:"rollback_error,omitempty" xml:"rollbackError,omitempty"`
 }
 
+// Rollback outcomes of a rename operation
+const (
+	RollbackDone   = "rolled_back" // The file is back at its old path
+	RollbackFailed = "failed"      // The file could not be moved back, see RollbackError
+)
+
 // DirStatResult represents the complete result of a directory statistics analysis
 type DirStatResult struct {
-	Metadata    *Metadata       `json:"metadata" xml:"metadata"`
-	TotalFiles  int             `json:"total_files" xml:"totalFiles"`
-	TotalSize   int64           `json:"total_size" xml:"totalSize"`
-	LargestFile *FileInfo       `json:"largest_file" xml:"largestFile"`
-	FileTypes   []FileType      `json:"file_types" xml:"fileTypes"`
-	Directories []DirectoryInfo `json:"directories" xml:"directories"`
-	Exclusions  []Exclusion     `json:"exclusions" xml:"exclusions"`
+	Metadata                *Metadata            `json:"metadata" xml:"metadata"`
+	Root                    string               `json:"root,omitempty" xml:"root,omitempty"` // Analyzed directory as given on the command line
+	TotalFiles              int                  `json:"total_files" xml:"totalFiles"`
+	TotalSize               int64                `json:"total_size" xml:"totalSize"`
+	LargestFile             *FileInfo            `json:"largest_file" xml:"largestFile"`
+	TotalInodes             int                  `json:"total_inodes" xml:"totalInodes"` // Distinct inodes in the tree, hard links counted once
+	Filesystem              *FilesystemInfo      `json:"filesystem,omitempty" xml:"filesystem,omitempty"`
+	EntryTypes              []EntryTypeCount     `json:"entry_types" xml:"entryTypes>entryType"` // Entries by file type, excluding the root
+	SpecialFiles            []SpecialFile        `json:"special_files" xml:"specialFiles>file"`
+	Xattrs                  *XattrStats          `json:"xattrs,omitempty" xml:"xattrs,omitempty"`
+	FileTypes               []FileType           `json:"file_types" xml:"fileTypes"`
+	ContentTypes            []ContentType        `json:"content_types,omitempty" xml:"contentTypes,omitempty"`
+	ContentCategories       []ContentCategory    `json:"content_categories,omitempty" xml:"contentCategories,omitempty"`
+	Categories              []CategoryInfo       `json:"categories,omitempty" xml:"categories,omitempty"` // User-defined categories, see --categories
+	Rules                   *RuleReport          `json:"rules,omitempty" xml:"rules,omitempty"`
+	Git                     *GitStats            `json:"git,omitempty" xml:"git,omitempty"`
+	Compression             *CompressionEstimate `json:"compression,omitempty" xml:"compression,omitempty"`
+	Directories             []DirectoryInfo      `json:"directories" xml:"directories"`
+	Owners                  []OwnerInfo          `json:"owners" xml:"owners"`
+	Groups                  []OwnerInfo          `json:"groups" xml:"groups"`
+	Permissions             *PermissionAudit     `json:"permissions" xml:"permissions"`
+	EmptyDirectories        []string             `json:"empty_directories" xml:"emptyDirectories>path"`
+	EmptyFiles              []string             `json:"empty_files" xml:"emptyFiles>path"`
+	BrokenSymlinks          []SymlinkInfo        `json:"broken_symlinks" xml:"brokenSymlinks>symlink"`
+	ExternalSymlinks        []SymlinkInfo        `json:"external_symlinks" xml:"externalSymlinks>symlink"` // Symlinks pointing outside the root
+	DirectEntryHotspots     []DirectoryEntries   `json:"direct_entry_hotspots" xml:"directEntryHotspots>directory"`
+	CumulativeEntryHotspots []DirectoryEntries   `json:"cumulative_entry_hotspots" xml:"cumulativeEntryHotspots>directory"`
+	Exclusions              []Exclusion          `json:"exclusions" xml:"exclusions"`
+}
+
+// DiffEntry represents the change of a single directory or file type between two dirstat runs
+type DiffEntry struct {
+	Key            string  `json:"key" xml:"key"`
+	Status         string  `json:"status" xml:"status"` // "added", "removed", "grown", "shrunk", "changed"
+	OldCount       int     `json:"old_count" xml:"oldCount"`
+	NewCount       int     `json:"new_count" xml:"newCount"`
+	CountDelta     int     `json:"count_delta" xml:"countDelta"`
+	OldSize        int64   `json:"old_size" xml:"oldSize"`
+	NewSize        int64   `json:"new_size" xml:"newSize"`
+	SizeDelta      int64   `json:"size_delta" xml:"sizeDelta"`
+	SizePercentage float64 `json:"size_percentage" xml:"sizePercentage"` // Relative to the old size, 0 for added entries
+}
+
+// DirStatDiffResult represents the comparison of a dirstat run against a saved snapshot
+type DirStatDiffResult struct {
+	Metadata            *Metadata   `json:"metadata" xml:"metadata"`
+	SnapshotGeneratedAt string      `json:"snapshot_generated_at" xml:"snapshotGeneratedAt"`
+	OldTotalFiles       int         `json:"old_total_files" xml:"oldTotalFiles"`
+	NewTotalFiles       int         `json:"new_total_files" xml:"newTotalFiles"`
+	OldTotalSize        int64       `json:"old_total_size" xml:"oldTotalSize"`
+	NewTotalSize        int64       `json:"new_total_size" xml:"newTotalSize"`
+	SizePercentage      float64     `json:"size_percentage" xml:"sizePercentage"`
+	Directories         []DiffEntry `json:"directories" xml:"directories"`
+	FileTypes           []DiffEntry `json:"file_types" xml:"fileTypes"`
+	Rules               *RuleReport `json:"rules,omitempty" xml:"rules,omitempty"`
+}
+
+// HistoryPoint represents one recorded dirstat run
+type HistoryPoint struct {
+	Time           string `json:"time" xml:"time"` // RFC 3339
+	TotalFiles     int    `json:"total_files" xml:"totalFiles"`
+	TotalSize      int64  `json:"total_size" xml:"totalSize"`
+	AvailableBytes uint64 `json:"available_bytes,omitempty" xml:"availableBytes,omitempty"` // Free space of the filesystem, 0 if unknown
+}
+
+// DirectoryGrowth represents the size trend of a directory across recorded runs
+type DirectoryGrowth struct {
+	Path         string  `json:"path" xml:"path"`
+	FirstSize    int64   `json:"first_size" xml:"firstSize"`
+	LastSize     int64   `json:"last_size" xml:"lastSize"`
+	GrowthPerDay float64 `json:"growth_per_day" xml:"growthPerDay"` // Bytes per day, fitted over all runs
+}
+
+// FillProjection represents when the filesystem holding the tree runs out
+// of space if its free space keeps shrinking at the fitted rate
+type FillProjection struct {
+	TotalBytes     uint64  `json:"total_bytes" xml:"totalBytes"`
+	AvailableBytes uint64  `json:"available_bytes" xml:"availableBytes"`  // At the latest run
+	ConsumedPerDay float64 `json:"consumed_per_day" xml:"consumedPerDay"` // Bytes per day, negative when space is freed
+	Filling        bool    `json:"filling" xml:"filling"`
+	DaysUntilFull  float64 `json:"days_until_full,omitempty" xml:"daysUntilFull,omitempty"`
+	FullAt         string  `json:"full_at,omitempty" xml:"fullAt,omitempty"` // RFC 3339, empty beyond the representable range
+}
+
+// DirStatHistoryResult represents the growth trends of a directory tree over recorded dirstat runs
+type DirStatHistoryResult struct {
+	Metadata     *Metadata         `json:"metadata" xml:"metadata"`
+	Root         string            `json:"root" xml:"root"`
+	Points       []HistoryPoint    `json:"points" xml:"points>point"`
+	GrowthPerDay float64           `json:"growth_per_day" xml:"growthPerDay"`       // Growth of the total size in bytes per day
+	Directories  []DirectoryGrowth `json:"directories" xml:"directories>directory"` // Fastest growing first
+	Projection   *FillProjection   `json:"projection,omitempty" xml:"projection,omitempty"`
 }
 
 // RenameResult represents the complete result of a rename operation
@@ -88,6 +340,8 @@ type RenameResult struct {
 	Operations []RenameOperation `json:"operations" xml:"operations"`
 	DryRun     bool              `json:"dry_run" xml:"dryRun"`
 	Exclusions []Exclusion       `json:"exclusions" xml:"exclusions"`
+	Journal    string            `json:"journal,omitempty" xml:"journal,omitempty"`        // Undo journal of the performed renames
+	RolledBack bool              `json:"rolled_back,omitempty" xml:"rolledBack,omitempty"` // An atomic run failed and reverted its renames
 }
 
 // OutputFormatter defines the interface for different output formats
@@ -95,6 +349,8 @@ type OutputFormatter interface {
 	FormatDuplicates(result *DuplicateResult, writer io.Writer) error
 	FormatDirStat(result *DirStatResult, writer io.Writer) error
 	FormatRename(result *RenameResult, writer io.Writer) error
+	FormatDirStatDiff(result *DirStatDiffResult, writer io.Writer) error
+	FormatDirStatHistory(result *DirStatHistoryResult, writer io.Writer) error
 }
 
 // OutputFormat represents the supported output formats
@@ -105,6 +361,8 @@ const (
 	FormatJSON OutputFormat = "json"
 	FormatXML  OutputFormat = "xml"
 	FormatHTML OutputFormat = "html"
+
+	FormatOpenMetrics OutputFormat = "openmetrics"
 )
 
 // NewFormatter creates a new formatter based on the specified format
@@ -116,6 +374,8 @@ func NewFormatter(format OutputFormat) OutputFormatter {
 		return &XMLFormatter{}
 	case FormatHTML:
 		return &HTMLFormatter{}
+	case FormatOpenMetrics:
+		return &OpenMetricsFormatter{}
 	case FormatText:
 		fallthrough
 	default:
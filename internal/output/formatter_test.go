@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func createTestResult() *DuplicateResult {
@@ -203,6 +204,7 @@ func TestNewFormatter(t *testing.T) {
 		{FormatJSON, "*output.JSONFormatter"},
 		{FormatXML, "*output.XMLFormatter"},
 		{FormatHTML, "*output.HTMLFormatter"},
+		{FormatOpenMetrics, "*output.OpenMetricsFormatter"},
 	}
 
 	for _, test := range tests {
@@ -223,3 +225,411 @@ func TestNewFormatter_Default(t *testing.T) {
 		t.Errorf("NewFormatter(invalid) = %s, expected %s", actual, expected)
 	}
 }
+
+func TestTextFormatter_FormatDirStatDiff(t *testing.T) {
+	result := &DirStatDiffResult{
+		OldTotalFiles:  2,
+		NewTotalFiles:  3,
+		OldTotalSize:   1024,
+		NewTotalSize:   3072,
+		SizePercentage: 200,
+		Directories: []DiffEntry{
+			{Key: "logs", Status: "grown", OldCount: 1, NewCount: 2, CountDelta: 1, OldSize: 1024, NewSize: 3072, SizeDelta: 2048, SizePercentage: 200},
+		},
+	}
+	formatter := &TextFormatter{}
+
+	var buf bytes.Buffer
+	if err := formatter.FormatDirStatDiff(result, &buf); err != nil {
+		t.Fatalf("FormatDirStatDiff failed: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Total Size: 1.0 KB -> 3.0 KB (+2.0 KB, +200.00%)") {
+		t.Errorf("Expected total size change in output, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Directory Changes") || !strings.Contains(output, "grown") {
+		t.Error("Expected directory changes table in output")
+	}
+	if strings.Contains(output, "File Type Changes") {
+		t.Error("Did not expect an empty file type changes table")
+	}
+}
+
+func TestBuildTreemap(t *testing.T) {
+	result := &DirStatResult{
+		TotalFiles: 6,
+		TotalSize:  1000,
+		Directories: []DirectoryInfo{
+			{Path: "a", FileCount: 1, TotalSize: 100},
+			{Path: "a/b/c", FileCount: 2, TotalSize: 500},
+			{Path: "d", FileCount: 2, TotalSize: 300},
+		},
+	}
+
+	root := buildTreemap(result)
+	if root.Size != 1000 || root.Files != 6 {
+		t.Fatalf("unexpected root totals: size=%d files=%d", root.Size, root.Files)
+	}
+
+	// Children are sorted by size: a (600), d (300), root files (100)
+	if len(root.Children) != 3 {
+		t.Fatalf("expected 3 root children, got %d", len(root.Children))
+	}
+	a := root.Children[0]
+	if a.Name != "a" || a.Size != 600 || a.Files != 3 {
+		t.Errorf("unexpected node a: %+v", a)
+	}
+	if root.Children[2].Name != "(files)" || root.Children[2].Size != 100 {
+		t.Errorf("expected root files leaf, got %+v", root.Children[2])
+	}
+
+	// Intermediate directory b is synthesized
+	if len(a.Children) != 2 || a.Children[0].Path != "a/b" || a.Children[0].Size != 500 {
+		t.Errorf("unexpected children of a: %+v", a.Children)
+	}
+}
+
+func TestHTMLFormatter_FormatDirStat_Treemap(t *testing.T) {
+	result := &DirStatResult{
+		TotalFiles:  1,
+		TotalSize:   10,
+		Directories: []DirectoryInfo{{Path: "</script>", FileCount: 1, TotalSize: 10}},
+	}
+	formatter := &HTMLFormatter{}
+
+	var buf bytes.Buffer
+	if err := formatter.FormatDirStat(result, &buf); err != nil {
+		t.Fatalf("FormatDirStat failed: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, `<svg id="treemap"`) || !strings.Contains(output, "var treemapData = ") {
+		t.Error("Expected embedded treemap in HTML output")
+	}
+	if strings.Contains(output, `"</script>"`) {
+		t.Error("Expected directory names to be escaped inside the treemap script")
+	}
+	if strings.Contains(output, "<script src") || strings.Contains(output, "<link") {
+		t.Error("Treemap must not load external resources")
+	}
+}
+
+func TestHTMLFormatter_FormatDirStat_TypeDrillDown(t *testing.T) {
+	result := &DirStatResult{
+		TotalFiles: 2,
+		TotalSize:  30,
+		FileTypes: []FileType{
+			{
+				Extension:    ".log",
+				Count:        1,
+				TotalSize:    20,
+				LargestFiles: []FileInfo{{Name: "a.log", Size: 20, Path: "logs/<a>.log"}},
+				Directories:  []DirectoryInfo{{Path: "logs", FileCount: 1, TotalSize: 20, Percentage: 100}},
+			},
+			{Extension: ".txt", Count: 1, TotalSize: 10},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (&HTMLFormatter{}).FormatDirStat(result, &buf); err != nil {
+		t.Fatalf("FormatDirStat failed: %v", err)
+	}
+	output := buf.String()
+
+	// Only types with a drill-down can be expanded
+	if strings.Count(output, `<tr class="expandable" onclick="toggleDetails(this)">`) != 1 {
+		t.Error("Expected exactly one expandable file type row")
+	}
+	if strings.Count(output, `<tr class="details-row" hidden>`) != 1 {
+		t.Error("Expected exactly one hidden details row")
+	}
+	if !strings.Contains(output, "logs/&lt;a&gt;.log") {
+		t.Error("Expected the escaped path of the largest file")
+	}
+
+	buf.Reset()
+	if err := (&TextFormatter{}).FormatDirStat(result, &buf); err != nil {
+		t.Fatalf("FormatDirStat failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "File Type Details") || !strings.Contains(buf.String(), "logs/<a>.log") {
+		t.Errorf("Expected the drill-down in text output, got:\n%s", buf.String())
+	}
+}
+
+func TestFormatDirStat_RuleViolations(t *testing.T) {
+	result := &DirStatResult{
+		Rules: &RuleReport{
+			Checked: 3,
+			Violations: []RuleViolation{
+				{Rule: "logs/ > 5G", Value: 6 << 30, Limit: 5 << 30, Unit: "bytes"},
+				{Rule: "*.core present", Value: 2, Unit: "count", Matches: []string{"a.core", "<b>.core"}, MatchCount: 12},
+			},
+		},
+	}
+
+	var text bytes.Buffer
+	if err := (&TextFormatter{}).FormatDirStat(result, &text); err != nil {
+		t.Fatalf("FormatDirStat failed: %v", err)
+	}
+	for _, want := range []string{"2 of 3 rules violated", "- logs/ > 5G: 6.0 GB (limit 5.0 GB)", "- *.core present: 12 matching files", "... and 10 more"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Expected %q in text output, got:\n%s", want, text.String())
+		}
+	}
+
+	var page bytes.Buffer
+	if err := (&HTMLFormatter{}).FormatDirStat(result, &page); err != nil {
+		t.Fatalf("FormatDirStat failed: %v", err)
+	}
+	if !strings.Contains(page.String(), `id="rules-table"`) || !strings.Contains(page.String(), "&lt;b&gt;.core") {
+		t.Error("Expected an escaped rule violations table in HTML output")
+	}
+
+	result.Rules.Violations = nil
+	text.Reset()
+	if err := (&TextFormatter{}).FormatDirStat(result, &text); err != nil {
+		t.Fatalf("FormatDirStat failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "All 3 rules passed") {
+		t.Errorf("Expected passing rules in text output, got:\n%s", text.String())
+	}
+}
+
+func TestOpenMetricsFormatter_FormatDirStat(t *testing.T) {
+	result := &DirStatResult{
+		Metadata:   &Metadata{GeneratedAt: "2024-01-02T03:04:05Z"},
+		Root:       `C:\data "share"`,
+		TotalFiles: 3,
+		TotalSize:  3072,
+		FileTypes: []FileType{
+			{Extension: ".log", Count: 2, TotalSize: 2048},
+			{Extension: "(no extension)", Count: 1, TotalSize: 1024},
+		},
+		Directories: []DirectoryInfo{
+			{Path: "logs\nnew", FileCount: 2, TotalSize: 2048},
+		},
+		EntryTypes: []EntryTypeCount{{Type: "regular", Count: 3}, {Type: "named_pipe", Count: 1}},
+		Rules:      &RuleReport{Checked: 1, Violations: []RuleViolation{{Rule: `logs/ > 1K`, Value: 2048, Limit: 1024, Unit: "bytes"}}},
+	}
+
+	var buf bytes.Buffer
+	if err := NewFormatter(FormatOpenMetrics).FormatDirStat(result, &buf); err != nil {
+		t.Fatalf("FormatDirStat failed: %v", err)
+	}
+	output := buf.String()
+
+	root := `root="C:\\data \"share\""`
+	for _, want := range []string{
+		"# TYPE filetools_dirstat_size_bytes gauge\n# UNIT filetools_dirstat_size_bytes bytes\n",
+		"filetools_dirstat_files{" + root + "} 3\n",
+		"filetools_dirstat_extension_size_bytes{" + root + `,extension=".log"} 2048` + "\n",
+		"filetools_dirstat_directory_files{" + root + `,directory="."} 1` + "\n",
+		"filetools_dirstat_directory_files{" + root + `,directory="logs\nnew"} 2` + "\n",
+		"filetools_dirstat_entries{" + root + `,type="named_pipe"} 1` + "\n",
+		"filetools_dirstat_rule_violations{" + root + "} 1\n",
+		"filetools_dirstat_generated_timestamp_seconds{" + root + "} 1704164645\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if !strings.HasSuffix(output, "\n# EOF\n") {
+		t.Error("Expected the exposition to end with # EOF")
+	}
+
+	// Every family's samples must directly follow its metadata
+	seen := make(map[string]bool)
+	current := ""
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		if strings.HasPrefix(line, "# TYPE ") {
+			current = strings.Fields(line)[2]
+			if seen[current] {
+				t.Errorf("Family %s appears twice", current)
+			}
+			seen[current] = true
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := line[:strings.IndexAny(line, "{ ")]
+		if name != current {
+			t.Errorf("Sample %s outside of its family %s", name, current)
+		}
+	}
+}
+
+func TestOpenMetricsFormatter_RuleSeries(t *testing.T) {
+	// The same rule listed twice gives one series, and rules differing in
+	// bytes that are not UTF-8 give two
+	result := &DirStatResult{Rules: &RuleReport{Checked: 4, Violations: []RuleViolation{
+		{Rule: "*.core present", Value: 2},
+		{Rule: "*.core present", Value: 2},
+		{Rule: "a\xff present", Value: 1},
+		{Rule: "a\xfe present", Value: 1},
+	}}}
+
+	var buf bytes.Buffer
+	if err := (&OpenMetricsFormatter{}).FormatDirStat(result, &buf); err != nil {
+		t.Fatalf("FormatDirStat failed: %v", err)
+	}
+	output := buf.String()
+	if n := strings.Count(output, `rule="*.core present"`); n != 1 {
+		t.Errorf("Expected one series for the repeated rule, got %d:\n%s", n, output)
+	}
+	for _, want := range []string{`rule="a\\xff present"} 1`, `rule="a\\xfe present"} 1`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if !utf8.ValidString(output) {
+		t.Error("Expected the exposition to be valid UTF-8")
+	}
+}
+
+func TestOpenMetricsFormatter_FormatDuplicates(t *testing.T) {
+	result := &DuplicateResult{
+		Groups: []DuplicateGroup{
+			{Size: 100, Files: []string{"a", "b", "c"}},
+			{Size: 10, Files: []string{"d", "e"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (&OpenMetricsFormatter{}).FormatDuplicates(result, &buf); err != nil {
+		t.Fatalf("FormatDuplicates failed: %v", err)
+	}
+	for _, want := range []string{"filetools_dupfind_duplicate_groups 2\n", "filetools_dupfind_duplicate_files 5\n", "filetools_dupfind_wasted_bytes 210\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestFormatRename_Rollback(t *testing.T) {
+	result := &RenameResult{
+		Operations: []RenameOperation{
+			{OldPath: "a", NewPath: "x", Rollback: RollbackDone},
+			{OldPath: "b", NewPath: "y", Rollback: RollbackFailed, RollbackError: "permission denied"},
+			{OldPath: "c", NewPath: "z", Error: "rename failed: file exists"},
+		},
+		RolledBack: true,
+	}
+
+	var text bytes.Buffer
+	if err := (&TextFormatter{}).FormatRename(result, &text); err != nil {
+		t.Fatalf("FormatRename failed: %v", err)
+	}
+	for _, want := range []string{
+		"ROLLED BACK - A rename failed",
+		"- a -> x (ROLLED BACK)\n",
+		"- b -> y (ROLLBACK FAILED: permission denied)\n",
+		"- c -> z (ERROR: rename failed: file exists)\n",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Expected %q in text output, got:\n%s", want, text.String())
+		}
+	}
+
+	var metrics bytes.Buffer
+	if err := (&OpenMetricsFormatter{}).FormatRename(result, &metrics); err != nil {
+		t.Fatalf("FormatRename failed: %v", err)
+	}
+	for _, want := range []string{
+		`filetools_rename_operations{status="ok"} 0`,
+		`filetools_rename_operations{status="error"} 1`,
+		`filetools_rename_operations{status="rolled_back"} 1`,
+		`filetools_rename_operations{status="rollback_failed"} 1`,
+		"filetools_rename_rolled_back 1\n",
+	} {
+		if !strings.Contains(metrics.String(), want) {
+			t.Errorf("Expected %q in OpenMetrics output, got:\n%s", want, metrics.String())
+		}
+	}
+}
+
+func TestFormatRename_Overwrite(t *testing.T) {
+	result := &RenameResult{
+		Operations: []RenameOperation{{OldPath: "a", NewPath: "b", Overwrite: true}},
+		DryRun:     true,
+	}
+
+	var text bytes.Buffer
+	if err := (&TextFormatter{}).FormatRename(result, &text); err != nil {
+		t.Fatalf("FormatRename failed: %v", err)
+	}
+	if want := "- a -> b (OVERWRITES EXISTING FILE)\n"; !strings.Contains(text.String(), want) {
+		t.Errorf("Expected %q in text output, got:\n%s", want, text.String())
+	}
+
+	var jsonOut bytes.Buffer
+	if err := (&JSONFormatter{}).FormatRename(result, &jsonOut); err != nil {
+		t.Fatalf("FormatRename failed: %v", err)
+	}
+	if want := `"overwrite": true`; !strings.Contains(jsonOut.String(), want) {
+		t.Errorf("Expected %q in JSON output, got:\n%s", want, jsonOut.String())
+	}
+}
+
+func TestFormatDirStatHistory(t *testing.T) {
+	result := &DirStatHistoryResult{
+		Root: "/srv/<data>",
+		Points: []HistoryPoint{
+			{Time: "2024-01-01T00:00:00Z", TotalFiles: 10, TotalSize: 1000, AvailableBytes: 4000},
+			{Time: "2024-01-03T00:00:00Z", TotalFiles: 12, TotalSize: 1600, AvailableBytes: 3000},
+		},
+		GrowthPerDay: 300,
+		Directories:  []DirectoryGrowth{{Path: "logs", FirstSize: 100, LastSize: 700, GrowthPerDay: 300}},
+		Projection: &FillProjection{
+			TotalBytes:     10000,
+			AvailableBytes: 3000,
+			ConsumedPerDay: 500,
+			Filling:        true,
+			DaysUntilFull:  6,
+			FullAt:         "2024-01-09T00:00:00Z",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (&TextFormatter{}).FormatDirStatHistory(result, &buf); err != nil {
+		t.Fatalf("FormatDirStatHistory failed: %v", err)
+	}
+	for _, want := range []string{"Runs: 2", "Total Size Growth: +300 B/day", "full in 6.0 days (2024-01-09T00:00:00Z)", "logs"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in text output, got:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := (&HTMLFormatter{}).FormatDirStatHistory(result, &buf); err != nil {
+		t.Fatalf("FormatDirStatHistory failed: %v", err)
+	}
+	output := buf.String()
+	if strings.Count(output, `<svg class="trend-chart"`) != 2 {
+		t.Error("Expected trend charts of the total size and the free space")
+	}
+	if strings.Count(output, `<circle class="trend-point"`) != 4 {
+		t.Error("Expected one chart point per run and chart")
+	}
+	if !strings.Contains(output, "/srv/&lt;data&gt;") {
+		t.Error("Expected the root to be escaped")
+	}
+	if strings.Contains(output, "<script src") || strings.Contains(output, "<link") {
+		t.Error("Trend charts must not load external resources")
+	}
+
+	buf.Reset()
+	if err := (&OpenMetricsFormatter{}).FormatDirStatHistory(result, &buf); err != nil {
+		t.Fatalf("FormatDirStatHistory failed: %v", err)
+	}
+	for _, want := range []string{
+		`filetools_dirstat_history_directory_growth_bytes_per_day{root="/srv/<data>",directory="logs"} 300` + "\n",
+		`filetools_dirstat_history_filesystem_days_until_full{root="/srv/<data>"} 6` + "\n",
+		`filetools_dirstat_history_filesystem_full_timestamp_seconds{root="/srv/<data>"} 1704758400` + "\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in OpenMetrics output, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -399,154 +399,7 @@ func (f *HTMLFormatter) generateDirStatHTML(result *DirStatResult) string {
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>Directory Statistics Report</title>
     <style>
-        body {
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
-            margin: 0;
-            padding: 20px;
-            background-color: #f5f5f5;
-        }
-        .container {
-            max-width: 1400px;
-            margin: 0 auto;
-            background: white;
-            border-radius: 8px;
-            box-shadow: 0 2px 10px rgba(0,0,0,0.1);
-            padding: 30px;
-        }
-        h1 {
-            color: #333;
-            border-bottom: 2px solid #007acc;
-            padding-bottom: 10px;
-        }
-        .summary {
-            background: #e9ecef;
-            padding: 20px;
-            border-radius: 6px;
-            margin-bottom: 30px;
-            display: grid;
-            grid-template-columns: repeat(auto-fit, minmax(200px, 1fr));
-            gap: 20px;
-        }
-        .summary-item {
-            text-align: center;
-        }
-        .summary-value {
-            font-size: 24px;
-            font-weight: bold;
-            color: #007acc;
-            display: block;
-        }
-        .summary-label {
-            font-size: 14px;
-            color: #666;
-            margin-top: 5px;
-        }
-        .section {
-            margin-bottom: 40px;
-        }
-        .section h2 {
-            color: #333;
-            border-bottom: 1px solid #ddd;
-            padding-bottom: 8px;
-            margin-bottom: 20px;
-        }
-        table {
-            width: 100%;
-            border-collapse: collapse;
-            margin-bottom: 20px;
-            background: white;
-            border-radius: 6px;
-            overflow: hidden;
-            box-shadow: 0 1px 3px rgba(0,0,0,0.1);
-        }
-        th, td {
-            padding: 12px 15px;
-            text-align: left;
-            border-bottom: 1px solid #eee;
-        }
-        th {
-            background: #f8f9fa;
-            font-weight: 600;
-            color: #333;
-            position: sticky;
-            top: 0;
-        }
-        tr:hover {
-            background-color: #f8f9fa;
-        }
-        .size-col {
-            text-align: right;
-            font-family: monospace;
-        }
-        .count-col {
-            text-align: right;
-        }
-        .percentage-col {
-            text-align: right;
-        }
-        .percentage-bar {
-            display: inline-block;
-            height: 8px;
-            background: #e9ecef;
-            border-radius: 4px;
-            width: 60px;
-            margin-left: 10px;
-            vertical-align: middle;
-        }
-        .percentage-fill {
-            display: block;
-            height: 100%;
-            background: #007acc;
-            border-radius: 4px;
-        }
-        .file-path {
-            font-family: monospace;
-            word-break: break-all;
-        }
-        .no-data {
-            text-align: center;
-            color: #666;
-            padding: 40px;
-            font-style: italic;
-        }
-        .sort-indicator {
-            margin-left: 5px;
-            opacity: 0.5;
-        }
-        .sort-indicator.active {
-            opacity: 1;
-        }
-        .exclusions-section {
-            margin-top: 40px;
-            border-top: 1px solid #ddd;
-            padding-top: 20px;
-        }
-        .exclusions-section h2 {
-            color: #333;
-            margin-bottom: 20px;
-        }
-        .exclusions-table {
-            width: 100%;
-            border-collapse: collapse;
-            margin-top: 10px;
-        }
-        .exclusions-table th,
-        .exclusions-table td {
-            border: 1px solid #ddd;
-            padding: 8px 12px;
-            text-align: left;
-        }
-        .exclusions-table th {
-            background-color: #f8f9fa;
-            font-weight: 600;
-        }
-        .exclusions-table tr:nth-child(even) {
-            background-color: #f8f9fa;
-        }
-        .exclusions-table tr:hover {
-            background-color: #e9ecef;
-        }
-    </style>
+` + dirStatCSS + treemapCSS + `    </style>
 </head>
 <body>
     <div class="container">
@@ -573,6 +426,49 @@ func (f *HTMLFormatter) generateDirStatHTML(result *DirStatResult) string {
             </div>`, formatSize(result.LargestFile.Size)))
 	}
 
+	sb.WriteString(fmt.Sprintf(`
+            <div class="summary-item">
+                <span class="summary-value">%d</span>
+                <span class="summary-label">Empty Directories</span>
+            </div>
+            <div class="summary-item">
+                <span class="summary-value">%d</span>
+                <span class="summary-label">Empty Files</span>
+            </div>
+            <div class="summary-item">
+                <span class="summary-value">%d</span>
+                <span class="summary-label">Broken Symlinks</span>
+            </div>
+            <div class="summary-item">
+                <span class="summary-value">%d</span>
+                <span class="summary-label">External Symlinks</span>
+            </div>
+            <div class="summary-item">
+                <span class="summary-value">%d</span>
+                <span class="summary-label">Special Files</span>
+            </div>`, len(result.EmptyDirectories), len(result.EmptyFiles), len(result.BrokenSymlinks), len(result.ExternalSymlinks), len(result.SpecialFiles)))
+
+	sb.WriteString(fmt.Sprintf(`
+            <div class="summary-item">
+                <span class="summary-value">%d</span>
+                <span class="summary-label">Total Inodes</span>
+            </div>`, result.TotalInodes))
+
+	if fs := result.Filesystem; fs != nil {
+		sb.WriteString(fmt.Sprintf(`
+            <div class="summary-item">
+                <span class="summary-value">%s</span>
+                <span class="summary-label">Free of %s</span>
+            </div>`, formatSize(int64(fs.AvailableBytes)), formatSize(int64(fs.TotalBytes))))
+		if fs.TotalInodes > 0 {
+			sb.WriteString(fmt.Sprintf(`
+            <div class="summary-item">
+                <span class="summary-value">%d</span>
+                <span class="summary-label">Free Inodes of %d</span>
+            </div>`, fs.FreeInodes, fs.TotalInodes))
+		}
+	}
+
 	// Add exclusions section if any
 	if len(result.Exclusions) > 0 {
 		sb.WriteString(`
@@ -604,6 +500,12 @@ func (f *HTMLFormatter) generateDirStatHTML(result *DirStatResult) string {
 	sb.WriteString(`
     </div>`)
 
+	// Threshold rules section
+	sb.WriteString(f.generateRulesHTML(result.Rules))
+
+	// Treemap section
+	sb.WriteString(f.generateTreemapHTML(result))
+
 	// File types section
 	if len(result.FileTypes) > 0 {
 		sb.WriteString(`
@@ -622,13 +524,20 @@ func (f *HTMLFormatter) generateDirStatHTML(result *DirStatResult) string {
 
 		for _, ft := range result.FileTypes {
 			percentage := ft.Percentage
+			row := "<tr>"
+			if len(ft.LargestFiles) > 0 {
+				row = `<tr class="expandable" onclick="toggleDetails(this)">`
+			}
 			sb.WriteString(fmt.Sprintf(`
-                    <tr>
+                    %s
                         <td>%s</td>
                         <td class="count-col">%d</td>
                         <td class="size-col">%s</td>
                         <td class="percentage-col">%.2f%%<span class="percentage-bar"><span class="percentage-fill" style="width: %.1f%%"></span></span></td>
-                    </tr>`, html.EscapeString(ft.Extension), ft.Count, formatSize(ft.TotalSize), percentage, percentage))
+                    </tr>`, row, html.EscapeString(ft.Extension), ft.Count, formatSize(ft.TotalSize), percentage, percentage))
+			if len(ft.LargestFiles) > 0 {
+				sb.WriteString(f.generateFileTypeDetailsHTML(ft))
+			}
 		}
 
 		sb.WriteString(`
@@ -637,31 +546,58 @@ func (f *HTMLFormatter) generateDirStatHTML(result *DirStatResult) string {
         </div>`)
 	}
 
-	// Directories section
-	if len(result.Directories) > 0 {
+	// Entry types section
+	if len(result.EntryTypes) > 0 {
 		sb.WriteString(`
         <div class="section">
-            <h2>Subdirectories</h2>
-            <table id="directories-table">
+            <h2>Entry Types</h2>
+            <table id="entry-types-table">
                 <thead>
                     <tr>
-                        <th>Path</th>
-                        <th class="count-col">Files</th>
+                        <th>Type</th>
+                        <th class="count-col">Count</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+		for _, entryType := range result.EntryTypes {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td>%s</td>
+                        <td class="count-col">%d</td>
+                    </tr>`, html.EscapeString(entryType.Type), entryType.Count))
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	// User-defined categories section
+	if len(result.Categories) > 0 {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Categories</h2>
+            <table id="categories-table">
+                <thead>
+                    <tr>
+                        <th>Category</th>
+                        <th class="count-col">Count</th>
                         <th class="size-col">Size</th>
                         <th class="percentage-col">Percentage</th>
                     </tr>
                 </thead>
                 <tbody>`)
 
-		for _, dir := range result.Directories {
-			percentage := dir.Percentage
+		for _, category := range result.Categories {
 			sb.WriteString(fmt.Sprintf(`
                     <tr>
-                        <td class="file-path">%s</td>
+                        <td>%s</td>
                         <td class="count-col">%d</td>
                         <td class="size-col">%s</td>
                         <td class="percentage-col">%.2f%%<span class="percentage-bar"><span class="percentage-fill" style="width: %.1f%%"></span></span></td>
-                    </tr>`, html.EscapeString(dir.Path), dir.FileCount, formatSize(dir.TotalSize), percentage, percentage))
+                    </tr>`, html.EscapeString(category.Name), category.Count, formatSize(category.TotalSize), category.Percentage, category.Percentage))
 		}
 
 		sb.WriteString(`
@@ -670,93 +606,894 @@ func (f *HTMLFormatter) generateDirStatHTML(result *DirStatResult) string {
         </div>`)
 	}
 
-	sb.WriteString(`
-    </div>`)
+	// Git status section
+	if g := result.Git; g != nil {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Git Status</h2>`)
+
+		if len(g.Repositories) == 0 {
+			sb.WriteString(`
+            <p>No git worktrees found.</p>`)
+		} else {
+			sb.WriteString(`
+            <table id="git-table">
+                <thead>
+                    <tr>
+                        <th>Worktree</th>
+                        <th class="size-col">Tracked</th>
+                        <th class="size-col">Untracked</th>
+                        <th class="size-col">Ignored</th>
+                        <th class="size-col">.git</th>
+                    </tr>
+                </thead>
+                <tbody>`)
 
-	// Add footer with branding
-	if result.Metadata != nil {
-		flags := []string{}
-		for _, f := range result.Metadata.Flags {
-			flags = append(flags, fmt.Sprintf("%s=%s", f.Name, f.Value))
+			for _, repo := range g.Repositories {
+				sb.WriteString(f.generateGitCountsRowHTML(repo.Path, repo.GitCounts))
+			}
+
+			sb.WriteString(`
+                </tbody>`)
+			if len(g.Repositories) > 1 {
+				sb.WriteString(`
+                <tfoot>`)
+				sb.WriteString(f.generateGitCountsRowHTML("Total", g.GitCounts))
+				sb.WriteString(`
+                </tfoot>`)
+			}
+			sb.WriteString(`
+            </table>`)
 		}
-		flagStr := strings.Join(flags, ", ")
-		sb.WriteString(fmt.Sprintf(`
-    <footer style="text-align: center; margin-top: 40px; color: #666; font-size: 14px;">
-        Generated by %s %s v%s on %s<br>
-        Flags: %s
-    </footer>`,
-			html.EscapeString(result.Metadata.ToolName),
-			html.EscapeString(result.Metadata.SubCommand),
-			html.EscapeString(result.Metadata.Version),
-			html.EscapeString(result.Metadata.GeneratedAt),
-			html.EscapeString(flagStr)))
-	}
 
-	sb.WriteString(`
-    <script>
-        // Table sorting functionality
-        function makeTableSortable(tableId) {
-            var table = document.getElementById(tableId);
-            if (!table) return;
+		sb.WriteString(`
+        </div>`)
+	}
 
-            var headers = table.querySelectorAll('th');
-            headers.forEach(function(header, index) {
-                header.style.cursor = 'pointer';
-                header.addEventListener('click', function() {
-                    sortTable(table, index);
-                });
-            });
-        }
+	// Compression estimate section
+	if c := result.Compression; c != nil {
+		sb.WriteString(fmt.Sprintf(`
+        <div class="section">
+            <h2>Compression Estimate</h2>
+            <p>Estimated compressed size %s of %s (ratio %.2fx, %.2f%% saved), sampling %s per file.</p>
+            <table id="compression-table">
+                <thead>
+                    <tr>
+                        <th>Extension</th>
+                        <th class="count-col">Count</th>
+                        <th class="size-col">Size</th>
+                        <th class="size-col">Compressed</th>
+                        <th class="count-col">Ratio</th>
+                        <th class="percentage-col">Savings</th>
+                    </tr>
+                </thead>
+                <tbody>`, formatSize(c.CompressedSize), formatSize(c.TotalSize), c.CompressionRatio, c.SavingsPercentage, formatSize(c.SampleSize)))
+
+		for _, ft := range c.FileTypes {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td>%s</td>
+                        <td class="count-col">%d</td>
+                        <td class="size-col">%s</td>
+                        <td class="size-col">%s</td>
+                        <td class="count-col">%.2fx</td>
+                        <td class="percentage-col">%.2f%%<span class="percentage-bar"><span class="percentage-fill" style="width: %.1f%%"></span></span></td>
+                    </tr>`, html.EscapeString(ft.Extension), ft.Count, formatSize(ft.TotalSize), formatSize(ft.CompressedSize), ft.CompressionRatio, ft.SavingsPercentage, ft.SavingsPercentage))
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	// Extended attributes section
+	if x := result.Xattrs; x != nil {
+		sb.WriteString(fmt.Sprintf(`
+        <div class="section">
+            <h2>Extended Attributes</h2>
+            <p>%d files carry extended attributes totalling %s, %d of them with POSIX ACLs.</p>`,
+			x.FilesWithXattrs, formatSize(x.TotalBytes), x.FilesWithACLs))
+
+		if len(x.Largest) > 0 {
+			sb.WriteString(`
+            <table id="xattrs-table">
+                <thead>
+                    <tr>
+                        <th>Path</th>
+                        <th class="count-col">Count</th>
+                        <th class="size-col">Size</th>
+                        <th>ACL</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+			for _, file := range x.Largest {
+				acl := "no"
+				if file.ACL {
+					acl = "yes"
+				}
+				sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td class="count-col">%d</td>
+                        <td class="size-col">%s</td>
+                        <td>%s</td>
+                    </tr>`, html.EscapeString(file.Path), file.Count, formatSize(file.Bytes), acl))
+			}
+
+			sb.WriteString(`
+                </tbody>
+            </table>`)
+		}
+
+		sb.WriteString(`
+        </div>`)
+	}
+
+	// Content categories section
+	if len(result.ContentCategories) > 0 {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Content Categories</h2>
+            <table id="content-categories-table">
+                <thead>
+                    <tr>
+                        <th>Category</th>
+                        <th class="count-col">Count</th>
+                        <th class="size-col">Size</th>
+                        <th class="percentage-col">Percentage</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+		for _, cc := range result.ContentCategories {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td>%s</td>
+                        <td class="count-col">%d</td>
+                        <td class="size-col">%s</td>
+                        <td class="percentage-col">%.2f%%<span class="percentage-bar"><span class="percentage-fill" style="width: %.1f%%"></span></span></td>
+                    </tr>`, html.EscapeString(cc.Category), cc.Count, formatSize(cc.TotalSize), cc.Percentage, cc.Percentage))
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	// Content types section
+	if len(result.ContentTypes) > 0 {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Content Types</h2>
+            <table id="content-types-table">
+                <thead>
+                    <tr>
+                        <th>MIME Type</th>
+                        <th>Category</th>
+                        <th class="count-col">Count</th>
+                        <th class="size-col">Size</th>
+                        <th class="percentage-col">Percentage</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+		for _, ct := range result.ContentTypes {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td class="count-col">%d</td>
+                        <td class="size-col">%s</td>
+                        <td class="percentage-col">%.2f%%<span class="percentage-bar"><span class="percentage-fill" style="width: %.1f%%"></span></span></td>
+                    </tr>`, html.EscapeString(ct.MIMEType), html.EscapeString(ct.Category), ct.Count, formatSize(ct.TotalSize), ct.Percentage, ct.Percentage))
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	// Directories section
+	if len(result.Directories) > 0 {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Subdirectories</h2>
+            <table id="directories-table">
+                <thead>
+                    <tr>
+                        <th>Path</th>
+                        <th class="count-col">Files</th>
+                        <th class="size-col">Size</th>
+                        <th class="percentage-col">Percentage</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+		for _, dir := range result.Directories {
+			percentage := dir.Percentage
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td class="count-col">%d</td>
+                        <td class="size-col">%s</td>
+                        <td class="percentage-col">%.2f%%<span class="percentage-bar"><span class="percentage-fill" style="width: %.1f%%"></span></span></td>
+                    </tr>`, html.EscapeString(dir.Path), dir.FileCount, formatSize(dir.TotalSize), percentage, percentage))
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	// Clutter section
+	if len(result.EmptyDirectories)+len(result.EmptyFiles)+len(result.BrokenSymlinks)+len(result.ExternalSymlinks) > 0 {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Clutter</h2>
+            <table id="clutter-table">
+                <thead>
+                    <tr>
+                        <th>Path</th>
+                        <th>Finding</th>
+                        <th>Target</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+		for _, path := range result.EmptyDirectories {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td>Empty directory</td>
+                        <td></td>
+                    </tr>`, html.EscapeString(path)))
+		}
+		for _, path := range result.EmptyFiles {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td>Empty file</td>
+                        <td></td>
+                    </tr>`, html.EscapeString(path)))
+		}
+		for _, link := range result.BrokenSymlinks {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td>Broken symlink</td>
+                        <td class="file-path">%s</td>
+                    </tr>`, html.EscapeString(link.Path), html.EscapeString(link.Target)))
+		}
+		for _, link := range result.ExternalSymlinks {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td>Symlink outside root</td>
+                        <td class="file-path">%s</td>
+                    </tr>`, html.EscapeString(link.Path), html.EscapeString(link.Target)))
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	// Special files section
+	if len(result.SpecialFiles) > 0 {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Special Files</h2>
+            <table id="special-files-table">
+                <thead>
+                    <tr>
+                        <th>Path</th>
+                        <th>Type</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+		for _, file := range result.SpecialFiles {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td>%s</td>
+                    </tr>`, html.EscapeString(file.Path), html.EscapeString(file.Type)))
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	// Entry count hot spot sections
+	sb.WriteString(f.generateEntryHotspotsHTML("Directories by Direct Entries", "direct-entries-table", result.DirectEntryHotspots))
+	sb.WriteString(f.generateEntryHotspotsHTML("Directories by Cumulative Entries", "cumulative-entries-table", result.CumulativeEntryHotspots))
+
+	// Owners and groups sections
+	sb.WriteString(f.generateOwnersHTML("Owners", "owners-table", result.Owners))
+	sb.WriteString(f.generateOwnersHTML("Groups", "groups-table", result.Groups))
+
+	// Permission audit section
+	if p := result.Permissions; p != nil && (len(p.WorldWritable)+len(p.Setuid)+len(p.Setgid)+len(p.Unreadable)) > 0 {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Permission Audit</h2>
+            <table id="permissions-table">
+                <thead>
+                    <tr>
+                        <th>Path</th>
+                        <th>Finding</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+		findings := []struct {
+			label string
+			paths []string
+		}{
+			{"World-writable", p.WorldWritable},
+			{"Setuid", p.Setuid},
+			{"Setgid", p.Setgid},
+			{"Unreadable", p.Unreadable},
+		}
+		for _, finding := range findings {
+			for _, path := range finding.paths {
+				sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td>%s</td>
+                    </tr>`, html.EscapeString(path), finding.label))
+			}
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	sb.WriteString(`
+    </div>`)
+
+	// Add footer with branding
+	if result.Metadata != nil {
+		flags := []string{}
+		for _, f := range result.Metadata.Flags {
+			flags = append(flags, fmt.Sprintf("%s=%s", f.Name, f.Value))
+		}
+		flagStr := strings.Join(flags, ", ")
+		sb.WriteString(fmt.Sprintf(`
+    <footer style="text-align: center; margin-top: 40px; color: #666; font-size: 14px;">
+        Generated by %s %s v%s on %s<br>
+        Flags: %s
+    </footer>`,
+			html.EscapeString(result.Metadata.ToolName),
+			html.EscapeString(result.Metadata.SubCommand),
+			html.EscapeString(result.Metadata.Version),
+			html.EscapeString(result.Metadata.GeneratedAt),
+			html.EscapeString(flagStr)))
+	}
+
+	sb.WriteString(`
+    <script>
+` + sortableTablesJS + `
+
+` + treemapJS + `
+
+        // Initialize sortable tables and the treemap
+        document.addEventListener('DOMContentLoaded', function() {
+            initTreemap();
+            makeTableSortable('file-types-table');
+            makeTableSortable('entry-types-table');
+            makeTableSortable('categories-table');
+            makeTableSortable('git-table');
+            makeTableSortable('compression-table');
+            makeTableSortable('xattrs-table');
+            makeTableSortable('content-categories-table');
+            makeTableSortable('content-types-table');
+            makeTableSortable('directories-table');
+            makeTableSortable('direct-entries-table');
+            makeTableSortable('cumulative-entries-table');
+            makeTableSortable('owners-table');
+            makeTableSortable('groups-table');
+            makeTableSortable('permissions-table');
+            makeTableSortable('clutter-table');
+            makeTableSortable('special-files-table');
+        });
+    </script>
+</body>
+</html>`)
+
+	return sb.String()
+}
+
+// FormatDirStatDiff formats a directory statistics comparison as HTML
+func (f *HTMLFormatter) FormatDirStatDiff(result *DirStatDiffResult, writer io.Writer) error {
+	htmlContent := f.generateDirStatDiffHTML(result)
+	_, err := writer.Write([]byte(htmlContent))
+	return err
+}
+
+// generateDirStatDiffHTML creates the complete HTML document for a snapshot comparison
+func (f *HTMLFormatter) generateDirStatDiffHTML(result *DirStatDiffResult) string {
+	var sb strings.Builder
+
+	sb.WriteString(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Directory Statistics Comparison</title>
+    <style>
+` + dirStatCSS + `    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>Directory Statistics Comparison</h1>`)
+
+	// Summary section
+	sb.WriteString(fmt.Sprintf(`
+        <div class="summary">
+            <div class="summary-item">
+                <span class="summary-value">%s</span>
+                <span class="summary-label">Snapshot</span>
+            </div>
+            <div class="summary-item">
+                <span class="summary-value">%d &rarr; %d</span>
+                <span class="summary-label">Total Files (%+d)</span>
+            </div>
+            <div class="summary-item">
+                <span class="summary-value">%s &rarr; %s</span>
+                <span class="summary-label">Total Size (%s, %+.2f%%)</span>
+            </div>
+        </div>`,
+		html.EscapeString(result.SnapshotGeneratedAt),
+		result.OldTotalFiles, result.NewTotalFiles, result.NewTotalFiles-result.OldTotalFiles,
+		formatSize(result.OldTotalSize), formatSize(result.NewTotalSize),
+		formatSizeDelta(result.NewTotalSize-result.OldTotalSize), result.SizePercentage))
+
+	sb.WriteString(f.generateRulesHTML(result.Rules))
+	sb.WriteString(f.generateDiffEntriesHTML("Directory Changes", "Path", "directory-changes-table", result.Directories))
+	sb.WriteString(f.generateDiffEntriesHTML("File Type Changes", "Extension", "file-type-changes-table", result.FileTypes))
+
+	if len(result.Directories) == 0 && len(result.FileTypes) == 0 {
+		sb.WriteString(`
+        <div class="no-data">No changes since the snapshot.</div>`)
+	}
+
+	sb.WriteString(`
+    </div>`)
+
+	// Add footer with branding
+	if result.Metadata != nil {
+		flags := []string{}
+		for _, f := range result.Metadata.Flags {
+			flags = append(flags, fmt.Sprintf("%s=%s", f.Name, f.Value))
+		}
+		flagStr := strings.Join(flags, ", ")
+		sb.WriteString(fmt.Sprintf(`
+    <footer style="text-align: center; margin-top: 40px; color: #666; font-size: 14px;">
+        Generated by %s %s v%s on %s<br>
+        Flags: %s
+    </footer>`,
+			html.EscapeString(result.Metadata.ToolName),
+			html.EscapeString(result.Metadata.SubCommand),
+			html.EscapeString(result.Metadata.Version),
+			html.EscapeString(result.Metadata.GeneratedAt),
+			html.EscapeString(flagStr)))
+	}
+
+	sb.WriteString(`
+    <script>
+` + sortableTablesJS + `
+
+        // Initialize sortable tables
+        document.addEventListener('DOMContentLoaded', function() {
+            makeTableSortable('directory-changes-table');
+            makeTableSortable('file-type-changes-table');
+        });
+    </script>
+</body>
+</html>`)
+
+	return sb.String()
+}
+
+// generateDiffEntriesHTML creates a section with a table of snapshot differences
+func (f *HTMLFormatter) generateDiffEntriesHTML(title, keyTitle, tableID string, entries []DiffEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`
+        <div class="section">
+            <h2>%s</h2>
+            <table id="%s">
+                <thead>
+                    <tr>
+                        <th>%s</th>
+                        <th>Status</th>
+                        <th class="size-col">Old Size</th>
+                        <th class="size-col">New Size</th>
+                        <th class="size-col">Delta</th>
+                        <th class="percentage-col">Change</th>
+                        <th class="count-col">Files</th>
+                    </tr>
+                </thead>
+                <tbody>`, title, tableID, keyTitle))
 
-        function sortTable(table, columnIndex) {
-            var tbody = table.querySelector('tbody');
-            var rows = Array.from(tbody.querySelectorAll('tr'));
+	for _, entry := range entries {
+		change := "new"
+		if entry.Status != "added" {
+			change = fmt.Sprintf("%+.2f%%", entry.SizePercentage)
+		}
+		sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td class="status-%s">%s</td>
+                        <td class="size-col">%s</td>
+                        <td class="size-col">%s</td>
+                        <td class="size-col">%s</td>
+                        <td class="percentage-col">%s</td>
+                        <td class="count-col">%d &rarr; %d</td>
+                    </tr>`,
+			html.EscapeString(entry.Key), entry.Status, entry.Status,
+			formatSize(entry.OldSize), formatSize(entry.NewSize), formatSizeDelta(entry.SizeDelta),
+			change, entry.OldCount, entry.NewCount))
+	}
 
-            // Remove existing sort indicators
-            table.querySelectorAll('.sort-indicator').forEach(function(indicator) {
-                indicator.classList.remove('active');
-            });
+	sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
 
-            // Determine sort direction
-            var isNumeric = table.rows[0].cells[columnIndex].classList.contains('count-col') ||
-                           table.rows[0].cells[columnIndex].classList.contains('size-col') ||
-                           table.rows[0].cells[columnIndex].classList.contains('percentage-col');
+	return sb.String()
+}
 
-            rows.sort(function(a, b) {
-                var aVal = a.cells[columnIndex].textContent.trim();
-                var bVal = b.cells[columnIndex].textContent.trim();
+// FormatDirStatHistory formats directory growth trends as HTML with trend charts
+func (f *HTMLFormatter) FormatDirStatHistory(result *DirStatHistoryResult, writer io.Writer) error {
+	htmlContent := f.generateDirStatHistoryHTML(result)
+	_, err := writer.Write([]byte(htmlContent))
+	return err
+}
 
-                if (isNumeric) {
-                    // Extract numeric value (remove units like %, B, KB, etc.)
-                    var aNum = parseFloat(aVal.replace(/[^\d.]/g, '')) || 0;
-                    var bNum = parseFloat(bVal.replace(/[^\d.]/g, '')) || 0;
-                    return bNum - aNum; // Descending for numeric
-                } else {
-                    return aVal.localeCompare(bVal);
-                }
-            });
+// generateDirStatHistoryHTML creates the complete HTML document for directory growth trends
+func (f *HTMLFormatter) generateDirStatHistoryHTML(result *DirStatHistoryResult) string {
+	var sb strings.Builder
 
-            // Re-append sorted rows
-            rows.forEach(function(row) {
-                tbody.appendChild(row);
-            });
+	sb.WriteString(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Directory History</title>
+    <style>
+` + dirStatCSS + trendCSS + `    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>Directory History</h1>`)
 
-            // Add sort indicator
-            var header = table.querySelectorAll('th')[columnIndex];
-            var indicator = header.querySelector('.sort-indicator') || document.createElement('span');
-            indicator.className = 'sort-indicator active';
-            indicator.textContent = '↓';
-            header.appendChild(indicator);
-        }
+	// Summary section
+	sb.WriteString(fmt.Sprintf(`
+        <div class="summary">
+            <div class="summary-item">
+                <span class="summary-value">%d</span>
+                <span class="summary-label">Runs of %s</span>
+            </div>
+            <div class="summary-item">
+                <span class="summary-value">%s</span>
+                <span class="summary-label">Total Size Growth</span>
+            </div>`, len(result.Points), html.EscapeString(result.Root), formatGrowthRate(result.GrowthPerDay)))
+	if p := result.Projection; p != nil {
+		sb.WriteString(fmt.Sprintf(`
+            <div class="summary-item">
+                <span class="summary-value">%s</span>
+                <span class="summary-label">Free of %s, %s</span>
+            </div>`, formatSize(int64(p.AvailableBytes)), formatSize(int64(p.TotalBytes)), html.EscapeString(formatProjection(p))))
+	}
+	sb.WriteString(`
+        </div>`)
+
+	// Trend charts
+	sizes, free := historyTrends(result.Points)
+	sb.WriteString(f.generateTrendChartHTML("Total Size", sizes))
+	sb.WriteString(f.generateTrendChartHTML("Filesystem Free Space", free))
+
+	// Fastest growing directories
+	if len(result.Directories) > 0 {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Fastest Growing Directories</h2>
+            <table id="growth-table">
+                <thead>
+                    <tr>
+                        <th>Path</th>
+                        <th class="size-col">First Size</th>
+                        <th class="size-col">Last Size</th>
+                        <th class="size-col">Growth</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+		for _, dir := range result.Directories {
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td class="size-col">%s</td>
+                        <td class="size-col">%s</td>
+                        <td class="size-col">%s</td>
+                    </tr>`, html.EscapeString(dir.Path), formatSize(dir.FirstSize), formatSize(dir.LastSize), formatGrowthRate(dir.GrowthPerDay)))
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	// Recorded runs
+	if len(result.Points) > 0 {
+		sb.WriteString(`
+        <div class="section">
+            <h2>Runs</h2>
+            <table id="runs-table">
+                <thead>
+                    <tr>
+                        <th>Time</th>
+                        <th class="count-col">Files</th>
+                        <th class="size-col">Size</th>
+                        <th class="size-col">Free</th>
+                    </tr>
+                </thead>
+                <tbody>`)
+
+		for _, point := range result.Points {
+			free := "-"
+			if point.AvailableBytes > 0 {
+				free = formatSize(int64(point.AvailableBytes))
+			}
+			sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td>%s</td>
+                        <td class="count-col">%d</td>
+                        <td class="size-col">%s</td>
+                        <td class="size-col">%s</td>
+                    </tr>`, html.EscapeString(point.Time), point.TotalFiles, formatSize(point.TotalSize), free))
+		}
+
+		sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+	}
+
+	sb.WriteString(`
+    </div>`)
+
+	// Add footer with branding
+	if result.Metadata != nil {
+		flags := []string{}
+		for _, f := range result.Metadata.Flags {
+			flags = append(flags, fmt.Sprintf("%s=%s", f.Name, f.Value))
+		}
+		flagStr := strings.Join(flags, ", ")
+		sb.WriteString(fmt.Sprintf(`
+    <footer style="text-align: center; margin-top: 40px; color: #666; font-size: 14px;">
+        Generated by %s %s v%s on %s<br>
+        Flags: %s
+    </footer>`,
+			html.EscapeString(result.Metadata.ToolName),
+			html.EscapeString(result.Metadata.SubCommand),
+			html.EscapeString(result.Metadata.Version),
+			html.EscapeString(result.Metadata.GeneratedAt),
+			html.EscapeString(flagStr)))
+	}
+
+	sb.WriteString(`
+    <script>
+` + sortableTablesJS + `
+
+        // Initialize sortable tables
+        document.addEventListener('DOMContentLoaded', function() {
+            makeTableSortable('growth-table');
+            makeTableSortable('runs-table');
+        });
+    </script>
+</body>
+</html>`)
+
+	return sb.String()
+}
+
+// generateRulesHTML creates the threshold rule violations section, if any rules were checked
+func (f *HTMLFormatter) generateRulesHTML(report *RuleReport) string {
+	if report == nil {
+		return ""
+	}
+
+	if len(report.Violations) == 0 {
+		return fmt.Sprintf(`
+        <div class="section">
+            <h2>Rule Violations</h2>
+            <p class="rule-passed">All %d rules passed.</p>
+        </div>`, report.Checked)
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`
+        <div class="section">
+            <h2>Rule Violations</h2>
+            <p class="rule-violated">%d of %d rules violated.</p>
+            <table id="rules-table">
+                <thead>
+                    <tr>
+                        <th>Rule</th>
+                        <th>Value</th>
+                        <th>Matching Files</th>
+                    </tr>
+                </thead>
+                <tbody>`, len(report.Violations), report.Checked))
+
+	for _, violation := range report.Violations {
+		matches := make([]string, 0, len(violation.Matches)+1)
+		for _, match := range violation.Matches {
+			matches = append(matches, html.EscapeString(match))
+		}
+		if more := violation.MatchCount - len(violation.Matches); more > 0 {
+			matches = append(matches, fmt.Sprintf("... and %d more", more))
+		}
+		sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="rule-violated">%s</td>
+                        <td>%s</td>
+                        <td class="file-path">%s</td>
+                    </tr>`, html.EscapeString(violation.Rule), html.EscapeString(formatRuleValue(violation)), strings.Join(matches, "<br>")))
+	}
+
+	sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+
+	return sb.String()
+}
+
+// generateGitCountsRowHTML creates a row of the git status table
+func (f *HTMLFormatter) generateGitCountsRowHTML(name string, counts GitCounts) string {
+	return fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td class="size-col">%s (%d)</td>
+                        <td class="size-col">%s (%d)</td>
+                        <td class="size-col">%s (%d)</td>
+                        <td class="size-col">%s (%d)</td>
+                    </tr>`, html.EscapeString(name),
+		formatSize(counts.TrackedSize), counts.TrackedFiles,
+		formatSize(counts.UntrackedSize), counts.UntrackedFiles,
+		formatSize(counts.IgnoredSize), counts.IgnoredFiles,
+		formatSize(counts.GitDirSize), counts.GitDirFiles)
+}
+
+// generateFileTypeDetailsHTML creates the hidden drill-down row of a file type
+func (f *HTMLFormatter) generateFileTypeDetailsHTML(ft FileType) string {
+	var sb strings.Builder
+
+	sb.WriteString(`
+                    <tr class="details-row" hidden>
+                        <td colspan="4">
+                            <div class="type-details">
+                                <div>
+                                    <h3>Largest Files</h3>
+                                    <ul>`)
+	for _, file := range ft.LargestFiles {
+		sb.WriteString(fmt.Sprintf(`
+                                        <li><span class="file-path">%s</span> (%s)</li>`, html.EscapeString(file.Path), formatSize(file.Size)))
+	}
+	sb.WriteString(`
+                                    </ul>
+                                </div>
+                                <div>
+                                    <h3>Directories</h3>
+                                    <ul>`)
+	for _, dir := range ft.Directories {
+		sb.WriteString(fmt.Sprintf(`
+                                        <li><span class="file-path">%s</span> (%d files, %s, %.2f%%)</li>`, html.EscapeString(dir.Path), dir.FileCount, formatSize(dir.TotalSize), dir.Percentage))
+	}
+	sb.WriteString(`
+                                    </ul>
+                                </div>
+                            </div>
+                        </td>
+                    </tr>`)
+
+	return sb.String()
+}
+
+// generateEntryHotspotsHTML creates a section with directories ranked by entry count
+func (f *HTMLFormatter) generateEntryHotspotsHTML(title, tableID string, entries []DirectoryEntries) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`
+        <div class="section">
+            <h2>%s</h2>
+            <table id="%s">
+                <thead>
+                    <tr>
+                        <th>Path</th>
+                        <th class="count-col">Direct Entries</th>
+                        <th class="count-col">Cumulative Entries</th>
+                    </tr>
+                </thead>
+                <tbody>`, title, tableID))
+
+	for _, entry := range entries {
+		sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td class="file-path">%s</td>
+                        <td class="count-col">%d</td>
+                        <td class="count-col">%d</td>
+                    </tr>`, html.EscapeString(entry.Path), entry.DirectEntries, entry.CumulativeEntries))
+	}
+
+	sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
+
+	return sb.String()
+}
+
+// generateOwnersHTML creates a section with an owner or group breakdown table
+func (f *HTMLFormatter) generateOwnersHTML(title, tableID string, owners []OwnerInfo) string {
+	if len(owners) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`
+        <div class="section">
+            <h2>%s</h2>
+            <table id="%s">
+                <thead>
+                    <tr>
+                        <th>Name</th>
+                        <th class="count-col">Files</th>
+                        <th class="size-col">Size</th>
+                        <th class="percentage-col">Percentage</th>
+                    </tr>
+                </thead>
+                <tbody>`, title, tableID))
+
+	for _, owner := range owners {
+		sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td title="ID %s">%s</td>
+                        <td class="count-col">%d</td>
+                        <td class="size-col">%s</td>
+                        <td class="percentage-col">%.2f%%<span class="percentage-bar"><span class="percentage-fill" style="width: %.1f%%"></span></span></td>
+                    </tr>`, html.EscapeString(owner.ID), html.EscapeString(owner.Name), owner.FileCount, formatSize(owner.TotalSize), owner.Percentage, owner.Percentage))
+	}
 
-        // Initialize sortable tables
-        document.addEventListener('DOMContentLoaded', function() {
-            makeTableSortable('file-types-table');
-            makeTableSortable('directories-table');
-        });
-    </script>
-</body>
-</html>`)
+	sb.WriteString(`
+                </tbody>
+            </table>
+        </div>`)
 
 	return sb.String()
 }
@@ -836,6 +1573,10 @@ func (f *HTMLFormatter) generateHTMLRename(result *RenameResult) string {
             color: #dc3545;
             font-weight: bold;
         }
+        .rolled-back {
+            color: #856404;
+            font-weight: bold;
+        }
         .footer {
             margin-top: 40px;
             padding-top: 20px;
@@ -875,6 +1616,9 @@ func (f *HTMLFormatter) generateHTMLRename(result *RenameResult) string {
 	if result.DryRun {
 		sb.WriteString("<div class=\"dry-run\">DRY RUN - No files were actually renamed</div>")
 	}
+	if result.RolledBack {
+		sb.WriteString("<div class=\"dry-run\">ROLLED BACK - A rename failed and the renames already done were reverted</div>")
+	}
 
 	// Operations table
 	if len(result.Operations) > 0 {
@@ -896,6 +1640,21 @@ func (f *HTMLFormatter) generateHTMLRename(result *RenameResult) string {
 				status = fmt.Sprintf("ERROR: %s", html.EscapeString(op.Error))
 				statusClass = "error"
 			}
+			if op.Overwrite {
+				status += "<br>OVERWRITES EXISTING FILE"
+			}
+			switch op.Rollback {
+			case RollbackDone:
+				if op.Error == "" {
+					status = "ROLLED BACK"
+					statusClass = "rolled-back"
+				} else {
+					status += "<br>ROLLED BACK"
+				}
+			case RollbackFailed:
+				status += fmt.Sprintf("<br>ROLLBACK FAILED: %s", html.EscapeString(op.RollbackError))
+				statusClass = "error"
+			}
 
 			sb.WriteString(fmt.Sprintf(`
                 <tr>
@@ -916,6 +1675,10 @@ func (f *HTMLFormatter) generateHTMLRename(result *RenameResult) string {
 		sb.WriteString("<p>No files matched the pattern.</p>")
 	}
 
+	if result.Journal != "" {
+		sb.WriteString(fmt.Sprintf("<p><strong>Undo journal:</strong> %s</p>", html.EscapeString(result.Journal)))
+	}
+
 	// Exclusions
 	if len(result.Exclusions) > 0 {
 		sb.WriteString(`
@@ -955,3 +1718,276 @@ func (f *HTMLFormatter) generateHTMLRename(result *RenameResult) string {
 
 	return sb.String()
 }
+
+// dirStatCSS holds the stylesheet shared by the directory statistics reports
+const dirStatCSS = `        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+            margin: 0;
+            padding: 20px;
+            background-color: #f5f5f5;
+        }
+        .container {
+            max-width: 1400px;
+            margin: 0 auto;
+            background: white;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0,0,0,0.1);
+            padding: 30px;
+        }
+        h1 {
+            color: #333;
+            border-bottom: 2px solid #007acc;
+            padding-bottom: 10px;
+        }
+        .summary {
+            background: #e9ecef;
+            padding: 20px;
+            border-radius: 6px;
+            margin-bottom: 30px;
+            display: grid;
+            grid-template-columns: repeat(auto-fit, minmax(200px, 1fr));
+            gap: 20px;
+        }
+        .summary-item {
+            text-align: center;
+        }
+        .summary-value {
+            font-size: 24px;
+            font-weight: bold;
+            color: #007acc;
+            display: block;
+        }
+        .summary-label {
+            font-size: 14px;
+            color: #666;
+            margin-top: 5px;
+        }
+        .section {
+            margin-bottom: 40px;
+        }
+        .section h2 {
+            color: #333;
+            border-bottom: 1px solid #ddd;
+            padding-bottom: 8px;
+            margin-bottom: 20px;
+        }
+        table {
+            width: 100%;
+            border-collapse: collapse;
+            margin-bottom: 20px;
+            background: white;
+            border-radius: 6px;
+            overflow: hidden;
+            box-shadow: 0 1px 3px rgba(0,0,0,0.1);
+        }
+        th, td {
+            padding: 12px 15px;
+            text-align: left;
+            border-bottom: 1px solid #eee;
+        }
+        th {
+            background: #f8f9fa;
+            font-weight: 600;
+            color: #333;
+            position: sticky;
+            top: 0;
+        }
+        tr:hover {
+            background-color: #f8f9fa;
+        }
+        .size-col {
+            text-align: right;
+            font-family: monospace;
+        }
+        .count-col {
+            text-align: right;
+        }
+        .percentage-col {
+            text-align: right;
+        }
+        .percentage-bar {
+            display: inline-block;
+            height: 8px;
+            background: #e9ecef;
+            border-radius: 4px;
+            width: 60px;
+            margin-left: 10px;
+            vertical-align: middle;
+        }
+        .percentage-fill {
+            display: block;
+            height: 100%;
+            background: #007acc;
+            border-radius: 4px;
+        }
+        .file-path {
+            font-family: monospace;
+            word-break: break-all;
+        }
+        .no-data {
+            text-align: center;
+            color: #666;
+            padding: 40px;
+            font-style: italic;
+        }
+        .sort-indicator {
+            margin-left: 5px;
+            opacity: 0.5;
+        }
+        .sort-indicator.active {
+            opacity: 1;
+        }
+        .exclusions-section {
+            margin-top: 40px;
+            border-top: 1px solid #ddd;
+            padding-top: 20px;
+        }
+        .exclusions-section h2 {
+            color: #333;
+            margin-bottom: 20px;
+        }
+        .exclusions-table {
+            width: 100%;
+            border-collapse: collapse;
+            margin-top: 10px;
+        }
+        .exclusions-table th,
+        .exclusions-table td {
+            border: 1px solid #ddd;
+            padding: 8px 12px;
+            text-align: left;
+        }
+        .exclusions-table th {
+            background-color: #f8f9fa;
+            font-weight: 600;
+        }
+        .exclusions-table tr:nth-child(even) {
+            background-color: #f8f9fa;
+        }
+        .exclusions-table tr:hover {
+            background-color: #e9ecef;
+        }
+        .status-added, .status-grown {
+            color: #dc3545;
+            font-weight: bold;
+        }
+        .status-removed, .status-shrunk {
+            color: #28a745;
+            font-weight: bold;
+        }
+        .status-changed {
+            color: #856404;
+        }
+        .rule-violated {
+            color: #dc3545;
+            font-weight: bold;
+        }
+        .rule-passed {
+            color: #28a745;
+            font-weight: bold;
+        }
+        .expandable {
+            cursor: pointer;
+        }
+        .expandable td:first-child::before {
+            content: '\25B8';
+            display: inline-block;
+            width: 1em;
+            color: #666;
+        }
+        .expandable.expanded td:first-child::before {
+            content: '\25BE';
+        }
+        .details-row td {
+            background: #fafbfc;
+        }
+        .type-details {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 30px;
+        }
+        .type-details h3 {
+            margin: 5px 0;
+            font-size: 14px;
+            color: #333;
+        }
+        .type-details ul {
+            margin: 0;
+            padding-left: 20px;
+        }
+`
+
+// sortableTablesJS provides click-to-sort behaviour for report tables
+const sortableTablesJS = `        // Table sorting functionality
+        function makeTableSortable(tableId) {
+            var table = document.getElementById(tableId);
+            if (!table) return;
+
+            var headers = table.querySelectorAll('th');
+            headers.forEach(function(header, index) {
+                header.style.cursor = 'pointer';
+                header.addEventListener('click', function() {
+                    sortTable(table, index);
+                });
+            });
+        }
+
+        function sortTable(table, columnIndex) {
+            var tbody = table.querySelector('tbody');
+            var rows = Array.from(tbody.children).filter(function(row) {
+                return !row.classList.contains('details-row');
+            });
+
+            // Expandable rows keep their details row directly below them
+            rows.forEach(function(row) {
+                var next = row.nextElementSibling;
+                row.detailsRow = next && next.classList.contains('details-row') ? next : null;
+            });
+
+            // Remove existing sort indicators
+            table.querySelectorAll('.sort-indicator').forEach(function(indicator) {
+                indicator.classList.remove('active');
+            });
+
+            // Determine sort direction
+            var isNumeric = table.rows[0].cells[columnIndex].classList.contains('count-col') ||
+                           table.rows[0].cells[columnIndex].classList.contains('size-col') ||
+                           table.rows[0].cells[columnIndex].classList.contains('percentage-col');
+
+            rows.sort(function(a, b) {
+                var aVal = a.cells[columnIndex].textContent.trim();
+                var bVal = b.cells[columnIndex].textContent.trim();
+
+                if (isNumeric) {
+                    // Extract numeric value (remove units like %, B, KB, etc.)
+                    var aNum = parseFloat(aVal.replace(/[^\d.]/g, '')) || 0;
+                    var bNum = parseFloat(bVal.replace(/[^\d.]/g, '')) || 0;
+                    return bNum - aNum; // Descending for numeric
+                } else {
+                    return aVal.localeCompare(bVal);
+                }
+            });
+
+            // Re-append sorted rows
+            rows.forEach(function(row) {
+                tbody.appendChild(row);
+                if (row.detailsRow) {
+                    tbody.appendChild(row.detailsRow);
+                }
+            });
+
+            // Add sort indicator
+            var header = table.querySelectorAll('th')[columnIndex];
+            var indicator = header.querySelector('.sort-indicator') || document.createElement('span');
+            indicator.className = 'sort-indicator active';
+            indicator.textContent = '↓';
+            header.appendChild(indicator);
+        }
+
+        // Show or hide the details row below an expandable row
+        function toggleDetails(row) {
+            var details = row.nextElementSibling;
+            if (!details || !details.classList.contains('details-row')) return;
+            details.hidden = !details.hidden;
+            row.classList.toggle('expanded', !details.hidden);
+        }`
@@ -28,3 +28,17 @@ func (f *JSONFormatter) FormatRename(result *RenameResult, writer io.Writer) err
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(result)
 }
+
+// FormatDirStatDiff formats a directory statistics comparison as JSON
+func (f *JSONFormatter) FormatDirStatDiff(result *DirStatDiffResult, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// FormatDirStatHistory formats directory growth trends as JSON
+func (f *JSONFormatter) FormatDirStatHistory(result *DirStatHistoryResult, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
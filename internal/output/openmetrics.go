@@ -0,0 +1,398 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// OpenMetricsFormatter implements the OutputFormatter interface for the
+// OpenMetrics text exposition format, as read by Prometheus and the
+// node_exporter textfile collector
+type OpenMetricsFormatter struct{}
+
+// metricLabel is a label name and value attached to a sample
+type metricLabel struct {
+	name  string
+	value string
+}
+
+// metricsWriter writes metric families one at a time, so that the samples of
+// a family are always grouped under their metadata as OpenMetrics requires
+type metricsWriter struct {
+	w      *bufio.Writer
+	prefix string
+	common []metricLabel // Labels added to every sample
+}
+
+// newMetricsWriter creates a metricsWriter for metrics named prefix_*
+func newMetricsWriter(writer io.Writer, prefix string, common ...metricLabel) *metricsWriter {
+	return &metricsWriter{w: bufio.NewWriter(writer), prefix: prefix, common: common}
+}
+
+// family writes the metadata of a gauge family. unit is empty for
+// dimensionless gauges, otherwise it must be the suffix of the name.
+func (m *metricsWriter) family(name, unit, help string) {
+	name = m.prefix + "_" + name
+	fmt.Fprintf(m.w, "# TYPE %s gauge\n", name)
+	if unit != "" {
+		fmt.Fprintf(m.w, "# UNIT %s %s\n", name, unit)
+	}
+	fmt.Fprintf(m.w, "# HELP %s %s\n", name, escapeMetricText(help, false))
+}
+
+// sample writes a sample of the family written last
+func (m *metricsWriter) sample(name string, value string, labels ...metricLabel) {
+	m.w.WriteString(m.prefix + "_" + name)
+
+	all := append(append([]metricLabel{}, m.common...), labels...)
+	if len(all) > 0 {
+		m.w.WriteByte('{')
+		for i, label := range all {
+			if i > 0 {
+				m.w.WriteByte(',')
+			}
+			fmt.Fprintf(m.w, `%s="%s"`, label.name, escapeMetricText(label.value, true))
+		}
+		m.w.WriteByte('}')
+	}
+
+	m.w.WriteByte(' ')
+	m.w.WriteString(value)
+	m.w.WriteByte('\n')
+}
+
+// gauge writes a family with a single unlabelled sample
+func (m *metricsWriter) gauge(name, unit, help string, value int64) {
+	m.family(name, unit, help)
+	m.sample(name, intValue(value))
+}
+
+// finish terminates the exposition and flushes it
+func (m *metricsWriter) finish() error {
+	m.w.WriteString("# EOF\n")
+	return m.w.Flush()
+}
+
+// escapeMetricText escapes backslashes and newlines, and double quotes in label
+// values. Bytes that are not valid UTF-8 are written as the text \xNN, so
+// that names differing in such bytes stay distinct.
+func escapeMetricText(text string, quoted bool) string {
+	var sb strings.Builder
+	for i, r := range text {
+		switch {
+		case r == utf8.RuneError && !strings.HasPrefix(text[i:], "\uFFFD"):
+			fmt.Fprintf(&sb, `\\x%02x`, text[i])
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '"' && quoted:
+			sb.WriteString(`\"`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// intValue formats an integer sample value
+func intValue(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+// floatValue formats a floating point sample value
+func floatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// label creates a metricLabel
+func label(name, value string) metricLabel {
+	return metricLabel{name: name, value: value}
+}
+
+// writeGeneratedAt writes the time the result was generated, useful to alert on stale textfiles
+func (m *metricsWriter) writeGeneratedAt(metadata *Metadata) {
+	if metadata == nil {
+		return
+	}
+	generated, err := time.Parse(time.RFC3339, metadata.GeneratedAt)
+	if err != nil {
+		return
+	}
+	m.family("generated_timestamp_seconds", "seconds", "Time the statistics were generated, in seconds since the epoch.")
+	m.sample("generated_timestamp_seconds", intValue(generated.Unix()))
+}
+
+// FormatDuplicates formats duplicate results as OpenMetrics gauges
+func (f *OpenMetricsFormatter) FormatDuplicates(result *DuplicateResult, writer io.Writer) error {
+	m := newMetricsWriter(writer, "filetools_dupfind")
+
+	var files, wasted int64
+	for _, group := range result.Groups {
+		files += int64(len(group.Files))
+		if len(group.Files) > 1 {
+			wasted += group.Size * int64(len(group.Files)-1)
+		}
+	}
+
+	m.gauge("duplicate_groups", "", "Number of groups of identical files.", int64(len(result.Groups)))
+	m.gauge("duplicate_files", "", "Number of files belonging to a duplicate group.", files)
+	m.gauge("wasted_bytes", "bytes", "Bytes that removing all but one file of each group would free.", wasted)
+	m.gauge("excluded", "", "Number of excluded files and directories.", int64(len(result.Exclusions)))
+	m.writeGeneratedAt(result.Metadata)
+
+	return m.finish()
+}
+
+// FormatDirStat formats directory statistics as OpenMetrics gauges
+func (f *OpenMetricsFormatter) FormatDirStat(result *DirStatResult, writer io.Writer) error {
+	m := newMetricsWriter(writer, "filetools_dirstat", label("root", result.Root))
+
+	m.gauge("files", "", "Number of files in the tree.", int64(result.TotalFiles))
+	m.gauge("size_bytes", "bytes", "Total size of the files in the tree.", result.TotalSize)
+	m.gauge("inodes", "", "Number of inodes used by the tree, hard links counted once.", int64(result.TotalInodes))
+	if result.LargestFile != nil {
+		m.family("largest_file_bytes", "bytes", "Size of the largest file in the tree.")
+		m.sample("largest_file_bytes", intValue(result.LargestFile.Size), label("path", result.LargestFile.Path))
+	}
+
+	if len(result.EntryTypes) > 0 {
+		m.family("entries", "", "Number of entries in the tree per file type, excluding the root.")
+		for _, entryType := range result.EntryTypes {
+			m.sample("entries", intValue(int64(entryType.Count)), label("type", entryType.Type))
+		}
+	}
+
+	if len(result.FileTypes) > 0 {
+		m.family("extension_files", "", "Number of files per file extension.")
+		for _, ft := range result.FileTypes {
+			m.sample("extension_files", intValue(int64(ft.Count)), label("extension", ft.Extension))
+		}
+		m.family("extension_size_bytes", "bytes", "Total size of files per file extension.")
+		for _, ft := range result.FileTypes {
+			m.sample("extension_size_bytes", intValue(ft.TotalSize), label("extension", ft.Extension))
+		}
+	}
+
+	// Files stored directly in the root are reported as directory "."
+	directories := result.Directories
+	rootFiles, rootSize := result.TotalFiles, result.TotalSize
+	for _, dir := range directories {
+		rootFiles -= dir.FileCount
+		rootSize -= dir.TotalSize
+	}
+	if rootFiles > 0 {
+		directories = append([]DirectoryInfo{{Path: ".", FileCount: rootFiles, TotalSize: rootSize}}, directories...)
+	}
+	if len(directories) > 0 {
+		m.family("directory_files", "", "Number of files stored directly in each directory.")
+		for _, dir := range directories {
+			m.sample("directory_files", intValue(int64(dir.FileCount)), label("directory", dir.Path))
+		}
+		m.family("directory_size_bytes", "bytes", "Total size of the files stored directly in each directory.")
+		for _, dir := range directories {
+			m.sample("directory_size_bytes", intValue(dir.TotalSize), label("directory", dir.Path))
+		}
+	}
+
+	if len(result.ContentCategories) > 0 {
+		m.family("content_category_size_bytes", "bytes", "Total size of files per detected content category.")
+		for _, cc := range result.ContentCategories {
+			m.sample("content_category_size_bytes", intValue(cc.TotalSize), label("category", cc.Category))
+		}
+	}
+	if len(result.Categories) > 0 {
+		m.family("category_files", "", "Number of files per user-defined category.")
+		for _, category := range result.Categories {
+			m.sample("category_files", intValue(int64(category.Count)), label("category", category.Name))
+		}
+		m.family("category_size_bytes", "bytes", "Total size of files per user-defined category.")
+		for _, category := range result.Categories {
+			m.sample("category_size_bytes", intValue(category.TotalSize), label("category", category.Name))
+		}
+	}
+
+	if len(result.Owners) > 0 {
+		m.family("owner_size_bytes", "bytes", "Total size of files per owning user.")
+		for _, owner := range result.Owners {
+			m.sample("owner_size_bytes", intValue(owner.TotalSize), label("owner", owner.Name))
+		}
+	}
+	if len(result.Groups) > 0 {
+		m.family("group_size_bytes", "bytes", "Total size of files per owning group.")
+		for _, group := range result.Groups {
+			m.sample("group_size_bytes", intValue(group.TotalSize), label("group", group.Name))
+		}
+	}
+
+	m.gauge("empty_directories", "", "Number of empty directories.", int64(len(result.EmptyDirectories)))
+	m.gauge("empty_files", "", "Number of zero-byte files.", int64(len(result.EmptyFiles)))
+	m.gauge("broken_symlinks", "", "Number of symlinks whose target does not exist.", int64(len(result.BrokenSymlinks)))
+	m.gauge("external_symlinks", "", "Number of symlinks pointing outside the tree.", int64(len(result.ExternalSymlinks)))
+	m.gauge("excluded", "", "Number of excluded files and directories.", int64(len(result.Exclusions)))
+
+	if fs := result.Filesystem; fs != nil {
+		m.gauge("filesystem_size_bytes", "bytes", "Size of the filesystem containing the tree.", int64(fs.TotalBytes))
+		m.gauge("filesystem_avail_bytes", "bytes", "Space on the filesystem available to unprivileged users.", int64(fs.AvailableBytes))
+		if fs.TotalInodes > 0 {
+			m.gauge("filesystem_inodes", "", "Number of inodes of the filesystem.", int64(fs.TotalInodes))
+			m.gauge("filesystem_free_inodes", "", "Number of free inodes of the filesystem.", int64(fs.FreeInodes))
+		}
+	}
+
+	if g := result.Git; g != nil && len(g.Repositories) > 0 {
+		m.family("git_size_bytes", "bytes", "Total size of files in git worktrees by status.")
+		for _, repo := range g.Repositories {
+			worktree := label("worktree", repo.Path)
+			m.sample("git_size_bytes", intValue(repo.TrackedSize), worktree, label("status", "tracked"))
+			m.sample("git_size_bytes", intValue(repo.UntrackedSize), worktree, label("status", "untracked"))
+			m.sample("git_size_bytes", intValue(repo.IgnoredSize), worktree, label("status", "ignored"))
+			m.sample("git_size_bytes", intValue(repo.GitDirSize), worktree, label("status", "git_dir"))
+		}
+	}
+
+	if x := result.Xattrs; x != nil {
+		m.gauge("xattr_files", "", "Number of files carrying extended attributes.", int64(x.FilesWithXattrs))
+		m.gauge("acl_files", "", "Number of files carrying POSIX ACLs.", int64(x.FilesWithACLs))
+		m.gauge("xattr_size_bytes", "bytes", "Total size of extended attribute names and values.", x.TotalBytes)
+	}
+
+	if c := result.Compression; c != nil {
+		m.gauge("compressed_size_bytes", "bytes", "Estimated total size of the files after compression.", c.CompressedSize)
+	}
+	if c := result.Compression; c != nil && len(c.FileTypes) > 0 {
+		m.family("compression_ratio", "", "Estimated compression ratio per file extension.")
+		for _, ft := range c.FileTypes {
+			m.sample("compression_ratio", floatValue(ft.CompressionRatio), label("extension", ft.Extension))
+		}
+	}
+
+	writeRuleMetrics(m, result.Rules)
+	m.writeGeneratedAt(result.Metadata)
+
+	return m.finish()
+}
+
+// writeRuleMetrics writes the outcome of the threshold rules, if any were checked
+func writeRuleMetrics(m *metricsWriter, report *RuleReport) {
+	if report == nil {
+		return
+	}
+	m.gauge("rules", "", "Number of threshold rules checked.", int64(report.Checked))
+	m.gauge("rule_violations", "", "Number of threshold rules violated.", int64(len(report.Violations)))
+	if len(report.Violations) > 0 {
+		m.family("rule_violation_value", "", "Measured value of each violated rule, or its number of matching files.")
+		// Rules listed twice measure the same value, and must not give two
+		// series with the same labels
+		seen := make(map[string]bool, len(report.Violations))
+		for _, violation := range report.Violations {
+			if key := escapeMetricText(violation.Rule, true); !seen[key] {
+				seen[key] = true
+				m.sample("rule_violation_value", intValue(violation.Value), label("rule", violation.Rule))
+			}
+		}
+	}
+}
+
+// FormatRename formats rename results as OpenMetrics gauges
+func (f *OpenMetricsFormatter) FormatRename(result *RenameResult, writer io.Writer) error {
+	m := newMetricsWriter(writer, "filetools_rename")
+
+	var failed, rolledBack, rollbackFailed int64
+	for _, op := range result.Operations {
+		switch {
+		case op.Rollback == RollbackFailed:
+			rollbackFailed++
+		case op.Rollback == RollbackDone:
+			rolledBack++
+		case op.Error != "":
+			failed++
+		}
+	}
+	ok := int64(len(result.Operations)) - failed - rolledBack - rollbackFailed
+
+	dryRun := int64(0)
+	if result.DryRun {
+		dryRun = 1
+	}
+	reverted := int64(0)
+	if result.RolledBack {
+		reverted = 1
+	}
+
+	m.family("operations", "", "Number of planned renames by outcome.")
+	m.sample("operations", intValue(ok), label("status", "ok"))
+	m.sample("operations", intValue(failed), label("status", "error"))
+	m.sample("operations", intValue(rolledBack), label("status", "rolled_back"))
+	m.sample("operations", intValue(rollbackFailed), label("status", "rollback_failed"))
+	m.gauge("dry_run", "", "Whether the renames were only planned (1) or performed (0).", dryRun)
+	m.gauge("rolled_back", "", "Whether an atomic run failed and reverted its renames (1) or not (0).", reverted)
+	m.gauge("excluded", "", "Number of excluded files and directories.", int64(len(result.Exclusions)))
+	m.writeGeneratedAt(result.Metadata)
+
+	return m.finish()
+}
+
+// FormatDirStatDiff formats a directory statistics comparison as OpenMetrics gauges
+func (f *OpenMetricsFormatter) FormatDirStatDiff(result *DirStatDiffResult, writer io.Writer) error {
+	m := newMetricsWriter(writer, "filetools_dirstat_diff")
+
+	m.gauge("files_delta", "", "Change in the number of files since the snapshot.", int64(result.NewTotalFiles-result.OldTotalFiles))
+	m.gauge("size_delta_bytes", "bytes", "Change in total size since the snapshot.", result.NewTotalSize-result.OldTotalSize)
+
+	if len(result.Directories) > 0 {
+		m.family("directory_size_delta_bytes", "bytes", "Change in size of each changed directory since the snapshot.")
+		for _, entry := range result.Directories {
+			m.sample("directory_size_delta_bytes", intValue(entry.SizeDelta), label("directory", entry.Key), label("status", entry.Status))
+		}
+	}
+	if len(result.FileTypes) > 0 {
+		m.family("extension_size_delta_bytes", "bytes", "Change in size of each changed file extension since the snapshot.")
+		for _, entry := range result.FileTypes {
+			m.sample("extension_size_delta_bytes", intValue(entry.SizeDelta), label("extension", entry.Key), label("status", entry.Status))
+		}
+	}
+
+	writeRuleMetrics(m, result.Rules)
+	m.writeGeneratedAt(result.Metadata)
+
+	return m.finish()
+}
+
+// FormatDirStatHistory formats directory growth trends as OpenMetrics gauges
+func (f *OpenMetricsFormatter) FormatDirStatHistory(result *DirStatHistoryResult, writer io.Writer) error {
+	m := newMetricsWriter(writer, "filetools_dirstat_history", label("root", result.Root))
+
+	m.gauge("runs", "", "Number of recorded dirstat runs.", int64(len(result.Points)))
+	m.family("size_growth_bytes_per_day", "", "Growth of the total size of the tree in bytes per day.")
+	m.sample("size_growth_bytes_per_day", floatValue(result.GrowthPerDay))
+
+	if len(result.Directories) > 0 {
+		m.family("directory_growth_bytes_per_day", "", "Growth of the files stored directly in each directory in bytes per day.")
+		for _, dir := range result.Directories {
+			m.sample("directory_growth_bytes_per_day", floatValue(dir.GrowthPerDay), label("directory", dir.Path))
+		}
+	}
+
+	if p := result.Projection; p != nil {
+		m.family("filesystem_consumed_bytes_per_day", "", "Free space of the filesystem consumed per day, negative when space is freed.")
+		m.sample("filesystem_consumed_bytes_per_day", floatValue(p.ConsumedPerDay))
+		if p.Filling {
+			m.family("filesystem_days_until_full", "", "Days until the filesystem is full at the current rate.")
+			m.sample("filesystem_days_until_full", floatValue(p.DaysUntilFull))
+		}
+		if fullAt, err := time.Parse(time.RFC3339, p.FullAt); err == nil {
+			m.family("filesystem_full_timestamp_seconds", "seconds", "Projected time the filesystem is full, in seconds since the epoch.")
+			m.sample("filesystem_full_timestamp_seconds", intValue(fullAt.Unix()))
+		}
+	}
+
+	m.writeGeneratedAt(result.Metadata)
+
+	return m.finish()
+}
@@ -3,6 +3,7 @@ package output
 import (
 	"fmt"
 	"io"
+	"math"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -96,8 +97,23 @@ func (f *TextFormatter) FormatDirStat(result *DirStatResult, writer io.Writer) e
 	if result.LargestFile != nil {
 		fmt.Fprintf(writer, "Largest File: %s (%s)\n", result.LargestFile.Path, formatSize(result.LargestFile.Size))
 	}
+	fmt.Fprintf(writer, "Empty Directories: %d\n", len(result.EmptyDirectories))
+	fmt.Fprintf(writer, "Empty Files: %d\n", len(result.EmptyFiles))
+	fmt.Fprintf(writer, "Broken Symlinks: %d\n", len(result.BrokenSymlinks))
+	fmt.Fprintf(writer, "External Symlinks: %d\n", len(result.ExternalSymlinks))
+	fmt.Fprintf(writer, "Special Files: %d\n", len(result.SpecialFiles))
+	fmt.Fprintf(writer, "Total Inodes: %d\n", result.TotalInodes)
+	if fs := result.Filesystem; fs != nil {
+		fmt.Fprintf(writer, "Filesystem Free Space: %s of %s\n", formatSize(int64(fs.AvailableBytes)), formatSize(int64(fs.TotalBytes)))
+		if fs.TotalInodes > 0 {
+			fmt.Fprintf(writer, "Filesystem Free Inodes: %d of %d\n", fs.FreeInodes, fs.TotalInodes)
+		}
+	}
 	fmt.Fprintln(writer)
 
+	// Threshold rule violations
+	writeRulesText(writer, result.Rules)
+
 	// File types
 	if len(result.FileTypes) > 0 {
 		fmt.Fprintf(writer, "File Types\n")
@@ -112,6 +128,135 @@ func (f *TextFormatter) FormatDirStat(result *DirStatResult, writer io.Writer) e
 		fmt.Fprintln(writer)
 	}
 
+	// Per-type drill-down
+	writeFileTypeDetailsText(writer, result.FileTypes)
+
+	// Entry types
+	if len(result.EntryTypes) > 0 {
+		fmt.Fprintf(writer, "Entry Types\n")
+		fmt.Fprintf(writer, "-----------\n")
+		fmt.Fprintf(writer, "%-15s %s\n", "Type", "Count")
+		fmt.Fprintf(writer, "%-15s %s\n", strings.Repeat("-", 15), strings.Repeat("-", 8))
+
+		for _, entryType := range result.EntryTypes {
+			fmt.Fprintf(writer, "%-15s %d\n", entryType.Type, entryType.Count)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	// User-defined categories
+	if len(result.Categories) > 0 {
+		fmt.Fprintf(writer, "Categories\n")
+		fmt.Fprintf(writer, "----------\n")
+		fmt.Fprintf(writer, "%-25s %-8s %-12s %s\n", "Category", "Count", "Size", "Percentage")
+		fmt.Fprintf(writer, "%-25s %-8s %-12s %s\n", strings.Repeat("-", 25), strings.Repeat("-", 8), strings.Repeat("-", 12), strings.Repeat("-", 10))
+
+		for _, category := range result.Categories {
+			fmt.Fprintf(writer, "%-25s %-8d %-12s %.2f%%\n",
+				category.Name, category.Count, formatSize(category.TotalSize), category.Percentage)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	// Content categories and MIME types
+	if len(result.ContentCategories) > 0 {
+		fmt.Fprintf(writer, "Content Categories\n")
+		fmt.Fprintf(writer, "------------------\n")
+		fmt.Fprintf(writer, "%-15s %-8s %-12s %s\n", "Category", "Count", "Size", "Percentage")
+		fmt.Fprintf(writer, "%-15s %-8s %-12s %s\n", strings.Repeat("-", 15), strings.Repeat("-", 8), strings.Repeat("-", 12), strings.Repeat("-", 10))
+
+		for _, cc := range result.ContentCategories {
+			fmt.Fprintf(writer, "%-15s %-8d %-12s %.2f%%\n",
+				cc.Category, cc.Count, formatSize(cc.TotalSize), cc.Percentage)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	if len(result.ContentTypes) > 0 {
+		fmt.Fprintf(writer, "Content Types\n")
+		fmt.Fprintf(writer, "-------------\n")
+		fmt.Fprintf(writer, "%-40s %-10s %-8s %-12s %s\n", "MIME Type", "Category", "Count", "Size", "Percentage")
+		fmt.Fprintf(writer, "%-40s %-10s %-8s %-12s %s\n", strings.Repeat("-", 40), strings.Repeat("-", 10), strings.Repeat("-", 8), strings.Repeat("-", 12), strings.Repeat("-", 10))
+
+		for _, ct := range result.ContentTypes {
+			mimeType := ct.MIMEType
+			if len(mimeType) > 40 {
+				mimeType = mimeType[:37] + "..."
+			}
+			fmt.Fprintf(writer, "%-40s %-10s %-8d %-12s %.2f%%\n",
+				mimeType, ct.Category, ct.Count, formatSize(ct.TotalSize), ct.Percentage)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	// Git status
+	if g := result.Git; g != nil {
+		fmt.Fprintf(writer, "Git Status\n")
+		fmt.Fprintf(writer, "----------\n")
+		if len(g.Repositories) == 0 {
+			fmt.Fprintf(writer, "No git worktrees found\n\n")
+		} else {
+			fmt.Fprintf(writer, "%-30s %-20s %-20s %-20s %s\n", "Worktree", "Tracked", "Untracked", "Ignored", ".git")
+			fmt.Fprintf(writer, "%-30s %-20s %-20s %-20s %s\n", strings.Repeat("-", 30), strings.Repeat("-", 20), strings.Repeat("-", 20), strings.Repeat("-", 20), strings.Repeat("-", 20))
+
+			for _, repo := range g.Repositories {
+				writeGitCountsText(writer, repo.Path, repo.GitCounts)
+			}
+			if len(g.Repositories) > 1 {
+				writeGitCountsText(writer, "Total", g.GitCounts)
+			}
+			fmt.Fprintln(writer)
+		}
+	}
+
+	// Compression estimate
+	if c := result.Compression; c != nil {
+		fmt.Fprintf(writer, "Compression Estimate\n")
+		fmt.Fprintf(writer, "--------------------\n")
+		fmt.Fprintf(writer, "Sample Size: %s per file\n", formatSize(c.SampleSize))
+		fmt.Fprintf(writer, "Estimated Compressed Size: %s of %s (ratio %.2fx, %.2f%% saved)\n\n",
+			formatSize(c.CompressedSize), formatSize(c.TotalSize), c.CompressionRatio, c.SavingsPercentage)
+
+		if len(c.FileTypes) > 0 {
+			fmt.Fprintf(writer, "%-15s %-8s %-12s %-12s %-8s %s\n", "Extension", "Count", "Size", "Compressed", "Ratio", "Savings")
+			fmt.Fprintf(writer, "%-15s %-8s %-12s %-12s %-8s %s\n", strings.Repeat("-", 15), strings.Repeat("-", 8), strings.Repeat("-", 12), strings.Repeat("-", 12), strings.Repeat("-", 8), strings.Repeat("-", 10))
+
+			for _, ft := range c.FileTypes {
+				fmt.Fprintf(writer, "%-15s %-8d %-12s %-12s %-8s %.2f%%\n",
+					ft.Extension, ft.Count, formatSize(ft.TotalSize), formatSize(ft.CompressedSize),
+					fmt.Sprintf("%.2fx", ft.CompressionRatio), ft.SavingsPercentage)
+			}
+			fmt.Fprintln(writer)
+		}
+	}
+
+	// Extended attributes and ACLs
+	if x := result.Xattrs; x != nil {
+		fmt.Fprintf(writer, "Extended Attributes\n")
+		fmt.Fprintf(writer, "-------------------\n")
+		fmt.Fprintf(writer, "Files with Extended Attributes: %d\n", x.FilesWithXattrs)
+		fmt.Fprintf(writer, "Files with POSIX ACLs: %d\n", x.FilesWithACLs)
+		fmt.Fprintf(writer, "Attribute Size: %s\n\n", formatSize(x.TotalBytes))
+
+		if len(x.Largest) > 0 {
+			fmt.Fprintf(writer, "%-50s %-8s %-12s %s\n", "Path", "Count", "Size", "ACL")
+			fmt.Fprintf(writer, "%-50s %-8s %-12s %s\n", strings.Repeat("-", 50), strings.Repeat("-", 8), strings.Repeat("-", 12), strings.Repeat("-", 3))
+
+			for _, file := range x.Largest {
+				path := file.Path
+				if len(path) > 47 {
+					path = "..." + path[len(path)-44:]
+				}
+				acl := "no"
+				if file.ACL {
+					acl = "yes"
+				}
+				fmt.Fprintf(writer, "%-50s %-8d %-12s %s\n", path, file.Count, formatSize(file.Bytes), acl)
+			}
+			fmt.Fprintln(writer)
+		}
+	}
+
 	// Directories
 	if len(result.Directories) > 0 {
 		fmt.Fprintf(writer, "Subdirectories\n")
@@ -127,11 +272,50 @@ func (f *TextFormatter) FormatDirStat(result *DirStatResult, writer io.Writer) e
 			fmt.Fprintf(writer, "%-50s %-8d %-12s %.2f%%\n",
 				path, dir.FileCount, formatSize(dir.TotalSize), dir.Percentage)
 		}
+		fmt.Fprintln(writer)
+	}
+
+	// Entry count hot spots
+	writeEntryHotspotsText(writer, "Directories by Direct Entries", result.DirectEntryHotspots)
+	writeEntryHotspotsText(writer, "Directories by Cumulative Entries", result.CumulativeEntryHotspots)
+
+	// Owners and groups
+	writeOwnersText(writer, "Owners", result.Owners)
+	writeOwnersText(writer, "Groups", result.Groups)
+
+	// Permission audit
+	if p := result.Permissions; p != nil && (len(p.WorldWritable)+len(p.Setuid)+len(p.Setgid)+len(p.Unreadable)) > 0 {
+		fmt.Fprintf(writer, "Permission Audit\n")
+		fmt.Fprintf(writer, "----------------\n")
+		writePathListText(writer, "World-writable", p.WorldWritable)
+		writePathListText(writer, "Setuid", p.Setuid)
+		writePathListText(writer, "Setgid", p.Setgid)
+		writePathListText(writer, "Unreadable", p.Unreadable)
+	}
+
+	// Clutter
+	if len(result.EmptyDirectories)+len(result.EmptyFiles)+len(result.BrokenSymlinks)+len(result.ExternalSymlinks) > 0 {
+		fmt.Fprintf(writer, "Clutter\n")
+		fmt.Fprintf(writer, "-------\n")
+		writePathListText(writer, "Empty directories", result.EmptyDirectories)
+		writePathListText(writer, "Empty files", result.EmptyFiles)
+		writeSymlinksText(writer, "Broken symlinks", result.BrokenSymlinks)
+		writeSymlinksText(writer, "Symlinks pointing outside the root", result.ExternalSymlinks)
+	}
+
+	// Special files
+	if len(result.SpecialFiles) > 0 {
+		fmt.Fprintf(writer, "Special Files\n")
+		fmt.Fprintf(writer, "-------------\n")
+		for _, file := range result.SpecialFiles {
+			fmt.Fprintf(writer, "- %s (%s)\n", file.Path, file.Type)
+		}
+		fmt.Fprintln(writer)
 	}
 
 	// Output exclusions if any
 	if len(result.Exclusions) > 0 {
-		fmt.Fprintln(writer, "\nExcluded files and directories:")
+		fmt.Fprintln(writer, "Excluded files and directories:")
 		for _, exclusion := range result.Exclusions {
 			fmt.Fprintf(writer, "- %s (%s)\n", exclusion.Path, exclusion.Reason)
 		}
@@ -161,6 +345,10 @@ func (f *TextFormatter) FormatRename(result *RenameResult, writer io.Writer) err
 		fmt.Fprintln(writer, "DRY RUN - No files were actually renamed")
 		fmt.Fprintln(writer)
 	}
+	if result.RolledBack {
+		fmt.Fprintln(writer, "ROLLED BACK - A rename failed and the renames already done were reverted")
+		fmt.Fprintln(writer)
+	}
 
 	if len(result.Operations) == 0 {
 		fmt.Fprintln(writer, "No files matched the pattern.")
@@ -169,11 +357,24 @@ func (f *TextFormatter) FormatRename(result *RenameResult, writer io.Writer) err
 
 	fmt.Fprintln(writer, "Rename operations:")
 	for _, op := range result.Operations {
+		line := fmt.Sprintf("- %s -> %s", op.OldPath, op.NewPath)
 		if op.Error != "" {
-			fmt.Fprintf(writer, "- %s -> %s (ERROR: %s)\n", op.OldPath, op.NewPath, op.Error)
-		} else {
-			fmt.Fprintf(writer, "- %s -> %s\n", op.OldPath, op.NewPath)
+			line += fmt.Sprintf(" (ERROR: %s)", op.Error)
 		}
+		if op.Overwrite {
+			line += " (OVERWRITES EXISTING FILE)"
+		}
+		switch op.Rollback {
+		case RollbackDone:
+			line += " (ROLLED BACK)"
+		case RollbackFailed:
+			line += fmt.Sprintf(" (ROLLBACK FAILED: %s)", op.RollbackError)
+		}
+		fmt.Fprintln(writer, line)
+	}
+
+	if result.Journal != "" {
+		fmt.Fprintf(writer, "\nUndo journal: %s\n", result.Journal)
 	}
 
 	// Output exclusions if any
@@ -187,6 +388,307 @@ func (f *TextFormatter) FormatRename(result *RenameResult, writer io.Writer) err
 	return nil
 }
 
+// FormatDirStatDiff formats a directory statistics comparison as plain text
+func (f *TextFormatter) FormatDirStatDiff(result *DirStatDiffResult, writer io.Writer) error {
+	// Add branding header
+	if result.Metadata != nil {
+		flags := []string{}
+		for _, f := range result.Metadata.Flags {
+			flags = append(flags, fmt.Sprintf("%s: %s", f.Name, f.Value))
+		}
+		flagStr := strings.Join(flags, ", ")
+		fmt.Fprintf(writer, "Generated by %s %s v%s on %s (%s)\n\n",
+			result.Metadata.ToolName,
+			result.Metadata.SubCommand,
+			result.Metadata.Version,
+			result.Metadata.GeneratedAt,
+			flagStr)
+	}
+
+	fmt.Fprintf(writer, "Directory Statistics Comparison\n")
+	fmt.Fprintf(writer, "===============================\n\n")
+	if result.SnapshotGeneratedAt != "" {
+		fmt.Fprintf(writer, "Snapshot: %s\n", result.SnapshotGeneratedAt)
+	}
+	fmt.Fprintf(writer, "Total Files: %d -> %d (%+d)\n", result.OldTotalFiles, result.NewTotalFiles, result.NewTotalFiles-result.OldTotalFiles)
+	fmt.Fprintf(writer, "Total Size: %s -> %s (%s, %+.2f%%)\n\n",
+		formatSize(result.OldTotalSize), formatSize(result.NewTotalSize),
+		formatSizeDelta(result.NewTotalSize-result.OldTotalSize), result.SizePercentage)
+
+	writeRulesText(writer, result.Rules)
+
+	writeDiffEntriesText(writer, "Directory Changes", "Path", result.Directories)
+	writeDiffEntriesText(writer, "File Type Changes", "Extension", result.FileTypes)
+
+	if len(result.Directories) == 0 && len(result.FileTypes) == 0 {
+		fmt.Fprintln(writer, "No changes since the snapshot.")
+	}
+
+	return nil
+}
+
+// writeFileTypeDetailsText writes the largest files and directories of each
+// file type, if the drill-down was collected
+func writeFileTypeDetailsText(writer io.Writer, fileTypes []FileType) {
+	var detailed []FileType
+	for _, ft := range fileTypes {
+		if len(ft.LargestFiles) > 0 {
+			detailed = append(detailed, ft)
+		}
+	}
+	if len(detailed) == 0 {
+		return
+	}
+
+	fmt.Fprintf(writer, "File Type Details\n")
+	fmt.Fprintf(writer, "-----------------\n")
+	for _, ft := range detailed {
+		fmt.Fprintf(writer, "%s (%d files, %s)\n", ft.Extension, ft.Count, formatSize(ft.TotalSize))
+		fmt.Fprintf(writer, "  Largest files:\n")
+		for _, file := range ft.LargestFiles {
+			fmt.Fprintf(writer, "    %-12s %s\n", formatSize(file.Size), file.Path)
+		}
+		fmt.Fprintf(writer, "  Directories:\n")
+		for _, dir := range ft.Directories {
+			fmt.Fprintf(writer, "    %-12s %-8s %-8d %s\n", formatSize(dir.TotalSize), fmt.Sprintf("%.2f%%", dir.Percentage), dir.FileCount, dir.Path)
+		}
+	}
+	fmt.Fprintln(writer)
+}
+
+// FormatDirStatHistory formats directory growth trends as plain text
+func (f *TextFormatter) FormatDirStatHistory(result *DirStatHistoryResult, writer io.Writer) error {
+	// Add branding header
+	if result.Metadata != nil {
+		flags := []string{}
+		for _, f := range result.Metadata.Flags {
+			flags = append(flags, fmt.Sprintf("%s: %s", f.Name, f.Value))
+		}
+		flagStr := strings.Join(flags, ", ")
+		fmt.Fprintf(writer, "Generated by %s %s v%s on %s (%s)\n\n",
+			result.Metadata.ToolName,
+			result.Metadata.SubCommand,
+			result.Metadata.Version,
+			result.Metadata.GeneratedAt,
+			flagStr)
+	}
+
+	fmt.Fprintf(writer, "Directory History\n")
+	fmt.Fprintf(writer, "=================\n\n")
+	fmt.Fprintf(writer, "Root: %s\n", result.Root)
+	fmt.Fprintf(writer, "Runs: %d\n", len(result.Points))
+	fmt.Fprintf(writer, "Total Size Growth: %s\n", formatGrowthRate(result.GrowthPerDay))
+	if p := result.Projection; p != nil {
+		fmt.Fprintf(writer, "Filesystem Free Space: %s of %s, %s\n",
+			formatSize(int64(p.AvailableBytes)), formatSize(int64(p.TotalBytes)), formatProjection(p))
+	}
+	fmt.Fprintln(writer)
+
+	if len(result.Points) > 0 {
+		fmt.Fprintf(writer, "Runs\n")
+		fmt.Fprintf(writer, "----\n")
+		fmt.Fprintf(writer, "%-25s %-10s %-12s %s\n", "Time", "Files", "Size", "Free")
+		fmt.Fprintf(writer, "%-25s %-10s %-12s %s\n", strings.Repeat("-", 25), strings.Repeat("-", 10), strings.Repeat("-", 12), strings.Repeat("-", 12))
+
+		for _, point := range result.Points {
+			free := "-"
+			if point.AvailableBytes > 0 {
+				free = formatSize(int64(point.AvailableBytes))
+			}
+			fmt.Fprintf(writer, "%-25s %-10d %-12s %s\n", point.Time, point.TotalFiles, formatSize(point.TotalSize), free)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	if len(result.Directories) > 0 {
+		fmt.Fprintf(writer, "Fastest Growing Directories\n")
+		fmt.Fprintf(writer, "---------------------------\n")
+		fmt.Fprintf(writer, "%-50s %-12s %-12s %s\n", "Path", "First Size", "Last Size", "Growth")
+		fmt.Fprintf(writer, "%-50s %-12s %-12s %s\n", strings.Repeat("-", 50), strings.Repeat("-", 12), strings.Repeat("-", 12), strings.Repeat("-", 15))
+
+		for _, dir := range result.Directories {
+			path := dir.Path
+			if len(path) > 47 {
+				path = "..." + path[len(path)-44:]
+			}
+			fmt.Fprintf(writer, "%-50s %-12s %-12s %s\n",
+				path, formatSize(dir.FirstSize), formatSize(dir.LastSize), formatGrowthRate(dir.GrowthPerDay))
+		}
+		fmt.Fprintln(writer)
+	}
+
+	return nil
+}
+
+// writeRulesText writes the outcome of the threshold rules, if any were checked
+func writeRulesText(writer io.Writer, report *RuleReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintf(writer, "Rule Violations\n")
+	fmt.Fprintf(writer, "---------------\n")
+	if len(report.Violations) == 0 {
+		fmt.Fprintf(writer, "All %d rules passed\n\n", report.Checked)
+		return
+	}
+
+	fmt.Fprintf(writer, "%d of %d rules violated:\n", len(report.Violations), report.Checked)
+	for _, violation := range report.Violations {
+		fmt.Fprintf(writer, "- %s: %s\n", violation.Rule, formatRuleValue(violation))
+		for _, match := range violation.Matches {
+			fmt.Fprintf(writer, "    %s\n", match)
+		}
+		if more := violation.MatchCount - len(violation.Matches); more > 0 {
+			fmt.Fprintf(writer, "    ... and %d more\n", more)
+		}
+	}
+	fmt.Fprintln(writer)
+}
+
+// formatRuleValue describes the measured value of a rule violation
+func formatRuleValue(violation RuleViolation) string {
+	if violation.MatchCount > 0 {
+		if violation.MatchCount == 1 {
+			return "1 matching file"
+		}
+		return fmt.Sprintf("%d matching files", violation.MatchCount)
+	}
+	if violation.Unit == "bytes" {
+		return fmt.Sprintf("%s (limit %s)", formatSize(violation.Value), formatSize(violation.Limit))
+	}
+	return fmt.Sprintf("%d (limit %d)", violation.Value, violation.Limit)
+}
+
+// writeDiffEntriesText writes a table of snapshot differences
+func writeDiffEntriesText(writer io.Writer, title, keyTitle string, entries []DiffEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintf(writer, "%s\n", title)
+	fmt.Fprintf(writer, "%s\n", strings.Repeat("-", len(title)))
+	fmt.Fprintf(writer, "%-40s %-8s %-12s %-12s %-12s %-10s %s\n", keyTitle, "Status", "Old Size", "New Size", "Delta", "Change", "Files")
+	fmt.Fprintf(writer, "%-40s %-8s %-12s %-12s %-12s %-10s %s\n", strings.Repeat("-", 40), strings.Repeat("-", 8), strings.Repeat("-", 12), strings.Repeat("-", 12), strings.Repeat("-", 12), strings.Repeat("-", 10), strings.Repeat("-", 8))
+
+	for _, entry := range entries {
+		key := entry.Key
+		if len(key) > 40 {
+			key = "..." + key[len(key)-37:]
+		}
+		change := "new"
+		if entry.Status != "added" {
+			change = fmt.Sprintf("%+.2f%%", entry.SizePercentage)
+		}
+		fmt.Fprintf(writer, "%-40s %-8s %-12s %-12s %-12s %-10s %+d\n",
+			key, entry.Status, formatSize(entry.OldSize), formatSize(entry.NewSize),
+			formatSizeDelta(entry.SizeDelta), change, entry.CountDelta)
+	}
+	fmt.Fprintln(writer)
+}
+
+// writeOwnersText writes an owner or group breakdown table
+func writeOwnersText(writer io.Writer, title string, owners []OwnerInfo) {
+	if len(owners) == 0 {
+		return
+	}
+
+	fmt.Fprintf(writer, "%s\n", title)
+	fmt.Fprintf(writer, "%s\n", strings.Repeat("-", len(title)))
+	fmt.Fprintf(writer, "%-20s %-8s %-12s %s\n", "Name", "Files", "Size", "Percentage")
+	fmt.Fprintf(writer, "%-20s %-8s %-12s %s\n", strings.Repeat("-", 20), strings.Repeat("-", 8), strings.Repeat("-", 12), strings.Repeat("-", 10))
+
+	for _, owner := range owners {
+		fmt.Fprintf(writer, "%-20s %-8d %-12s %.2f%%\n",
+			owner.Name, owner.FileCount, formatSize(owner.TotalSize), owner.Percentage)
+	}
+	fmt.Fprintln(writer)
+}
+
+// writeGitCountsText writes a row of the git status table
+func writeGitCountsText(writer io.Writer, name string, counts GitCounts) {
+	if len(name) > 30 {
+		name = "..." + name[len(name)-27:]
+	}
+	fmt.Fprintf(writer, "%-30s %-20s %-20s %-20s %s\n", name,
+		fmt.Sprintf("%s (%d)", formatSize(counts.TrackedSize), counts.TrackedFiles),
+		fmt.Sprintf("%s (%d)", formatSize(counts.UntrackedSize), counts.UntrackedFiles),
+		fmt.Sprintf("%s (%d)", formatSize(counts.IgnoredSize), counts.IgnoredFiles),
+		fmt.Sprintf("%s (%d)", formatSize(counts.GitDirSize), counts.GitDirFiles))
+}
+
+// writeEntryHotspotsText writes a table of directories ranked by entry count
+func writeEntryHotspotsText(writer io.Writer, title string, entries []DirectoryEntries) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintf(writer, "%s\n", title)
+	fmt.Fprintf(writer, "%s\n", strings.Repeat("-", len(title)))
+	fmt.Fprintf(writer, "%-50s %-10s %s\n", "Path", "Direct", "Cumulative")
+	fmt.Fprintf(writer, "%-50s %-10s %s\n", strings.Repeat("-", 50), strings.Repeat("-", 10), strings.Repeat("-", 10))
+
+	for _, entry := range entries {
+		path := entry.Path
+		if len(path) > 47 {
+			path = "..." + path[len(path)-44:]
+		}
+		fmt.Fprintf(writer, "%-50s %-10d %d\n", path, entry.DirectEntries, entry.CumulativeEntries)
+	}
+	fmt.Fprintln(writer)
+}
+
+// writePathListText writes a titled list of paths, skipping empty lists
+func writePathListText(writer io.Writer, title string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	fmt.Fprintf(writer, "%s (%d):\n", title, len(paths))
+	for _, path := range paths {
+		fmt.Fprintf(writer, "- %s\n", path)
+	}
+	fmt.Fprintln(writer)
+}
+
+// writeSymlinksText writes a titled list of symlinks and their targets, skipping empty lists
+func writeSymlinksText(writer io.Writer, title string, symlinks []SymlinkInfo) {
+	if len(symlinks) == 0 {
+		return
+	}
+
+	fmt.Fprintf(writer, "%s (%d):\n", title, len(symlinks))
+	for _, link := range symlinks {
+		fmt.Fprintf(writer, "- %s -> %s\n", link.Path, link.Target)
+	}
+	fmt.Fprintln(writer)
+}
+
+// formatSizeDelta formats a signed size difference in human-readable format
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + formatSize(-delta)
+	}
+	return "+" + formatSize(delta)
+}
+
+// formatGrowthRate formats a growth rate in bytes per day
+func formatGrowthRate(bytesPerDay float64) string {
+	return formatSizeDelta(int64(math.Round(bytesPerDay))) + "/day"
+}
+
+// formatProjection describes when the filesystem is projected to be full
+func formatProjection(p *FillProjection) string {
+	switch {
+	case !p.Filling:
+		return "not filling up"
+	case p.FullAt != "":
+		return fmt.Sprintf("%s consumed, full in %.1f days (%s)", formatGrowthRate(p.ConsumedPerDay), p.DaysUntilFull, p.FullAt)
+	default:
+		return fmt.Sprintf("%s consumed, full in %.0f days", formatGrowthRate(p.ConsumedPerDay), p.DaysUntilFull)
+	}
+}
+
 // formatSize formats a size in bytes to human-readable format
 func formatSize(size int64) string {
 	if size < 1024 {
@@ -0,0 +1,345 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// treemapNode is a directory in the cumulative hierarchy rendered by the HTML treemap
+type treemapNode struct {
+	Name     string         `json:"name"`
+	Path     string         `json:"path"`
+	Size     int64          `json:"size"`
+	Files    int            `json:"files"`
+	Children []*treemapNode `json:"children,omitempty"`
+
+	directSize  int64
+	directFiles int
+	index       map[string]*treemapNode
+}
+
+// child returns the named child node, creating it if needed
+func (n *treemapNode) child(name, path string) *treemapNode {
+	if n.index == nil {
+		n.index = make(map[string]*treemapNode)
+	}
+	if c, exists := n.index[name]; exists {
+		return c
+	}
+	c := &treemapNode{Name: name, Path: path}
+	n.index[name] = c
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// finalize computes cumulative sizes bottom-up, adding a "(files)" leaf for
+// files stored directly in a directory that also has subdirectories
+func (n *treemapNode) finalize() {
+	n.Size = n.directSize
+	n.Files = n.directFiles
+	for _, c := range n.Children {
+		c.finalize()
+		n.Size += c.Size
+		n.Files += c.Files
+	}
+
+	if len(n.Children) > 0 && n.directSize > 0 {
+		n.Children = append(n.Children, &treemapNode{
+			Name:  "(files)",
+			Path:  n.Path,
+			Size:  n.directSize,
+			Files: n.directFiles,
+		})
+	}
+
+	sort.Slice(n.Children, func(i, j int) bool {
+		if n.Children[i].Size != n.Children[j].Size {
+			return n.Children[i].Size > n.Children[j].Size
+		}
+		return n.Children[i].Name < n.Children[j].Name
+	})
+}
+
+// buildTreemap builds the cumulative directory hierarchy of a dirstat result.
+// Directories only hold the files stored directly in them, so intermediate
+// directories are synthesized and sizes are summed up the tree.
+func buildTreemap(result *DirStatResult) *treemapNode {
+	root := &treemapNode{Name: ".", Path: "."}
+
+	rootSize := result.TotalSize
+	rootFiles := result.TotalFiles
+	for _, dir := range result.Directories {
+		node := root
+		parts := strings.Split(filepath.ToSlash(dir.Path), "/")
+		for i, part := range parts {
+			node = node.child(part, strings.Join(parts[:i+1], "/"))
+		}
+		node.directSize += dir.TotalSize
+		node.directFiles += dir.FileCount
+		rootSize -= dir.TotalSize
+		rootFiles -= dir.FileCount
+	}
+
+	// Whatever is not attributed to a subdirectory lives in the root itself
+	if rootSize > 0 {
+		root.directSize = rootSize
+		root.directFiles = rootFiles
+	}
+
+	root.finalize()
+	return root
+}
+
+// generateTreemapHTML creates the treemap section of the directory statistics report
+func (f *HTMLFormatter) generateTreemapHTML(result *DirStatResult) string {
+	if result.TotalSize <= 0 {
+		return ""
+	}
+
+	data, err := json.Marshal(buildTreemap(result))
+	if err != nil {
+		return ""
+	}
+
+	// json.Marshal escapes <, > and &, so the data is safe inside a script element
+	return fmt.Sprintf(`
+        <div class="section">
+            <h2>Space Usage Treemap</h2>
+            <div class="treemap-breadcrumb" id="treemap-breadcrumb"></div>
+            <div class="treemap-container">
+                <svg id="treemap" viewBox="0 0 1200 600" preserveAspectRatio="none"></svg>
+                <div class="treemap-tooltip" id="treemap-tooltip"></div>
+            </div>
+            <p class="treemap-hint">Click a directory to zoom in, use the path above to zoom out.</p>
+        </div>
+        <script>
+            var treemapData = %s;
+        </script>`, data)
+}
+
+// treemapCSS styles the treemap section
+const treemapCSS = `        .treemap-container {
+            position: relative;
+            border: 1px solid #ddd;
+            border-radius: 6px;
+            overflow: hidden;
+        }
+        #treemap {
+            display: block;
+            width: 100%;
+            height: 600px;
+        }
+        #treemap rect {
+            stroke: #fff;
+            stroke-width: 1;
+            cursor: pointer;
+        }
+        #treemap rect:hover {
+            opacity: 0.8;
+        }
+        #treemap text {
+            font-size: 12px;
+            fill: #fff;
+            pointer-events: none;
+        }
+        .treemap-breadcrumb {
+            margin-bottom: 10px;
+            font-family: monospace;
+        }
+        .treemap-breadcrumb a {
+            color: #007acc;
+            cursor: pointer;
+            text-decoration: none;
+        }
+        .treemap-breadcrumb a:hover {
+            text-decoration: underline;
+        }
+        .treemap-tooltip {
+            position: absolute;
+            display: none;
+            background: rgba(0,0,0,0.85);
+            color: #fff;
+            padding: 8px 10px;
+            border-radius: 4px;
+            font-size: 13px;
+            pointer-events: none;
+            white-space: nowrap;
+        }
+        .treemap-hint {
+            color: #666;
+            font-size: 13px;
+        }
+`
+
+// treemapJS renders the treemap data as a squarified, zoomable SVG treemap
+const treemapJS = `        // Treemap rendering
+        function initTreemap() {
+            if (typeof treemapData === 'undefined') return;
+
+            var svg = document.getElementById('treemap');
+            var tooltip = document.getElementById('treemap-tooltip');
+            var breadcrumb = document.getElementById('treemap-breadcrumb');
+            var width = 1200, height = 600, headerHeight = 16, maxDepth = 2;
+            var total = treemapData.size;
+            var svgNS = 'http://www.w3.org/2000/svg';
+
+            function setParents(node, parent) {
+                node.parent = parent;
+                (node.children || []).forEach(function(child) {
+                    setParents(child, node);
+                });
+            }
+            setParents(treemapData, null);
+
+            function formatSize(size) {
+                var units = ['B', 'KB', 'MB', 'GB', 'TB'];
+                var i = 0;
+                while (size >= 1024 && i < units.length - 1) {
+                    size /= 1024;
+                    i++;
+                }
+                return (i === 0 ? size : size.toFixed(1)) + ' ' + units[i];
+            }
+
+            // Aspect ratio of the worst rectangle in a row laid along a side of the given length
+            function worst(row, side) {
+                var sum = 0, max = 0, min = Infinity;
+                row.forEach(function(item) {
+                    sum += item.area;
+                    max = Math.max(max, item.area);
+                    min = Math.min(min, item.area);
+                });
+                return Math.max(side * side * max / (sum * sum), (sum * sum) / (side * side * min));
+            }
+
+            // Squarified treemap layout of nodes within a rectangle
+            function squarify(nodes, x, y, w, h) {
+                var sum = 0;
+                nodes.forEach(function(node) { sum += node.size; });
+                if (sum <= 0 || w <= 0 || h <= 0) return [];
+
+                var scale = w * h / sum;
+                var items = nodes.filter(function(node) { return node.size > 0; })
+                    .map(function(node) { return { node: node, area: node.size * scale }; });
+                var rects = [];
+
+                while (items.length > 0) {
+                    var side = Math.min(w, h);
+                    var row = [items[0]];
+                    var i = 1;
+                    while (i < items.length && worst(row.concat([items[i]]), side) <= worst(row, side)) {
+                        row.push(items[i]);
+                        i++;
+                    }
+                    items = items.slice(i);
+
+                    var rowArea = 0;
+                    row.forEach(function(item) { rowArea += item.area; });
+
+                    if (w >= h) {
+                        var rowWidth = rowArea / h, cy = y;
+                        row.forEach(function(item) {
+                            var rh = item.area / rowWidth;
+                            rects.push({ node: item.node, x: x, y: cy, w: rowWidth, h: rh });
+                            cy += rh;
+                        });
+                        x += rowWidth;
+                        w -= rowWidth;
+                    } else {
+                        var rowHeight = rowArea / w, cx = x;
+                        row.forEach(function(item) {
+                            var rw = item.area / rowHeight;
+                            rects.push({ node: item.node, x: cx, y: y, w: rw, h: rowHeight });
+                            cx += rw;
+                        });
+                        y += rowHeight;
+                        h -= rowHeight;
+                    }
+                }
+                return rects;
+            }
+
+            function showTooltip(event, node) {
+                var pct = total > 0 ? (node.size / total * 100).toFixed(2) : '0.00';
+                tooltip.textContent = '';
+                [node.path + (node.name === '(files)' ? ' (files)' : ''),
+                 'Size: ' + formatSize(node.size),
+                 'Files: ' + node.files,
+                 'Percentage: ' + pct + '%'].forEach(function(line, i) {
+                    if (i > 0) tooltip.appendChild(document.createElement('br'));
+                    tooltip.appendChild(document.createTextNode(line));
+                });
+                var bounds = svg.parentNode.getBoundingClientRect();
+                var left = event.clientX - bounds.left + 12;
+                var top = event.clientY - bounds.top + 12;
+                tooltip.style.display = 'block';
+                if (left + tooltip.offsetWidth > bounds.width) left -= tooltip.offsetWidth + 24;
+                if (top + tooltip.offsetHeight > bounds.height) top -= tooltip.offsetHeight + 24;
+                tooltip.style.left = left + 'px';
+                tooltip.style.top = top + 'px';
+            }
+
+            function drawNode(node, rect, depth, hue, current) {
+                var el = document.createElementNS(svgNS, 'rect');
+                el.setAttribute('x', rect.x);
+                el.setAttribute('y', rect.y);
+                el.setAttribute('width', Math.max(rect.w, 0));
+                el.setAttribute('height', Math.max(rect.h, 0));
+                el.setAttribute('fill', 'hsl(' + hue + ', 55%, ' + (38 + depth * 12) + '%)');
+                el.addEventListener('mousemove', function(event) { showTooltip(event, node); });
+                el.addEventListener('mouseleave', function() { tooltip.style.display = 'none'; });
+                el.addEventListener('click', function(event) {
+                    event.stopPropagation();
+                    var target = node.children ? node : node.parent;
+                    if (target && target !== current) render(target);
+                });
+                svg.appendChild(el);
+
+                if (rect.w > 40 && rect.h > 14) {
+                    var label = document.createElementNS(svgNS, 'text');
+                    label.setAttribute('x', rect.x + 4);
+                    label.setAttribute('y', rect.y + 12);
+                    var text = node.name + ' (' + formatSize(node.size) + ')';
+                    label.textContent = text.length * 7 > rect.w ? node.name : text;
+                    svg.appendChild(label);
+                }
+
+                var children = node.children || [];
+                if (depth < maxDepth && children.length > 0 && rect.w > 30 && rect.h > headerHeight + 10) {
+                    squarify(children, rect.x + 2, rect.y + headerHeight, rect.w - 4, rect.h - headerHeight - 2).forEach(function(childRect) {
+                        drawNode(childRect.node, childRect, depth + 1, hue, current);
+                    });
+                }
+            }
+
+            function renderBreadcrumb(node) {
+                var chain = [];
+                for (var n = node; n; n = n.parent) chain.unshift(n);
+                breadcrumb.textContent = '';
+                chain.forEach(function(n, i) {
+                    if (i > 0) breadcrumb.appendChild(document.createTextNode(' / '));
+                    var link = document.createElement('a');
+                    link.textContent = n.name;
+                    link.addEventListener('click', function() { render(n); });
+                    breadcrumb.appendChild(link);
+                });
+                breadcrumb.appendChild(document.createTextNode(
+                    '  ' + formatSize(node.size) + ', ' + node.files + ' files'));
+            }
+
+            function render(node) {
+                while (svg.firstChild) svg.removeChild(svg.firstChild);
+                tooltip.style.display = 'none';
+                renderBreadcrumb(node);
+
+                var children = node.children && node.children.length > 0 ? node.children : [node];
+                squarify(children, 0, 0, width, height).forEach(function(rect, i) {
+                    drawNode(rect.node, rect, 1, (i * 137) % 360, node);
+                });
+            }
+
+            render(treemapData);
+        }`
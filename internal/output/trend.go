@@ -0,0 +1,142 @@
+package output
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// Dimensions of the trend chart drawing area, in SVG user units
+const (
+	trendWidth        = 800
+	trendHeight       = 260
+	trendMarginLeft   = 80
+	trendMarginRight  = 20
+	trendMarginTop    = 15
+	trendMarginBottom = 35
+)
+
+// trendPoint is a value at a point in time plotted by a trend chart
+type trendPoint struct {
+	time  time.Time
+	value float64
+}
+
+// historyTrends returns the total size and, where recorded, the free space
+// of the filesystem of each run with a parseable time
+func historyTrends(points []HistoryPoint) (sizes, free []trendPoint) {
+	for _, point := range points {
+		at, err := time.Parse(time.RFC3339, point.Time)
+		if err != nil {
+			continue
+		}
+		sizes = append(sizes, trendPoint{time: at, value: float64(point.TotalSize)})
+		if point.AvailableBytes > 0 {
+			free = append(free, trendPoint{time: at, value: float64(point.AvailableBytes)})
+		}
+	}
+	return sizes, free
+}
+
+// generateTrendChartHTML creates a section with an inline SVG line chart of
+// sizes over time. The chart is rendered here, so the report needs no script.
+func (f *HTMLFormatter) generateTrendChartHTML(title string, points []trendPoint) string {
+	if len(points) == 0 {
+		return ""
+	}
+
+	first, last := points[0].time, points[len(points)-1].time
+	maxValue := 0.0
+	for _, point := range points {
+		if point.value > maxValue {
+			maxValue = point.value
+		}
+	}
+	if maxValue <= 0 {
+		maxValue = 1
+	}
+
+	plotWidth := float64(trendWidth - trendMarginLeft - trendMarginRight)
+	plotHeight := float64(trendHeight - trendMarginTop - trendMarginBottom)
+	x := func(at time.Time) float64 {
+		span := last.Sub(first)
+		if span <= 0 {
+			return trendMarginLeft + plotWidth/2
+		}
+		return trendMarginLeft + plotWidth*float64(at.Sub(first))/float64(span)
+	}
+	y := func(value float64) float64 {
+		return trendMarginTop + plotHeight*(1-value/maxValue)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`
+        <div class="section">
+            <h2>%s</h2>
+            <svg class="trend-chart" viewBox="0 0 %d %d" role="img" aria-label="%s">`,
+		html.EscapeString(title), trendWidth, trendHeight, html.EscapeString(title)))
+
+	// Horizontal grid lines at zero, half and the maximum
+	for _, fraction := range []float64{0, 0.5, 1} {
+		value := maxValue * fraction
+		sb.WriteString(fmt.Sprintf(`
+                <line class="trend-grid" x1="%d" y1="%.1f" x2="%d" y2="%.1f"/>
+                <text class="trend-label" x="%d" y="%.1f" text-anchor="end">%s</text>`,
+			trendMarginLeft, y(value), trendWidth-trendMarginRight, y(value),
+			trendMarginLeft-8, y(value)+4, formatSize(int64(value))))
+	}
+
+	// Time axis labels at the first and last run
+	sb.WriteString(fmt.Sprintf(`
+                <text class="trend-label" x="%d" y="%d" text-anchor="start">%s</text>`,
+		trendMarginLeft, trendHeight-10, first.Format("2006-01-02")))
+	if last.After(first) {
+		sb.WriteString(fmt.Sprintf(`
+                <text class="trend-label" x="%d" y="%d" text-anchor="end">%s</text>`,
+			trendWidth-trendMarginRight, trendHeight-10, last.Format("2006-01-02")))
+	}
+
+	coordinates := make([]string, len(points))
+	for i, point := range points {
+		coordinates[i] = fmt.Sprintf("%.1f,%.1f", x(point.time), y(point.value))
+	}
+	sb.WriteString(fmt.Sprintf(`
+                <polyline class="trend-line" points="%s"/>`, strings.Join(coordinates, " ")))
+	for _, point := range points {
+		sb.WriteString(fmt.Sprintf(`
+                <circle class="trend-point" cx="%.1f" cy="%.1f" r="3"><title>%s: %s</title></circle>`,
+			x(point.time), y(point.value), point.time.Format(time.RFC3339), formatSize(int64(point.value))))
+	}
+
+	sb.WriteString(`
+            </svg>
+        </div>`)
+	return sb.String()
+}
+
+// trendCSS styles the trend charts of the history report
+const trendCSS = `        .trend-chart {
+            display: block;
+            width: 100%;
+            height: auto;
+            border: 1px solid #ddd;
+            border-radius: 6px;
+        }
+        .trend-grid {
+            stroke: #e9ecef;
+            stroke-width: 1;
+        }
+        .trend-label {
+            font-size: 11px;
+            fill: #666;
+        }
+        .trend-line {
+            fill: none;
+            stroke: #007acc;
+            stroke-width: 2;
+        }
+        .trend-point {
+            fill: #007acc;
+        }
+`
@@ -67,3 +67,43 @@ func (f *XMLFormatter) FormatRename(result *RenameResult, writer io.Writer) erro
 	_, err := writer.Write([]byte("\n"))
 	return err
 }
+
+// FormatDirStatDiff formats a directory statistics comparison as XML
+func (f *XMLFormatter) FormatDirStatDiff(result *DirStatDiffResult, writer io.Writer) error {
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+
+	// Write XML header
+	if _, err := writer.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	// Encode the result
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+
+	// Write a newline at the end
+	_, err := writer.Write([]byte("\n"))
+	return err
+}
+
+// FormatDirStatHistory formats directory growth trends as XML
+func (f *XMLFormatter) FormatDirStatHistory(result *DirStatHistoryResult, writer io.Writer) error {
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+
+	// Write XML header
+	if _, err := writer.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	// Encode the result
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+
+	// Write a newline at the end
+	_, err := writer.Write([]byte("\n"))
+	return err
+}
@@ -0,0 +1,57 @@
+package pathmatch
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether a slash-separated relative path matches a glob
+// pattern. Pattern segments use path.Match syntax, and a "**" segment
+// matches zero or more whole path segments, so "src/**/*.go" matches both
+// "src/main.go" and "src/cmd/tool/main.go".
+func Match(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Validate reports whether pattern is well formed
+func Validate(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(patterns, names []string) (bool, error) {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			// Collapse consecutive "**" segments, they match the same paths
+			for len(patterns) > 1 && patterns[1] == "**" {
+				patterns = patterns[1:]
+			}
+			for i := 0; i <= len(names); i++ {
+				matched, err := matchSegments(patterns[1:], names[i:])
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(names) == 0 {
+			return false, nil
+		}
+		matched, err := path.Match(patterns[0], names[0])
+		if err != nil || !matched {
+			return false, err
+		}
+		patterns = patterns[1:]
+		names = names[1:]
+	}
+	return len(names) == 0, nil
+}
@@ -0,0 +1,49 @@
+package pathmatch
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/tool/main.go", true},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/**/*.go", "lib/main.go", false},
+		{"build/**", "build/out/app", true},
+		{"build/**", "build", true},
+		{"build/**", "builder/app", false},
+		{"**/node_modules/**", "web/node_modules/react/index.js", true},
+		{"**/**/*.log", "logs/app.log", true},
+		{"**", "anything/at/all", true},
+		{"a/?/c", "a/b/c", true},
+		{"a/[bc]/d", "a/x/d", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"|"+tt.name, func(t *testing.T) {
+			got, err := Match(tt.pattern, tt.name)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("src/**/[a-z]*.go"); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := Validate("src/[a-"); err == nil {
+		t.Error("Validate() accepted a malformed pattern")
+	}
+}
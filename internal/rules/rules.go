@@ -0,0 +1,371 @@
+package rules
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"amurru/filetools/internal/output"
+	"amurru/filetools/internal/pathmatch"
+)
+
+// Units of rule values and measured values
+const (
+	UnitBytes = "bytes"
+	UnitCount = "count"
+)
+
+// maxMatches is the number of matching paths reported for a presence rule
+const maxMatches = 10
+
+// Rule is a threshold evaluated against the statistics of a directory tree,
+// such as "logs/ > 5G", "file count > 1e6" or "*.core present"
+type Rule struct {
+	text   string
+	target string // "total", a directory ("logs"), a file type (".log") or a presence glob
+	kind   targetKind
+	metric string // UnitBytes or UnitCount
+	op     string
+	limit  int64
+	inodes bool // Count inodes rather than files or bytes
+}
+
+// targetKind identifies what a rule measures
+type targetKind int
+
+const (
+	targetTotal targetKind = iota
+	targetFree
+	targetDirectory
+	targetFileType
+	targetPresence
+)
+
+// String returns the rule as written
+func (r Rule) String() string {
+	return r.text
+}
+
+// Parse parses a single rule. Supported forms are:
+//
+//	<subject> <op> <value>   op is one of >, >=, <, <=
+//	<glob> present           violated if any file matches the glob
+//
+// The subject is "size", "files", "inodes", "free space", "free inodes", a
+// directory with a trailing slash ("logs/") or an extension glob ("*.log"),
+// optionally followed by "size" or "files". Sizes accept K, M, G and T
+// suffixes (powers of 1024) and counts accept exponents such as 1e6.
+func Parse(text string) (Rule, error) {
+	text = strings.TrimSpace(text)
+	rule := Rule{text: text}
+
+	if glob, ok := strings.CutSuffix(text, " present"); ok {
+		glob = strings.TrimSpace(glob)
+		if glob == "" {
+			return rule, fmt.Errorf("rule %q: missing glob", text)
+		}
+		if err := pathmatch.Validate(glob); err != nil {
+			return rule, fmt.Errorf("rule %q: invalid glob: %w", text, err)
+		}
+		rule.kind = targetPresence
+		rule.target = glob
+		return rule, nil
+	}
+
+	i := strings.IndexAny(text, "<>")
+	if i < 0 {
+		return rule, fmt.Errorf("rule %q: expected a comparison (>, >=, <, <=) or \"present\"", text)
+	}
+	rule.op = text[i : i+1]
+	valueText := text[i+1:]
+	if strings.HasPrefix(valueText, "=") {
+		rule.op += "="
+		valueText = valueText[1:]
+	}
+	subject := strings.Fields(text[:i])
+	if len(subject) == 0 {
+		return rule, fmt.Errorf("rule %q: missing subject", text)
+	}
+
+	if err := rule.parseSubject(subject); err != nil {
+		return rule, fmt.Errorf("rule %q: %w", text, err)
+	}
+
+	var err error
+	if rule.metric == UnitBytes {
+		rule.limit, err = ParseSize(strings.TrimSpace(valueText))
+	} else {
+		rule.limit, err = parseCount(strings.TrimSpace(valueText))
+	}
+	if err != nil {
+		return rule, fmt.Errorf("rule %q: %w", text, err)
+	}
+
+	return rule, nil
+}
+
+// parseSubject determines what a comparison rule measures
+func (r *Rule) parseSubject(words []string) error {
+	lower := strings.ToLower(strings.Join(words, " "))
+	switch lower {
+	case "size", "total size":
+		r.kind, r.target, r.metric = targetTotal, "total", UnitBytes
+		return nil
+	case "files", "file count", "total files":
+		r.kind, r.target, r.metric = targetTotal, "total", UnitCount
+		return nil
+	case "inodes", "inode count":
+		r.kind, r.target, r.metric, r.inodes = targetTotal, "total", UnitCount, true
+		return nil
+	case "free space", "free":
+		r.kind, r.target, r.metric = targetFree, "filesystem", UnitBytes
+		return nil
+	case "free inodes":
+		r.kind, r.target, r.metric, r.inodes = targetFree, "filesystem", UnitCount, true
+		return nil
+	}
+
+	target := words[0]
+	r.metric = UnitBytes
+	if len(words) > 1 {
+		switch strings.ToLower(strings.Join(words[1:], " ")) {
+		case "size":
+		case "files", "file count", "count":
+			r.metric = UnitCount
+		default:
+			return fmt.Errorf("unknown measure %q, expected size or files", strings.Join(words[1:], " "))
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(target, "/"):
+		r.kind = targetDirectory
+		r.target = filepath.Clean(filepath.FromSlash(target))
+	case strings.HasPrefix(target, "*.") && len(target) > 2:
+		r.kind = targetFileType
+		r.target = strings.ToLower(target[1:])
+	default:
+		return fmt.Errorf("unknown subject %q, directories need a trailing slash and file types the form *.ext", target)
+	}
+	return nil
+}
+
+// ParseSize parses a size such as "512", "5G", "1.5 TB" or "10MiB", using powers of 1024
+func ParseSize(text string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(text))
+	upper = strings.TrimSuffix(strings.TrimSuffix(upper, "IB"), "B")
+
+	multiplier := 1.0
+	for i, suffix := range []string{"K", "M", "G", "T", "P"} {
+		if number, ok := strings.CutSuffix(upper, suffix); ok {
+			upper = number
+			multiplier = math.Pow(1024, float64(i+1))
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(upper), 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+	return int64(value * multiplier), nil
+}
+
+// parseCount parses a count such as "1000" or "1e6"
+func parseCount(text string) (int64, error) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("invalid count %q", text)
+	}
+	return int64(value), nil
+}
+
+// Load reads rules from a file with one rule per line. Blank lines and
+// lines starting with # are ignored.
+func Load(filename string) ([]Rule, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Evaluator checks rules against a directory tree. Presence rules need to
+// see every file, which is why files are observed during the walk.
+type Evaluator struct {
+	rules   []Rule
+	matches map[int][]string // First matching paths in sorted order per presence rule, by rule index, at most maxMatches
+	counts  map[int]int      // Number of matching paths per presence rule
+}
+
+// NewEvaluator creates an Evaluator for a set of rules
+func NewEvaluator(rules []Rule) *Evaluator {
+	return &Evaluator{
+		rules:   rules,
+		matches: make(map[int][]string),
+		counts:  make(map[int]int),
+	}
+}
+
+// ObserveFile checks a file's relative path against the presence rules. It
+// is not safe for concurrent use.
+func (e *Evaluator) ObserveFile(relPath string) {
+	slashPath := filepath.ToSlash(relPath)
+	for i, rule := range e.rules {
+		if rule.kind != targetPresence {
+			continue
+		}
+		name := slashPath
+		if !strings.Contains(rule.target, "/") {
+			name = path.Base(slashPath)
+		}
+		if matched, _ := pathmatch.Match(rule.target, name); matched {
+			e.counts[i]++
+			e.matches[i] = insertMatch(e.matches[i], relPath)
+		}
+	}
+}
+
+// insertMatch adds a path to sorted matches, keeping the first maxMatches
+func insertMatch(matches []string, relPath string) []string {
+	pos := sort.SearchStrings(matches, relPath)
+	if pos == maxMatches {
+		return matches
+	}
+	if len(matches) < maxMatches {
+		matches = append(matches, "")
+	}
+	copy(matches[pos+1:], matches[pos:])
+	matches[pos] = relPath
+	return matches
+}
+
+// Evaluate checks all rules against the result and returns the report
+func (e *Evaluator) Evaluate(result *output.DirStatResult) *output.RuleReport {
+	report := &output.RuleReport{Checked: len(e.rules), Violations: []output.RuleViolation{}}
+
+	for i, rule := range e.rules {
+		if rule.kind == targetPresence {
+			if e.counts[i] == 0 {
+				continue
+			}
+			report.Violations = append(report.Violations, output.RuleViolation{
+				Rule:       rule.text,
+				Value:      int64(e.counts[i]),
+				Unit:       UnitCount,
+				Matches:    e.matches[i],
+				MatchCount: e.counts[i],
+			})
+			continue
+		}
+
+		value, ok := rule.measure(result)
+		if !ok || !rule.violatedBy(value) {
+			continue
+		}
+		report.Violations = append(report.Violations, output.RuleViolation{
+			Rule:  rule.text,
+			Value: value,
+			Limit: rule.limit,
+			Unit:  rule.metric,
+		})
+	}
+
+	return report
+}
+
+// measure returns the value a comparison rule is checked against, or false
+// if it cannot be measured
+func (r Rule) measure(result *output.DirStatResult) (int64, bool) {
+	switch r.kind {
+	case targetTotal:
+		switch {
+		case r.inodes:
+			return int64(result.TotalInodes), true
+		case r.metric == UnitCount:
+			return int64(result.TotalFiles), true
+		default:
+			return result.TotalSize, true
+		}
+
+	case targetFree:
+		fs := result.Filesystem
+		if fs == nil || (r.inodes && fs.TotalInodes == 0) {
+			return 0, false
+		}
+		if r.inodes {
+			return int64(fs.FreeInodes), true
+		}
+		return int64(fs.AvailableBytes), true
+
+	case targetDirectory:
+		// Directories only hold their direct files, so sum up the subtree
+		var files, size int64
+		if r.target == "." {
+			files, size = int64(result.TotalFiles), result.TotalSize
+		} else {
+			prefix := r.target + string(filepath.Separator)
+			for _, dir := range result.Directories {
+				if dir.Path == r.target || strings.HasPrefix(dir.Path, prefix) {
+					files += int64(dir.FileCount)
+					size += dir.TotalSize
+				}
+			}
+		}
+		if r.metric == UnitCount {
+			return files, true
+		}
+		return size, true
+
+	case targetFileType:
+		for _, ft := range result.FileTypes {
+			if ft.Extension == r.target {
+				if r.metric == UnitCount {
+					return int64(ft.Count), true
+				}
+				return ft.TotalSize, true
+			}
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// violatedBy reports whether a measured value breaks the rule
+func (r Rule) violatedBy(value int64) bool {
+	switch r.op {
+	case ">":
+		return value > r.limit
+	case ">=":
+		return value >= r.limit
+	case "<":
+		return value < r.limit
+	case "<=":
+		return value <= r.limit
+	}
+	return false
+}
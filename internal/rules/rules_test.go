@@ -0,0 +1,166 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"amurru/filetools/internal/output"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{"512", 512},
+		{"5G", 5 << 30},
+		{"5 GB", 5 << 30},
+		{"1.5k", 1536},
+		{"10MiB", 10 << 20},
+		{"2T", 2 << 40},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.text)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.text, got, err, tt.want)
+		}
+	}
+
+	for _, text := range []string{"", "G", "-1G", "lots"} {
+		if _, err := ParseSize(text); err == nil {
+			t.Errorf("ParseSize(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"logs/ 5G", "expected a comparison"},
+		{"> 5G", "missing subject"},
+		{"logs > 5G", "unknown subject"},
+		{"logs/ weight > 5", "unknown measure"},
+		{"logs/ > lots", "invalid size"},
+		{"files > many", "invalid count"},
+		{"[a- present", "invalid glob"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.rule)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want it to contain %q", tt.rule, err, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	result := &output.DirStatResult{
+		TotalFiles:  1200,
+		TotalSize:   10 << 30,
+		TotalInodes: 1300,
+		FileTypes: []output.FileType{
+			{Extension: ".log", Count: 1000, TotalSize: 6 << 30},
+			{Extension: ".go", Count: 200, TotalSize: 4 << 30},
+		},
+		Directories: []output.DirectoryInfo{
+			{Path: "logs", FileCount: 10, TotalSize: 1 << 30},
+			{Path: filepath.Join("logs", "old"), FileCount: 990, TotalSize: 5 << 30},
+			{Path: "logsbackup", FileCount: 1, TotalSize: 1 << 30},
+			{Path: "src", FileCount: 199, TotalSize: 3 << 30},
+		},
+		Filesystem: &output.FilesystemInfo{AvailableBytes: 1 << 30, TotalInodes: 100, FreeInodes: 50},
+	}
+
+	tests := []struct {
+		rule     string
+		violated bool
+		value    int64
+	}{
+		{"logs/ > 5G", true, 6 << 30},
+		{"logs/ > 6G", false, 0},
+		{"logs/ >= 6G", true, 6 << 30},
+		{"logs/ files > 999", true, 1000},
+		{"./ size > 9G", true, 10 << 30},
+		{"file count > 1e3", true, 1200},
+		{"file count > 1e6", false, 0},
+		{"inodes > 1299", true, 1300},
+		{"*.log > 5G", true, 6 << 30},
+		{"*.LOG files < 2000", true, 1000},
+		{"*.txt size > 0", false, 0},
+		{"free space < 2G", true, 1 << 30},
+		{"free inodes <= 50", true, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report := NewEvaluator([]Rule{rule}).Evaluate(result)
+			if report.Checked != 1 {
+				t.Errorf("checked %d rules, want 1", report.Checked)
+			}
+			if got := len(report.Violations) == 1; got != tt.violated {
+				t.Fatalf("violated = %v, want %v", got, tt.violated)
+			}
+			if tt.violated && report.Violations[0].Value != tt.value {
+				t.Errorf("value = %d, want %d", report.Violations[0].Value, tt.value)
+			}
+		})
+	}
+}
+
+func TestEvaluatePresence(t *testing.T) {
+	core, _ := Parse("*.core present")
+	nested, _ := Parse("tmp/**/*.swp present")
+	absent, _ := Parse("*.bak present")
+
+	evaluator := NewEvaluator([]Rule{core, nested, absent})
+	for i := 11; i >= 0; i-- {
+		evaluator.ObserveFile(filepath.Join("crashes", strings.Repeat("x", i+1)+".core"))
+	}
+	evaluator.ObserveFile(filepath.Join("tmp", "a", "b", ".file.swp"))
+	evaluator.ObserveFile(filepath.Join("src", ".file.swp"))
+
+	report := evaluator.Evaluate(&output.DirStatResult{})
+	if len(report.Violations) != 2 {
+		t.Fatalf("got %d violations, want 2: %+v", len(report.Violations), report.Violations)
+	}
+	if v := report.Violations[0]; v.MatchCount != 12 || len(v.Matches) != maxMatches {
+		t.Errorf("core rule: %d matches, %d listed", v.MatchCount, len(v.Matches))
+	} else if !sort.StringsAreSorted(v.Matches) || v.Matches[0] != filepath.Join("crashes", "x.core") {
+		t.Errorf("core rule: matches %v, want the first %d in sorted order", v.Matches, maxMatches)
+	}
+	if v := report.Violations[1]; v.MatchCount != 1 || v.Matches[0] != filepath.Join("tmp", "a", "b", ".file.swp") {
+		t.Errorf("swap rule: %+v", v)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "rules.txt")
+	content := "# quotas\nlogs/ > 5G\n\n*.core present\nbogus\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(filename)
+	if err == nil || !strings.Contains(err.Error(), "rules.txt:5") {
+		t.Errorf("Load() error = %v, want the line number of the bad rule", err)
+	}
+
+	if err := os.WriteFile(filename, []byte(content[:len(content)-len("bogus\n")]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || loaded[0].String() != "logs/ > 5G" {
+		t.Errorf("Load() = %v", loaded)
+	}
+}